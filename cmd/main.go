@@ -12,10 +12,33 @@ import (
 
 	"chatbot-wsp/internal/domain/repository"
 	"chatbot-wsp/internal/domain/service"
+	"chatbot-wsp/internal/infrastructure/calendar"
 	"chatbot-wsp/internal/infrastructure/config"
+	"chatbot-wsp/internal/infrastructure/delivery"
+	"chatbot-wsp/internal/infrastructure/flows"
 	"chatbot-wsp/internal/infrastructure/http/handlers"
 	"chatbot-wsp/internal/infrastructure/http/routes"
 	"chatbot-wsp/internal/infrastructure/logger"
+	"chatbot-wsp/internal/infrastructure/media"
+	"chatbot-wsp/internal/infrastructure/metrics"
+	"chatbot-wsp/internal/infrastructure/notification"
+	"chatbot-wsp/internal/infrastructure/outbound"
+	"chatbot-wsp/internal/infrastructure/persistence/redis"
+	"chatbot-wsp/internal/infrastructure/persistence/sqlite"
+	"chatbot-wsp/internal/infrastructure/reminder"
+	"chatbot-wsp/internal/infrastructure/schedule"
+	"chatbot-wsp/internal/infrastructure/whatsapp"
+)
+
+const (
+	// messageStatusTTL is how long Redis keeps the delivery status of a message
+	messageStatusTTL = 30 * 24 * time.Hour
+	// messageStatusCapacity is how many delivery statuses the in-memory backend keeps
+	messageStatusCapacity = 10000
+	// transcriptCapacity is how many messages per user the in-memory backend keeps
+	transcriptCapacity = 500
+	// deadLetterCapacity is how many undeliverable messages are kept for staff
+	deadLetterCapacity = 1000
 )
 
 func main() {
@@ -34,26 +57,187 @@ func main() {
 		"host": cfg.Server.Host,
 	}).Info("Starting WhatsApp Chatbot service")
 
-	// Initialize repository
-	chatbotRepo := repository.NewInMemoryChatbotRepository()
+	// Load conversation flows
+	chatbotFlows, err := flows.Load(cfg.Flows.File)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to load conversation flows")
+	}
+
+	// Initialize repositories
+	dedupTTL := time.Duration(cfg.Session.DedupTTLHours) * time.Hour
+	var chatbotRepo repository.ChatbotRepository
+	var dedupRepo repository.MessageDedupRepository
+	var lastInboundRepo repository.LastInboundRepository
+	var transcriptRepo repository.TranscriptRepository
+	var statusRepo repository.MessageStatusRepository
+	var appointmentRepo repository.AppointmentRepository
+	var reminderRepo repository.ReminderRepository
+	var deadLetterRepo repository.DeadLetterRepository
+	switch cfg.Storage.Backend {
+	case config.StorageBackendSQLite:
+		db, err := sqlite.Open(cfg.Storage.SQLitePath)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to open SQLite database")
+		}
+		defer db.Close()
+		chatbotRepo = sqlite.NewChatbotRepository(db, chatbotFlows)
+		dedupRepo = repository.NewInMemoryMessageDedupRepository(dedupTTL)
+		lastInboundRepo = sqlite.NewLastInboundRepository(db, outbound.ServiceWindow)
+		transcriptRepo = sqlite.NewTranscriptRepository(db)
+		statusRepo = sqlite.NewMessageStatusRepository(db)
+		appointmentRepo = sqlite.NewAppointmentRepository(db)
+		reminderRepo = sqlite.NewReminderRepository(db)
+		deadLetterRepo = sqlite.NewDeadLetterRepository(db, deadLetterCapacity)
+	case config.StorageBackendRedis:
+		client, err := redis.Open(&redis.Config{
+			Addr:     cfg.Storage.RedisAddr,
+			Password: cfg.Storage.RedisPassword,
+			DB:       cfg.Storage.RedisDB,
+		})
+		if err != nil {
+			log.WithError(err).Fatal("Failed to connect to Redis")
+		}
+		defer client.Close()
+		chatbotRepo = redis.NewChatbotRepository(client, cfg.Storage.RedisKeyPrefix, chatbotFlows)
+		dedupRepo = redis.NewMessageDedupRepository(client, cfg.Storage.RedisKeyPrefix, dedupTTL)
+		lastInboundRepo = redis.NewLastInboundRepository(client, cfg.Storage.RedisKeyPrefix, outbound.ServiceWindow)
+		transcriptRepo = redis.NewTranscriptRepository(client, cfg.Storage.RedisKeyPrefix)
+		statusRepo = redis.NewMessageStatusRepository(client, cfg.Storage.RedisKeyPrefix, messageStatusTTL)
+		appointmentRepo = redis.NewAppointmentRepository(client, cfg.Storage.RedisKeyPrefix)
+		reminderRepo = redis.NewReminderRepository(client, cfg.Storage.RedisKeyPrefix)
+		deadLetterRepo = redis.NewDeadLetterRepository(client, cfg.Storage.RedisKeyPrefix, deadLetterCapacity)
+	default:
+		chatbotRepo = repository.NewInMemoryChatbotRepository(chatbotFlows)
+		dedupRepo = repository.NewInMemoryMessageDedupRepository(dedupTTL)
+		lastInboundRepo = repository.NewInMemoryLastInboundRepository(outbound.ServiceWindow)
+		transcriptRepo = repository.NewInMemoryTranscriptRepository(transcriptCapacity)
+		statusRepo = repository.NewInMemoryMessageStatusRepository(messageStatusCapacity)
+		appointmentRepo = repository.NewInMemoryAppointmentRepository()
+		reminderRepo = repository.NewInMemoryReminderRepository()
+		deadLetterRepo = repository.NewInMemoryDeadLetterRepository(deadLetterCapacity)
+	}
+	log.WithField("backend", cfg.Storage.Backend).Info("Session storage initialized")
 
 	// Start session cleanup
 	chatbotRepo.StartSessionCleanup(cfg.Session.ExpirationHours, cfg.Session.CleanupIntervalMin)
 	defer chatbotRepo.StopSessionCleanup()
 
+	// Start message deduplication cleanup
+	dedupRepo.StartCleanup(cfg.Session.CleanupIntervalMin)
+	defer dedupRepo.StopCleanup()
+
+	// Start cleanup of last inbound messages outside the service window
+	lastInboundRepo.StartCleanup(cfg.Session.CleanupIntervalMin)
+	defer lastInboundRepo.StopCleanup()
+
+	// Initialize metrics
+	appMetrics := metrics.New()
+	appMetrics.SetActiveSessions(chatbotRepo.CountUserStates)
+
+	// Initialize outbound message queue
+	var whatsappClient whatsapp.WhatsAppClient = whatsapp.NewGraphClient(&whatsapp.Config{
+		AccessToken:    cfg.WhatsApp.AccessToken,
+		PhoneNumberID:  cfg.WhatsApp.PhoneNumberID,
+		MyPhoneNumber:  cfg.WhatsApp.MyPhoneNumber,
+		SandboxMode:    cfg.WhatsApp.DeliveryMode == config.DeliveryModeSandbox,
+		SandboxNumbers: cfg.WhatsApp.SandboxNumbers,
+		BaseURL:        cfg.WhatsApp.APIBaseURL,
+		APIVersion:     cfg.WhatsApp.APIVersion,
+	}, appMetrics)
+	deliveryTracker := delivery.NewTracker(statusRepo, transcriptRepo, appMetrics)
+	outboundQueue := outbound.NewQueue(whatsappClient, deadLetterRepo, &outbound.Config{
+		Workers:        cfg.Outbound.Workers,
+		QueueSize:      cfg.Outbound.QueueSize,
+		MaxAttempts:    cfg.Outbound.MaxAttempts,
+		InitialBackoff: time.Duration(cfg.Outbound.InitialBackoffMs) * time.Millisecond,
+		MaxBackoff:     time.Duration(cfg.Outbound.MaxBackoffMs) * time.Millisecond,
+	})
+	outboundQueue.SetTracker(deliveryTracker)
+	if cfg.WhatsApp.WindowTemplate != "" {
+		outboundQueue.SetWindowFallback(outbound.NewWindowFallback(lastInboundRepo.LastInbound, cfg.WhatsApp.WindowTemplate, cfg.WhatsApp.WindowTemplateLanguage, cfg.WhatsApp.WindowTemplateWithText))
+	}
+	outboundQueue.Start()
+
 	// Initialize service
-	chatbotService := service.NewChatbotService(chatbotRepo)
+	serviceOptions := []service.Option{
+		service.WithHandoffTimeout(time.Duration(cfg.Session.HandoffTimeoutMinutes) * time.Minute),
+		service.WithMetrics(appMetrics),
+		service.WithInvalidInputEscalation(cfg.Session.MaxInvalidAttempts),
+	}
+	if notifier := newNotifier(cfg, outboundQueue); notifier != nil {
+		serviceOptions = append(serviceOptions, service.WithNotifier(notifier))
+	}
+	if cfg.Flows.InteractiveMenus {
+		serviceOptions = append(serviceOptions, service.WithInteractiveMenus())
+	}
+	serviceOptions = append(serviceOptions, service.WithCommands(newCommands(cfg)))
+	if cfg.Emergency.Disabled {
+		serviceOptions = append(serviceOptions, service.WithEmergencyKeywords(nil))
+	} else if len(cfg.Emergency.Keywords) > 0 {
+		serviceOptions = append(serviceOptions, service.WithEmergencyKeywords(cfg.Emergency.Keywords))
+	}
+	if cfg.BusinessHours.Schedule != "" {
+		businessHours, err := schedule.New(cfg.BusinessHours.Schedule, cfg.BusinessHours.Holidays, cfg.BusinessHours.Timezone)
+		if err != nil {
+			log.WithError(err).Fatal("Invalid business hours")
+		}
+		serviceOptions = append(serviceOptions, service.WithBusinessHours(businessHours, service.AfterHoursMode(cfg.BusinessHours.AfterHoursMode)))
+	}
+	calendarTimezone := time.Local
+	if cfg.Appointments.File != "" {
+		appointmentCalendar, err := calendar.Load(cfg.Appointments.File)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to load appointment calendar")
+		}
+		serviceOptions = append(serviceOptions, service.WithBooking(appointmentCalendar, appointmentRepo))
+		calendarTimezone = appointmentCalendar.Timezone()
+		log.WithField("locations", len(appointmentCalendar.Locations())).Info("Appointment booking enabled")
+	}
+	// Appointments are only booked with a calendar, and requests are only
+	// called back when they were made outside business hours
+	var appointmentLead time.Duration
+	if cfg.Appointments.File != "" {
+		appointmentLead = time.Duration(cfg.Reminders.AppointmentHours) * time.Hour
+	}
+	callbacks := cfg.BusinessHours.Schedule != "" && cfg.Reminders.Callbacks
+	var reminderScheduler *reminder.Scheduler
+	if appointmentLead > 0 || callbacks {
+		reminderScheduler = reminder.NewScheduler(reminderRepo, appointmentRepo, outboundQueue, &reminder.Config{
+			AppointmentLead: appointmentLead,
+			Callbacks:       callbacks,
+			Timezone:        calendarTimezone,
+		})
+		serviceOptions = append(serviceOptions, service.WithReminders(reminderScheduler))
+		reminderScheduler.Start(time.Duration(cfg.Reminders.IntervalSeconds) * time.Second)
+	}
+	chatbotService := service.NewChatbotService(chatbotRepo, serviceOptions...)
+
+	// Initialize media storage
+	mediaDownloader := media.NewDownloader(whatsappClient, media.NewLocalBlobStore(cfg.Media.StorageDir))
 
 	// Initialize handler
-	whatsappHandler := handlers.NewWhatsAppHandler(chatbotService, &handlers.Config{
-		VerifyToken:   cfg.WhatsApp.VerifyToken,
-		AccessToken:   cfg.WhatsApp.AccessToken,
-		PhoneNumberID: cfg.WhatsApp.PhoneNumberID,
-		MyPhoneNumber: cfg.WhatsApp.MyPhoneNumber,
+	whatsappHandler := handlers.NewWhatsAppHandler(chatbotService, outboundQueue, deliveryTracker, dedupRepo, lastInboundRepo, transcriptRepo, mediaDownloader, appMetrics, &handlers.Config{
+		VerifyToken: cfg.WhatsApp.VerifyToken,
 	})
+	adminHandler := handlers.NewAdminHandler(chatbotService, transcriptRepo, statusRepo, appointmentRepo, deadLetterRepo)
+	if reminderScheduler != nil {
+		adminHandler.SetReminders(reminderScheduler)
+	}
+	adminHandler.SetTimezone(calendarTimezone)
+	metricsHandler := handlers.NewMetricsHandler(appMetrics)
 
 	// Setup routes
-	router := routes.SetupRoutes(whatsappHandler)
+	if cfg.WhatsApp.SkipSignature {
+		log.Warn("Webhook signature verification is disabled")
+	}
+	if cfg.Admin.APIToken == "" {
+		log.Warn("ADMIN_API_TOKEN is not set - admin API is disabled")
+	}
+	router := routes.SetupRoutes(whatsappHandler, adminHandler, metricsHandler, &routes.Config{
+		AppSecret:     cfg.WhatsApp.AppSecret,
+		SkipSignature: cfg.WhatsApp.SkipSignature,
+		AdminToken:    cfg.Admin.APIToken,
+	})
 
 	// Create HTTP server
 	server := &http.Server{
@@ -84,5 +268,55 @@ func main() {
 		log.WithError(err).Fatal("Server forced to shutdown")
 	}
 
+	// Stop sending reminders before draining the queue they are sent through
+	if reminderScheduler != nil {
+		reminderScheduler.Stop()
+	}
+
+	// Deliver the replies still waiting in the outbound queue
+	log.Info("Draining outbound message queue...")
+	if err := outboundQueue.Shutdown(ctx); err != nil {
+		log.WithError(err).Error("Outbound queue did not drain in time")
+	}
+
 	log.Info("Server exited")
 }
+
+// newNotifier builds the staff notifiers enabled in the configuration
+func newNotifier(cfg *config.Config, queue notification.MessageQueue) service.Notifier {
+	var notifiers notification.Multi
+	if cfg.Notification.StaffNumber != "" {
+		notifiers = append(notifiers, notification.NewWhatsAppNotifier(queue, cfg.Notification.StaffNumber))
+	}
+	if cfg.Notification.WebhookURL != "" {
+		notifiers = append(notifiers, notification.NewWebhookNotifier(cfg.Notification.WebhookURL))
+	}
+
+	if len(notifiers) == 0 {
+		logger.GetLogger().Warn("No staff notification configured - staff won't be told about completed requests")
+		return nil
+	}
+	return notifiers
+}
+
+// newCommands overrides the default synonyms of the global commands with the configured ones
+func newCommands(cfg *config.Config) map[service.Command][]string {
+	commands := make(map[service.Command][]string, len(service.DefaultCommands))
+	for command, synonyms := range service.DefaultCommands {
+		commands[command] = synonyms
+	}
+
+	configured := map[service.Command][]string{
+		service.CommandMenu:    cfg.Commands.Menu,
+		service.CommandBack:    cfg.Commands.Back,
+		service.CommandRestart: cfg.Commands.Restart,
+		service.CommandHelp:    cfg.Commands.Help,
+	}
+	for command, synonyms := range configured {
+		if len(synonyms) > 0 {
+			commands[command] = synonyms
+		}
+	}
+
+	return commands
+}
@@ -4,12 +4,19 @@ import "errors"
 
 // Common errors
 var (
-	ErrFlowNotFound   = errors.New("flow not found")
-	ErrInvalidState   = errors.New("invalid state")
-	ErrInvalidOption  = errors.New("invalid option")
-	ErrInvalidData    = errors.New("invalid data")
-	ErrUserNotFound   = errors.New("user not found")
-	ErrInvalidWebhook = errors.New("invalid webhook payload")
-	ErrMissingToken   = errors.New("missing verification token")
-	ErrInvalidToken   = errors.New("invalid verification token")
+	ErrFlowNotFound    = errors.New("flow not found")
+	ErrInvalidState    = errors.New("invalid state")
+	ErrInvalidOption   = errors.New("invalid option")
+	ErrInvalidData     = errors.New("invalid data")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrMessageNotFound = errors.New("message not found")
+	ErrInvalidWebhook  = errors.New("invalid webhook payload")
+	ErrMissingToken    = errors.New("missing verification token")
+	ErrInvalidToken    = errors.New("invalid verification token")
+
+	ErrAppointmentNotFound = errors.New("appointment not found")
+	ErrSlotTaken           = errors.New("slot already booked")
+
+	ErrMissingSignature = errors.New("missing webhook signature")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
 )
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Appointment statuses
+const (
+	AppointmentBooked    = "booked"
+	AppointmentCancelled = "cancelled"
+)
+
+// Location is a practice where appointments are given
+type Location struct {
+	ID   string `json:"id" yaml:"id"`
+	Name string `json:"name" yaml:"name"`
+}
+
+// Slot is a time an appointment can be booked at
+type Slot struct {
+	LocationID string    `json:"location_id"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+}
+
+// Appointment is a slot reserved by a patient
+type Appointment struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"user_id"` // WhatsApp number that booked it
+	PatientName  string    `json:"patient_name"`
+	LocationID   string    `json:"location_id"`
+	LocationName string    `json:"location_name"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+	CancelledAt  time.Time `json:"cancelled_at,omitempty"`
+}
+
+// BookingProgress is the position of a user in the booking conversation
+type BookingProgress struct {
+	Step        string       `json:"step"`
+	LocationID  string       `json:"location_id,omitempty"`
+	Offered     []Slot       `json:"offered,omitempty"` // Free slots listed to the user, in order
+	Slot        *Slot        `json:"slot,omitempty"`    // Slot chosen by the user
+	PatientName string       `json:"patient_name,omitempty"`
+	Existing    *Appointment `json:"existing,omitempty"` // Appointment being rescheduled or cancelled
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// DeadLetter is a message the outbound queue gave up on without WhatsApp accepting it
+type DeadLetter struct {
+	Response   *WhatsAppResponse `json:"response"`
+	Attempts   int               `json:"attempts"`
+	LastError  string            `json:"last_error"`
+	FailedAt   time.Time         `json:"failed_at"`
+	ReminderID string            `json:"reminder_id,omitempty"` // Reminder the message delivered, already marked sent
+}
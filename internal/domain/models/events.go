@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// RequestCompletedEvent is emitted when a patient answers all the data requested by a flow
+type RequestCompletedEvent struct {
+	UserID         string            `json:"user_id"`          // Patient's WhatsApp number
+	State          string            `json:"state"`            // Flow whose data was collected
+	Option         string            `json:"option,omitempty"` // Menu option that led to the flow
+	Data           map[string]string `json:"data"`
+	CompletedAt    time.Time         `json:"completed_at"`
+	CallbackWindow *CallbackWindow   `json:"callback_window,omitempty"` // When staff is expected to answer
+}
+
+// CallbackWindow is the range of opening hours in which staff can answer a request
+type CallbackWindow struct {
+	From       time.Time `json:"from"`
+	Until      time.Time `json:"until"`
+	AfterHours bool      `json:"after_hours"` // The request arrived while the practice was closed
+}
+
+// EmergencyEvent is emitted when a patient's message matches an emergency keyword
+type EmergencyEvent struct {
+	UserID     string    `json:"user_id"` // Patient's WhatsApp number
+	State      string    `json:"state"`   // State the conversation was in
+	Message    string    `json:"message"` // Message that triggered the detection
+	Keyword    string    `json:"keyword"` // Keyword or phrase that matched
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// EscalationEvent is emitted when a conversation is handed to staff because the
+// patient's answers were repeatedly not understood
+type EscalationEvent struct {
+	UserID      string    `json:"user_id"`      // Patient's WhatsApp number
+	State       string    `json:"state"`        // State the conversation was stuck in
+	Attempts    int       `json:"attempts"`     // Consecutive invalid answers
+	LastMessage string    `json:"last_message"` // Last answer that was not understood
+	EscalatedAt time.Time `json:"escalated_at"`
+}
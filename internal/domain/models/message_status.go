@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Delivery statuses of an outbound message. Queued is set when the message is
+// handed to the outbound queue, accepted when the WhatsApp API returns the
+// message ID, the others come from status webhooks.
+const (
+	DeliveryQueued    = "queued"
+	DeliveryAccepted  = "accepted"
+	DeliverySent      = "sent"
+	DeliveryDelivered = "delivered"
+	DeliveryRead      = "read"
+	DeliveryFailed    = "failed"
+)
+
+// MessageStatus is the delivery status of a message sent to a user
+type MessageStatus struct {
+	MessageID  string    `json:"message_id"` // ID returned by the WhatsApp API (wamid)
+	UserID     string    `json:"user_id"`    // Recipient
+	Status     string    `json:"status"`
+	ErrorCode  int       `json:"error_code,omitempty"`
+	ErrorTitle string    `json:"error_title,omitempty"`
+	AcceptedAt time.Time `json:"accepted_at,omitempty"` // When the WhatsApp API accepted the message
+	UpdatedAt  time.Time `json:"updated_at"`            // When the status last changed
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Kinds of reminder
+const (
+	ReminderAppointment = "appointment" // Sent ahead of a booked appointment
+	ReminderCallback    = "callback"    // Sent when staff starts answering a request made after hours
+)
+
+// Reminder statuses
+const (
+	ReminderPending   = "pending"
+	ReminderSent      = "sent"
+	ReminderCancelled = "cancelled" // Cancelled, or no longer relevant when it came due
+)
+
+// Reminder is a message scheduled to be sent to a patient later
+type Reminder struct {
+	ID            string    `json:"id"` // Derived from what it reminds of, so it can be replaced or cancelled
+	Kind          string    `json:"kind"`
+	UserID        string    `json:"user_id"`
+	AppointmentID string    `json:"appointment_id,omitempty"`
+	Text          string    `json:"text"`
+	DueAt         time.Time `json:"due_at"`
+	ExpiresAt     time.Time `json:"expires_at"` // Not sent after this, e.g. once the appointment started
+	Status        string    `json:"status"`
+	CreatedAt     time.Time `json:"created_at"`
+	SentAt        time.Time `json:"sent_at,omitempty"`
+}
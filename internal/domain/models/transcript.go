@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Transcript directions
+const (
+	DirectionInbound  = "inbound"
+	DirectionOutbound = "outbound"
+)
+
+// TranscriptEntry is a message exchanged with a patient
+type TranscriptEntry struct {
+	ID          string    `json:"id,omitempty"` // Set on outbound messages, to record their delivery once known
+	UserID      string    `json:"user_id"`
+	MessageID   string    `json:"message_id,omitempty"` // WhatsApp message ID, when known
+	Direction   string    `json:"direction"`            // "inbound" or "outbound"
+	Type        string    `json:"type"`                 // WhatsApp message type
+	Text        string    `json:"text,omitempty"`
+	MediaRef    string    `json:"media_ref,omitempty"` // Blob store reference of received media
+	StateBefore string    `json:"state_before,omitempty"`
+	StateAfter  string    `json:"state_after,omitempty"`
+	Status      string    `json:"status,omitempty"` // Delivery of outbound messages: queued, accepted or failed
+	Timestamp   time.Time `json:"timestamp"`
+}
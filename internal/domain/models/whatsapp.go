@@ -4,12 +4,31 @@ import "time"
 
 // WhatsAppMessage represents a WhatsApp message
 type WhatsAppMessage struct {
-	ID        string    `json:"id"`
-	From      string    `json:"from"`
-	To        string    `json:"to"`
-	Text      string    `json:"text"`
-	Type      string    `json:"type"`
-	Timestamp time.Time `json:"timestamp"`
+	ID        string        `json:"id"`
+	From      string        `json:"from"`
+	To        string        `json:"to"`
+	Text      string        `json:"text"`
+	Type      string        `json:"type"`
+	Media     *WebhookMedia `json:"media,omitempty"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// WebhookMedia represents the media attached to an image, document, audio or video message
+type WebhookMedia struct {
+	ID       string `json:"id"`
+	MimeType string `json:"mime_type"`
+	SHA256   string `json:"sha256,omitempty"`
+	Caption  string `json:"caption,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// MediaReference points to a media file downloaded into the blob store
+type MediaReference struct {
+	Ref      string `json:"ref"`
+	MimeType string `json:"mime_type"`
+	Filename string `json:"filename,omitempty"`
+	Caption  string `json:"caption,omitempty"`
+	Size     int64  `json:"size"`
 }
 
 // WhatsAppWebhook represents the webhook payload from WhatsApp
@@ -31,46 +50,207 @@ type WhatsAppWebhook struct {
 					Text      struct {
 						Body string `json:"body"`
 					} `json:"text"`
-					Type string `json:"type"`
+					Interactive *WebhookInteractive `json:"interactive,omitempty"`
+					Image       *WebhookMedia       `json:"image,omitempty"`
+					Document    *WebhookMedia       `json:"document,omitempty"`
+					Audio       *WebhookMedia       `json:"audio,omitempty"`
+					Video       *WebhookMedia       `json:"video,omitempty"`
+					Type        string              `json:"type"`
 				} `json:"messages"`
+				Statuses []WebhookStatus `json:"statuses"`
 			} `json:"value"`
 			Field string `json:"field"`
 		} `json:"changes"`
 	} `json:"entry"`
 }
 
+// WebhookStatus is a delivery status callback for a message we sent
+type WebhookStatus struct {
+	ID          string         `json:"id"` // ID of the message the status refers to
+	RecipientID string         `json:"recipient_id"`
+	Status      string         `json:"status"`    // "sent", "delivered", "read" or "failed"
+	Timestamp   string         `json:"timestamp"` // Unix seconds
+	Errors      []WebhookError `json:"errors,omitempty"`
+}
+
+// WebhookError describes why a message could not be delivered
+type WebhookError struct {
+	Code    int    `json:"code"`
+	Title   string `json:"title"`
+	Message string `json:"message,omitempty"`
+}
+
 // WhatsAppResponse represents the response to send to WhatsApp
 type WhatsAppResponse struct {
-	MessagingProduct string `json:"messaging_product"`
-	To               string `json:"to"`
-	Type             string `json:"type"`
-	Text             struct {
-		Body string `json:"body"`
-	} `json:"text"`
+	MessagingProduct string              `json:"messaging_product"`
+	To               string              `json:"to"`
+	Type             string              `json:"type"`
+	Text             TextContent         `json:"text"`
+	Interactive      *InteractiveContent `json:"interactive,omitempty"`
+	Template         *TemplateContent    `json:"template,omitempty"`
+
+	// Conversation states around the reply and the transcript entry recording
+	// it, kept for transcripts and not sent to WhatsApp
+	StateBefore  string `json:"-"`
+	StateAfter   string `json:"-"`
+	TranscriptID string `json:"-"`
+
+	// StaffNotification marks messages to staff, which are never replaced by
+	// the service window template meant for patients
+	StaffNotification bool `json:"-"`
+
+	// ReminderID is the reminder the message delivers. Reminders are marked
+	// sent when queued, so a dead letter keeps it for staff to follow up.
+	ReminderID string `json:"-"`
+}
+
+// TextContent represents the body of a text message
+type TextContent struct {
+	Body string `json:"body"`
+}
+
+// InteractiveContent represents a reply-button or list message
+type InteractiveContent struct {
+	Type   string            `json:"type"` // "button" or "list"
+	Body   TextContent       `json:"body"`
+	Action InteractiveAction `json:"action"`
+}
+
+// InteractiveAction holds the buttons or list sections of an interactive message
+type InteractiveAction struct {
+	Button   string               `json:"button,omitempty"` // Label of the button opening a list
+	Buttons  []InteractiveButton  `json:"buttons,omitempty"`
+	Sections []InteractiveSection `json:"sections,omitempty"`
+}
+
+// InteractiveButton represents a reply button
+type InteractiveButton struct {
+	Type  string           `json:"type"` // Always "reply"
+	Reply InteractiveReply `json:"reply"`
+}
+
+// InteractiveReply represents the ID and title of a reply button
+type InteractiveReply struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// InteractiveSection represents a group of rows in a list message
+type InteractiveSection struct {
+	Title string           `json:"title,omitempty"`
+	Rows  []InteractiveRow `json:"rows"`
+}
+
+// InteractiveRow represents a selectable row in a list message
+type InteractiveRow struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// TemplateContent represents a pre-approved message template
+type TemplateContent struct {
+	Name       string              `json:"name"`
+	Language   TemplateLanguage    `json:"language"`
+	Components []TemplateComponent `json:"components,omitempty"`
+}
+
+// TemplateLanguage identifies the translation of a template, e.g. "es_AR"
+type TemplateLanguage struct {
+	Code string `json:"code"`
+}
+
+// TemplateComponent fills the variables of a part of a template
+type TemplateComponent struct {
+	Type       string              `json:"type"` // "header", "body" or "button"
+	Parameters []TemplateParameter `json:"parameters"`
+}
+
+// TemplateParameter is the value of a template variable
+type TemplateParameter struct {
+	Type string `json:"type"` // Always "text"
+	Text string `json:"text"`
+}
+
+// WebhookInteractive represents the user's answer to an interactive message
+type WebhookInteractive struct {
+	Type        string `json:"type"` // "button_reply" or "list_reply"
+	ButtonReply *struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	} `json:"button_reply,omitempty"`
+	ListReply *struct {
+		ID          string `json:"id"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	} `json:"list_reply,omitempty"`
+}
+
+// SelectedID returns the ID of the selected button or list row
+func (i *WebhookInteractive) SelectedID() string {
+	switch {
+	case i.ButtonReply != nil:
+		return i.ButtonReply.ID
+	case i.ListReply != nil:
+		return i.ListReply.ID
+	default:
+		return ""
+	}
 }
 
 // ChatbotState represents the current state of a user conversation
 type ChatbotState struct {
-	UserID    string            `json:"user_id"`
-	State     string            `json:"state"`
-	Option    string            `json:"option,omitempty"`
-	Data      map[string]string `json:"data,omitempty"`
-	CreatedAt time.Time         `json:"created_at"`
-	UpdatedAt time.Time         `json:"updated_at"`
+	UserID          string            `json:"user_id"`
+	State           string            `json:"state"`
+	Option          string            `json:"option,omitempty"`
+	Data            map[string]string `json:"data,omitempty"`
+	FieldIndex      int               `json:"field_index,omitempty"`      // Field of the current flow being asked
+	History         []string          `json:"history,omitempty"`          // States the user navigated through, most recent last
+	InvalidAttempts int               `json:"invalid_attempts,omitempty"` // Consecutive answers that were not understood
+	Handoff         bool              `json:"handoff,omitempty"`          // Staff is answering in person, the bot stays silent
+	HandoffAt       time.Time         `json:"handoff_at,omitempty"`       // When the current handoff started
+	Emergency       bool              `json:"emergency,omitempty"`        // The patient described an emergency
+	EmergencyAt     time.Time         `json:"emergency_at,omitempty"`     // When the last emergency was detected
+	Booking         *BookingProgress  `json:"booking,omitempty"`          // Appointment being booked in a booking flow
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
 }
 
 // ChatbotOption represents a menu option
 type ChatbotOption struct {
-	ID          string `json:"id"`
-	Label       string `json:"label"`
-	Description string `json:"description"`
-	NextState   string `json:"next_state"`
+	ID          string `json:"id" yaml:"id"`
+	Label       string `json:"label" yaml:"label"`
+	Title       string `json:"title,omitempty" yaml:"title,omitempty"` // Short title for interactive menus, defaults to the description
+	Description string `json:"description" yaml:"description"`
+	NextState   string `json:"next_state" yaml:"next_state"`
 }
 
 // ChatbotFlow represents the conversation flow
 type ChatbotFlow struct {
-	State       string          `json:"state"`
-	Message     string          `json:"message"`
-	Options     []ChatbotOption `json:"options,omitempty"`
-	DataRequest string          `json:"data_request,omitempty"`
+	State       string          `json:"state" yaml:"state"`
+	Message     string          `json:"message" yaml:"message"`
+	Options     []ChatbotOption `json:"options,omitempty" yaml:"options,omitempty"`
+	DataRequest string          `json:"data_request,omitempty" yaml:"data_request,omitempty"`
+	Fields      []DataField     `json:"fields,omitempty" yaml:"fields,omitempty"`         // Asked one by one before moving to NextState
+	NextState   string          `json:"next_state,omitempty" yaml:"next_state,omitempty"` // State to move to once the data request is answered
+	Handoff     bool            `json:"handoff,omitempty" yaml:"handoff,omitempty"`       // Hand the conversation to staff once its data is collected
+	Booking     bool            `json:"booking,omitempty" yaml:"booking,omitempty"`       // Book an appointment in the bot when a calendar is configured
+	System      bool            `json:"system,omitempty" yaml:"system,omitempty"`         // Used by the bot itself, not reached through a transition
+}
+
+// Data field types
+const (
+	FieldTypeName  = "name"
+	FieldTypeAge   = "age"
+	FieldTypeText  = "text"
+	FieldTypeMedia = "media"
+	FieldTypeDate  = "date"
+)
+
+// DataField represents a single answer collected by a flow
+type DataField struct {
+	Key          string `json:"key" yaml:"key"`
+	Type         string `json:"type" yaml:"type"`
+	Prompt       string `json:"prompt" yaml:"prompt"`
+	ErrorMessage string `json:"error_message,omitempty" yaml:"error_message,omitempty"` // Shown before the prompt on invalid input
 }
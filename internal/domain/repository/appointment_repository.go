@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/domain/models"
+)
+
+// AppointmentRepository stores the appointments booked by patients
+type AppointmentRepository interface {
+	// Book reserves the slot of an appointment and assigns the appointment an ID.
+	// It fails with errors.ErrSlotTaken when the slot is already booked, so two
+	// patients can never hold the same slot.
+	Book(appointment *models.Appointment) error
+	// Cancel frees the slot of a booked appointment, or returns errors.ErrAppointmentNotFound
+	Cancel(id string, cancelledAt time.Time) (*models.Appointment, error)
+	// ListByUser returns the booked appointments of a user starting after from, earliest first
+	ListByUser(userID string, from time.Time) ([]*models.Appointment, error)
+	// ListBooked returns the booked appointments starting in [from, to), earliest first
+	ListBooked(from, to time.Time) ([]*models.Appointment, error)
+}
+
+// NewAppointmentID returns a random appointment ID
+func NewAppointmentID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// SlotKey identifies the slot of an appointment, so that backends can keep one booking per slot
+func SlotKey(locationID string, start time.Time) string {
+	return locationID + "@" + start.UTC().Format(time.RFC3339)
+}
+
+// sortAppointments orders appointments by start time
+func sortAppointments(appointments []*models.Appointment) {
+	sort.Slice(appointments, func(i, j int) bool {
+		return appointments[i].Start.Before(appointments[j].Start)
+	})
+}
+
+// InMemoryAppointmentRepository implements AppointmentRepository using in-memory storage.
+// Appointments are lost on restart, use a persistent backend in production.
+type InMemoryAppointmentRepository struct {
+	appointments map[string]*models.Appointment
+	slots        map[string]string // Slot key to the ID of the appointment holding it
+	mutex        sync.RWMutex
+}
+
+// NewInMemoryAppointmentRepository creates a new in-memory appointment repository
+func NewInMemoryAppointmentRepository() *InMemoryAppointmentRepository {
+	return &InMemoryAppointmentRepository{
+		appointments: make(map[string]*models.Appointment),
+		slots:        make(map[string]string),
+	}
+}
+
+// Book reserves the slot of an appointment
+func (r *InMemoryAppointmentRepository) Book(appointment *models.Appointment) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := SlotKey(appointment.LocationID, appointment.Start)
+	if _, taken := r.slots[key]; taken {
+		return errors.ErrSlotTaken
+	}
+
+	if appointment.ID == "" {
+		appointment.ID = NewAppointmentID()
+	}
+	appointment.Status = models.AppointmentBooked
+
+	stored := *appointment
+	r.appointments[stored.ID] = &stored
+	r.slots[key] = stored.ID
+	return nil
+}
+
+// Cancel frees the slot of a booked appointment
+func (r *InMemoryAppointmentRepository) Cancel(id string, cancelledAt time.Time) (*models.Appointment, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	appointment, exists := r.appointments[id]
+	if !exists || appointment.Status != models.AppointmentBooked {
+		return nil, errors.ErrAppointmentNotFound
+	}
+
+	appointment.Status = models.AppointmentCancelled
+	appointment.CancelledAt = cancelledAt
+	delete(r.slots, SlotKey(appointment.LocationID, appointment.Start))
+
+	cancelled := *appointment
+	return &cancelled, nil
+}
+
+// ListByUser returns the booked appointments of a user starting after from
+func (r *InMemoryAppointmentRepository) ListByUser(userID string, from time.Time) ([]*models.Appointment, error) {
+	return r.list(func(appointment *models.Appointment) bool {
+		return appointment.UserID == userID && appointment.Start.After(from)
+	}), nil
+}
+
+// ListBooked returns the booked appointments starting in [from, to)
+func (r *InMemoryAppointmentRepository) ListBooked(from, to time.Time) ([]*models.Appointment, error) {
+	return r.list(func(appointment *models.Appointment) bool {
+		return !appointment.Start.Before(from) && appointment.Start.Before(to)
+	}), nil
+}
+
+func (r *InMemoryAppointmentRepository) list(match func(*models.Appointment) bool) []*models.Appointment {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var appointments []*models.Appointment
+	for _, appointment := range r.appointments {
+		if appointment.Status == models.AppointmentBooked && match(appointment) {
+			copied := *appointment
+			appointments = append(appointments, &copied)
+		}
+	}
+
+	sortAppointments(appointments)
+	return appointments
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"sort"
 	"sync"
 	"time"
 
@@ -12,6 +13,12 @@ import (
 type ChatbotRepository interface {
 	GetUserState(userID string) (*models.ChatbotState, error)
 	SaveUserState(state *models.ChatbotState) error
+	ListUserStates() ([]*models.ChatbotState, error)
+	// CountUserStates returns how many sessions have not expired, without loading them
+	CountUserStates() (int, error)
+	// DeleteUserState removes the session of a user. An expired session is removed
+	// too, but reported as ErrUserNotFound like a missing one.
+	DeleteUserState(userID string) error
 	GetFlowByState(state string) (*models.ChatbotFlow, error)
 	GetAllFlows() (map[string]*models.ChatbotFlow, error)
 	StartSessionCleanup(expirationHours, cleanupIntervalMin int)
@@ -20,189 +27,98 @@ type ChatbotRepository interface {
 
 // InMemoryChatbotRepository implements ChatbotRepository using in-memory storage
 type InMemoryChatbotRepository struct {
+	*FlowCatalog
 	userStates      map[string]*models.ChatbotState
-	flows           map[string]*models.ChatbotFlow
 	mutex           sync.RWMutex
 	stopCleanup     chan bool
 	expirationHours int
 }
 
-// NewInMemoryChatbotRepository creates a new in-memory repository
-func NewInMemoryChatbotRepository() *InMemoryChatbotRepository {
-	repo := &InMemoryChatbotRepository{
+// NewInMemoryChatbotRepository creates a new in-memory repository serving the given flows
+func NewInMemoryChatbotRepository(flows map[string]*models.ChatbotFlow) *InMemoryChatbotRepository {
+	return &InMemoryChatbotRepository{
+		FlowCatalog:     NewFlowCatalog(flows),
 		userStates:      make(map[string]*models.ChatbotState),
-		flows:           make(map[string]*models.ChatbotFlow),
 		stopCleanup:     make(chan bool),
 		expirationHours: 24, // Default to 24 hours
 	}
-
-	// Initialize default flows
-	repo.initializeFlows()
-
-	return repo
 }
 
-// GetUserState retrieves the current state of a user
+// GetUserState retrieves a copy of the current state of a user
 func (r *InMemoryChatbotRepository) GetUserState(userID string) (*models.ChatbotState, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
 	state, exists := r.userStates[userID]
 	if !exists {
-		return &models.ChatbotState{
-			UserID:    userID,
-			State:     "welcome",
-			Data:      make(map[string]string),
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		}, nil
+		return NewUserState(userID), nil
 	}
 
 	// Check if session has expired (lazy cleanup)
-	if r.isSessionExpired(state) {
+	if IsSessionExpired(state, r.expirationHours) {
 		// Return a fresh state instead of the expired one
-		return &models.ChatbotState{
-			UserID:    userID,
-			State:     "welcome",
-			Data:      make(map[string]string),
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		}, nil
+		return NewUserState(userID), nil
 	}
 
-	return state, nil
+	return copyUserState(state), nil
 }
 
-// SaveUserState saves the current state of a user
+// SaveUserState saves a copy of the current state of a user
 func (r *InMemoryChatbotRepository) SaveUserState(state *models.ChatbotState) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	r.userStates[state.UserID] = state
+	r.userStates[state.UserID] = copyUserState(state)
 	return nil
 }
 
-// GetFlowByState retrieves the flow configuration for a given state
-func (r *InMemoryChatbotRepository) GetFlowByState(state string) (*models.ChatbotFlow, error) {
-	flow, exists := r.flows[state]
-	if !exists {
-		return nil, errors.ErrFlowNotFound
-	}
-	return flow, nil
-}
-
-// GetAllFlows retrieves all available flows
-func (r *InMemoryChatbotRepository) GetAllFlows() (map[string]*models.ChatbotFlow, error) {
-	return r.flows, nil
-}
-
-// initializeFlows sets up the default conversation flows
-func (r *InMemoryChatbotRepository) initializeFlows() {
-	// Welcome flow
-	r.flows["welcome"] = &models.ChatbotFlow{
-		State: "welcome",
-		Message: `🤖 Chatbot BabyHome – Dra. Carla Narváez
-👋 ¡Hola! Gracias por comunicarte.
-Por favor, seleccioná una opción escribiendo la letra correspondiente:
-A. Realizar consulta médica telefónica
-B. Enviar estudios para lectura
-C. Solicitar turno en consultorio
-D. Consulta sobre BabyHome
-(Si es una urgencia, por favor acudí a una guardia)`,
-		Options: []models.ChatbotOption{
-			{ID: "A", Label: "A", Description: "Realizar consulta médica telefónica", NextState: "option_a"},
-			{ID: "B", Label: "B", Description: "Enviar estudios para lectura", NextState: "option_b"},
-			{ID: "C", Label: "C", Description: "Solicitar turno en consultorio", NextState: "option_c"},
-			{ID: "D", Label: "D", Description: "Consulta sobre BabyHome", NextState: "option_d"},
-		},
-	}
-
-	// Option A flow - Consulta médica telefónica
-	r.flows["option_a"] = &models.ChatbotFlow{
-		State: "option_a",
-		Message: `La consulta telefónica es un acto médico y tiene un valor de $15.000 ARS (no cubierta por obra social).
-Para avanzar, enviá:
-1️⃣ Nombre y edad del paciente
-2️⃣ Motivo de la consulta
-3️⃣ Comprobante de pago (Alias: Narvaez.Carla.B)
-
-Información importante:
-https://appar.com.ar/consulta-pediatrica-online/
+// ListUserStates returns copies of the sessions that have not expired, most recently active first
+func (r *InMemoryChatbotRepository) ListUserStates() ([]*models.ChatbotState, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
 
-📌 Una vez completados estos pasos, la Dra. se pondrá en contacto.`,
-		DataRequest: "datos_consulta_medica",
+	states := make([]*models.ChatbotState, 0, len(r.userStates))
+	for _, state := range r.userStates {
+		if !IsSessionExpired(state, r.expirationHours) {
+			states = append(states, copyUserState(state))
+		}
 	}
 
-	// Option B flow - Lectura de estudios
-	r.flows["option_b"] = &models.ChatbotFlow{
-		State: "option_b",
-		Message: `Por favor enviá:
-1️⃣ Fotos claras o PDF de los estudios
-2️⃣ Síntomas actuales y fecha de realización
-3️⃣ Tu duda o pregunta principal
-4️⃣ Comprobante de pago (Alias: Narvaez.Carla.B) $15.000 ARS
-
-Información importante:
-https://appar.com.ar/consulta-pediatrica-online/
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].UpdatedAt.After(states[j].UpdatedAt)
+	})
+	return states, nil
+}
 
-📌 Una vez completados estos pasos, la Dra. se pondrá en contacto.`,
-		DataRequest: "datos_lectura_estudios",
-	}
+// CountUserStates returns how many sessions have not expired
+func (r *InMemoryChatbotRepository) CountUserStates() (int, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
 
-	// Option C flow - Solicitar turno en consultorio
-	r.flows["option_c"] = &models.ChatbotFlow{
-		State: "option_c",
-		Message: `Para turnos comunicarse a los siguientes números
-– Centro Médico Cervantes (WhatsApp: 343-4066281)
-– Consultorios OSPEP (WhatsApp: 343-5138637)`,
-		DataRequest: "datos_turno",
+	count := 0
+	for _, state := range r.userStates {
+		if !IsSessionExpired(state, r.expirationHours) {
+			count++
+		}
 	}
+	return count, nil
+}
 
-	// Option D flow - Información sobre BabyHome
-	r.flows["option_d"] = &models.ChatbotFlow{
-		State: "option_d",
-		Message: `💜 ¡Qué alegría que te interese BabyHome!
-Ofrecemos:
-✅ Consulta prenatal
-✅ Recepción neonatal personalizada (COPAP y primera hora siempre que mamá y bebé estén clínicamente bien)
-✅ Controles en domicilio
-
-Para orientarte, contanos:
-1️⃣ Semana de embarazo / FPP
-2️⃣ Maternidad y obstetra
-3️⃣ Si desean priorizar COPAP/primera hora
-4️⃣ Si quieren coordinar una consulta prenatal`,
-		DataRequest: "datos_babyhome",
-	}
+// DeleteUserState removes the session of a user
+func (r *InMemoryChatbotRepository) DeleteUserState(userID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 
-	// Data collection flows
-	r.flows["collecting_data"] = &models.ChatbotFlow{
-		State: "collecting_data",
-		Message: `Gracias por la información. ¿Hay algo más en lo que pueda ayudarte?
-Por favor, seleccioná una opción escribiendo la letra correspondiente:
-A. Realizar consulta médica telefónica
-B. Enviar estudios para lectura
-C. Solicitar turno en consultorio
-D. Consulta sobre BabyHome`,
-		Options: []models.ChatbotOption{
-			{ID: "A", Label: "A", Description: "Realizar consulta médica telefónica", NextState: "option_a"},
-			{ID: "B", Label: "B", Description: "Enviar estudios para lectura", NextState: "option_b"},
-			{ID: "C", Label: "C", Description: "Solicitar turno en consultorio", NextState: "option_c"},
-			{ID: "D", Label: "D", Description: "Consulta sobre BabyHome", NextState: "option_d"},
-		},
+	state, exists := r.userStates[userID]
+	if !exists {
+		return errors.ErrUserNotFound
 	}
 
-	// Invalid option validation flow
-	r.flows["invalid_option"] = &models.ChatbotFlow{
-		State:   "invalid_option",
-		Message: `⚠️ Por favor, ingresa una opción válida (A, B, C o D).`,
+	delete(r.userStates, userID)
+	if IsSessionExpired(state, r.expirationHours) {
+		return errors.ErrUserNotFound
 	}
-}
-
-// isSessionExpired checks if a session has expired based on UpdatedAt timestamp
-func (r *InMemoryChatbotRepository) isSessionExpired(state *models.ChatbotState) bool {
-	expirationDuration := time.Duration(r.expirationHours) * time.Hour
-	return time.Since(state.UpdatedAt) > expirationDuration
+	return nil
 }
 
 // StartSessionCleanup starts the background cleanup goroutine
@@ -254,3 +170,32 @@ func (r *InMemoryChatbotRepository) cleanupExpiredSessions() {
 		// fmt.Printf("Cleaned up %d expired sessions\n", len(expiredUsers))
 	}
 }
+
+// copyUserState returns a deep copy of state, so callers never share the
+// stored session with each other
+func copyUserState(state *models.ChatbotState) *models.ChatbotState {
+	copied := *state
+	copied.Data = make(map[string]string, len(state.Data))
+	for key, value := range state.Data {
+		copied.Data[key] = value
+	}
+	if state.History != nil {
+		copied.History = append([]string(nil), state.History...)
+	}
+	if state.Booking != nil {
+		booking := *state.Booking
+		if booking.Offered != nil {
+			booking.Offered = append([]models.Slot(nil), booking.Offered...)
+		}
+		if booking.Slot != nil {
+			slot := *booking.Slot
+			booking.Slot = &slot
+		}
+		if booking.Existing != nil {
+			existing := *booking.Existing
+			booking.Existing = &existing
+		}
+		copied.Booking = &booking
+	}
+	return &copied
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	domainerrors "chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/domain/models"
+)
+
+func TestInMemoryChatbotRepository_DeleteUserState(t *testing.T) {
+	repo := NewInMemoryChatbotRepository(nil)
+
+	now := time.Now()
+	repo.SaveUserState(&models.ChatbotState{UserID: "active", State: "option_a", CreatedAt: now, UpdatedAt: now})
+	repo.SaveUserState(&models.ChatbotState{UserID: "expired", State: "option_b", CreatedAt: now, UpdatedAt: now.Add(-48 * time.Hour)})
+
+	if err := repo.DeleteUserState("active"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := repo.DeleteUserState("active"); !errors.Is(err, domainerrors.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound deleting a missing session, got %v", err)
+	}
+
+	// An expired session is removed, but reported as missing
+	if err := repo.DeleteUserState("expired"); !errors.Is(err, domainerrors.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound deleting an expired session, got %v", err)
+	}
+	if _, exists := repo.userStates["expired"]; exists {
+		t.Error("Expected the expired session to be removed")
+	}
+}
+
+func TestInMemoryChatbotRepository_ReturnsCopies(t *testing.T) {
+	repo := NewInMemoryChatbotRepository(nil)
+
+	state := NewUserState("user123")
+	state.Data["nombre"] = "Juan"
+	state.History = []string{InitialState}
+	state.Booking = &models.BookingProgress{Step: "slot", Offered: []models.Slot{{LocationID: "centro"}}, Slot: &models.Slot{LocationID: "centro"}}
+	repo.SaveUserState(state)
+	// Changes after saving are not stored
+	state.Data["nombre"] = "Pedro"
+
+	stored, _ := repo.GetUserState("user123")
+	stored.State = "option_a"
+	stored.Data["edad"] = "8"
+	stored.History[0] = "option_a"
+	stored.Booking.Offered[0].LocationID = "norte"
+	stored.Booking.Slot.LocationID = "norte"
+
+	listed, _ := repo.ListUserStates()
+	listed[0].Data["apellido"] = "Pérez"
+
+	again, _ := repo.GetUserState("user123")
+	if again.State != InitialState || len(again.Data) != 1 || again.Data["nombre"] != "Juan" || again.History[0] != InitialState {
+		t.Errorf("Expected the stored session unchanged, got %+v", again)
+	}
+	if again.Booking.Offered[0].LocationID != "centro" || again.Booking.Slot.LocationID != "centro" {
+		t.Errorf("Expected the stored booking unchanged, got %+v", again.Booking)
+	}
+}
+
+func TestInMemoryChatbotRepository_CountUserStates(t *testing.T) {
+	repo := NewInMemoryChatbotRepository(nil)
+	repo.SaveUserState(NewUserState("user1"))
+	repo.SaveUserState(NewUserState("user2"))
+	expired := NewUserState("expired")
+	expired.UpdatedAt = time.Now().Add(-48 * time.Hour)
+	repo.SaveUserState(expired)
+
+	if count, err := repo.CountUserStates(); err != nil || count != 2 {
+		t.Errorf("Expected 2 active sessions, got %d (%v)", count, err)
+	}
+}
+
+func TestInMemoryChatbotRepository_ConcurrentAccess(t *testing.T) {
+	repo := NewInMemoryChatbotRepository(nil)
+	repo.SaveUserState(NewUserState("user123"))
+
+	// Run with -race: handlers update a session while the admin API lists it
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			state, _ := repo.GetUserState("user123")
+			state.Data["step"] = strconv.Itoa(i)
+			state.UpdatedAt = time.Now()
+			repo.SaveUserState(state)
+		}(i)
+		go func() {
+			defer wg.Done()
+			states, _ := repo.ListUserStates()
+			for _, state := range states {
+				_ = state.Data["step"]
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"sync"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+// DeadLetterRepository keeps permanently failed messages for inspection
+type DeadLetterRepository interface {
+	// Add stores a dead letter, discarding the oldest ones past the capacity
+	Add(letter *models.DeadLetter) error
+	// List returns the stored dead letters, oldest first
+	List() ([]*models.DeadLetter, error)
+}
+
+// InMemoryDeadLetterRepository keeps the most recent dead letters in memory.
+// They are lost on restart, use a persistent backend in production.
+type InMemoryDeadLetterRepository struct {
+	letters  []*models.DeadLetter
+	capacity int
+	mutex    sync.RWMutex
+}
+
+// NewInMemoryDeadLetterRepository creates a repository holding at most capacity letters
+func NewInMemoryDeadLetterRepository(capacity int) *InMemoryDeadLetterRepository {
+	return &InMemoryDeadLetterRepository{
+		capacity: capacity,
+	}
+}
+
+// Add stores a dead letter, discarding the oldest one when full
+func (r *InMemoryDeadLetterRepository) Add(letter *models.DeadLetter) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.letters = append(r.letters, letter)
+	if len(r.letters) > r.capacity {
+		r.letters = r.letters[len(r.letters)-r.capacity:]
+	}
+	return nil
+}
+
+// List returns the stored dead letters, oldest first
+func (r *InMemoryDeadLetterRepository) List() ([]*models.DeadLetter, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	letters := make([]*models.DeadLetter, len(r.letters))
+	copy(letters, r.letters)
+	return letters, nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"sync"
+	"time"
+)
+
+// LastInboundRepository remembers when each user last wrote to us, which opens
+// WhatsApp's 24 hour service window. It is kept apart from sessions, which can
+// expire sooner or be deleted by staff while the window is still open.
+type LastInboundRepository interface {
+	// Touch records that the user wrote to us at the given time
+	Touch(userID string, at time.Time) error
+	// LastInbound returns when the user last wrote to us, the zero time if not within the TTL
+	LastInbound(userID string) (time.Time, error)
+	StartCleanup(cleanupIntervalMin int)
+	StopCleanup()
+}
+
+// InMemoryLastInboundRepository implements LastInboundRepository using in-memory storage
+type InMemoryLastInboundRepository struct {
+	lastInbound map[string]time.Time
+	ttl         time.Duration
+	mutex       sync.RWMutex
+	stopCleanup chan bool
+}
+
+// NewInMemoryLastInboundRepository creates a repository remembering messages for ttl
+func NewInMemoryLastInboundRepository(ttl time.Duration) *InMemoryLastInboundRepository {
+	return &InMemoryLastInboundRepository{
+		lastInbound: make(map[string]time.Time),
+		ttl:         ttl,
+		stopCleanup: make(chan bool),
+	}
+}
+
+// Touch records that the user wrote to us at the given time
+func (r *InMemoryLastInboundRepository) Touch(userID string, at time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.lastInbound[userID] = at
+	return nil
+}
+
+// LastInbound returns when the user last wrote to us, the zero time if not within the TTL
+func (r *InMemoryLastInboundRepository) LastInbound(userID string) (time.Time, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	at, exists := r.lastInbound[userID]
+	if !exists || time.Since(at) > r.ttl {
+		return time.Time{}, nil
+	}
+	return at, nil
+}
+
+// StartCleanup starts the background goroutine forgetting expired messages
+func (r *InMemoryLastInboundRepository) StartCleanup(cleanupIntervalMin int) {
+	go func() {
+		ticker := time.NewTicker(time.Duration(cleanupIntervalMin) * time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.cleanupExpired()
+			case <-r.stopCleanup:
+				return
+			}
+		}
+	}()
+}
+
+// StopCleanup stops the background cleanup goroutine
+func (r *InMemoryLastInboundRepository) StopCleanup() {
+	close(r.stopCleanup)
+}
+
+// cleanupExpired forgets messages older than the TTL
+func (r *InMemoryLastInboundRepository) cleanupExpired() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	for userID, at := range r.lastInbound {
+		if now.Sub(at) > r.ttl {
+			delete(r.lastInbound, userID)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInMemoryLastInboundRepository_Touch(t *testing.T) {
+	repo := NewInMemoryLastInboundRepository(time.Hour)
+	now := time.Now()
+
+	if at, err := repo.LastInbound("user123"); err != nil || !at.IsZero() {
+		t.Fatalf("Expected no message from an unknown user, got %v err=%v", at, err)
+	}
+
+	repo.Touch("user123", now.Add(-time.Minute))
+	repo.Touch("user123", now)
+
+	if at, _ := repo.LastInbound("user123"); !at.Equal(now) {
+		t.Errorf("Expected the latest message at %v, got %v", now, at)
+	}
+
+	repo.Touch("user456", now.Add(-2*time.Hour))
+	if at, _ := repo.LastInbound("user456"); !at.IsZero() {
+		t.Errorf("Expected a message older than the TTL to be ignored, got %v", at)
+	}
+}
+
+func TestInMemoryLastInboundRepository_Cleanup(t *testing.T) {
+	repo := NewInMemoryLastInboundRepository(time.Hour)
+	repo.Touch("old", time.Now().Add(-2*time.Hour))
+	repo.Touch("new", time.Now())
+
+	repo.cleanupExpired()
+
+	repo.mutex.RLock()
+	_, oldKept := repo.lastInbound["old"]
+	_, newKept := repo.lastInbound["new"]
+	repo.mutex.RUnlock()
+	if oldKept || !newKept {
+		t.Errorf("Expected only the expired message to be forgotten, got old=%v new=%v", oldKept, newKept)
+	}
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+// MessageDedupRepository remembers processed message IDs so redelivered webhooks are ignored
+type MessageDedupRepository interface {
+	// MarkProcessed records the message ID and reports whether it had already been seen
+	MarkProcessed(messageID string) (bool, error)
+	// Unmark forgets a message ID, so a redelivery of a message that failed
+	// before changing the conversation is processed again
+	Unmark(messageID string) error
+	// KeepReply holds the reply to a processed message that could not be
+	// queued, so a redelivery sends it instead of processing the message again
+	KeepReply(messageID string, response *models.WhatsAppResponse) error
+	// TakeReply returns and forgets the reply kept for a message, nil if there is none
+	TakeReply(messageID string) (*models.WhatsAppResponse, error)
+	StartCleanup(cleanupIntervalMin int)
+	StopCleanup()
+}
+
+// InMemoryMessageDedupRepository implements MessageDedupRepository using in-memory storage
+type InMemoryMessageDedupRepository struct {
+	seen        map[string]time.Time
+	replies     map[string]*models.WhatsAppResponse
+	ttl         time.Duration
+	mutex       sync.Mutex
+	stopCleanup chan bool
+}
+
+// NewInMemoryMessageDedupRepository creates a repository remembering message IDs for ttl
+func NewInMemoryMessageDedupRepository(ttl time.Duration) *InMemoryMessageDedupRepository {
+	return &InMemoryMessageDedupRepository{
+		seen:        make(map[string]time.Time),
+		replies:     make(map[string]*models.WhatsAppResponse),
+		ttl:         ttl,
+		stopCleanup: make(chan bool),
+	}
+}
+
+// MarkProcessed records the message ID and reports whether it had already been seen
+func (r *InMemoryMessageDedupRepository) MarkProcessed(messageID string) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	if seenAt, exists := r.seen[messageID]; exists && now.Sub(seenAt) <= r.ttl {
+		return true, nil
+	}
+
+	r.seen[messageID] = now
+	return false, nil
+}
+
+// Unmark forgets a message ID
+func (r *InMemoryMessageDedupRepository) Unmark(messageID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.seen, messageID)
+	delete(r.replies, messageID)
+	return nil
+}
+
+// KeepReply holds the reply to a processed message until the message ID is forgotten
+func (r *InMemoryMessageDedupRepository) KeepReply(messageID string, response *models.WhatsAppResponse) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.seen[messageID]; !exists {
+		return nil
+	}
+	kept := *response
+	r.replies[messageID] = &kept
+	return nil
+}
+
+// TakeReply returns and forgets the reply kept for a message
+func (r *InMemoryMessageDedupRepository) TakeReply(messageID string) (*models.WhatsAppResponse, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	response, exists := r.replies[messageID]
+	if !exists {
+		return nil, nil
+	}
+	delete(r.replies, messageID)
+	return response, nil
+}
+
+// StartCleanup starts the background goroutine forgetting expired message IDs
+func (r *InMemoryMessageDedupRepository) StartCleanup(cleanupIntervalMin int) {
+	r.startCleanup(time.Duration(cleanupIntervalMin) * time.Minute)
+}
+
+func (r *InMemoryMessageDedupRepository) startCleanup(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.cleanupExpired()
+			case <-r.stopCleanup:
+				return
+			}
+		}
+	}()
+}
+
+// StopCleanup stops the background cleanup goroutine
+func (r *InMemoryMessageDedupRepository) StopCleanup() {
+	close(r.stopCleanup)
+}
+
+// cleanupExpired forgets message IDs older than the TTL
+func (r *InMemoryMessageDedupRepository) cleanupExpired() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	for messageID, seenAt := range r.seen {
+		if now.Sub(seenAt) > r.ttl {
+			delete(r.seen, messageID)
+			delete(r.replies, messageID)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+func TestInMemoryMessageDedupRepository_MarkProcessed(t *testing.T) {
+	repo := NewInMemoryMessageDedupRepository(time.Hour)
+
+	if seen, _ := repo.MarkProcessed("wamid.1"); seen {
+		t.Error("Expected a new message not to be seen")
+	}
+	if seen, _ := repo.MarkProcessed("wamid.1"); !seen {
+		t.Error("Expected a redelivered message to be seen")
+	}
+	if seen, _ := repo.MarkProcessed("wamid.2"); seen {
+		t.Error("Expected another message not to be seen")
+	}
+
+	repo.Unmark("wamid.1")
+	if seen, _ := repo.MarkProcessed("wamid.1"); seen {
+		t.Error("Expected an unmarked message to be processed again")
+	}
+}
+
+func TestInMemoryMessageDedupRepository_Expiry(t *testing.T) {
+	repo := NewInMemoryMessageDedupRepository(10 * time.Millisecond)
+
+	repo.MarkProcessed("wamid.1")
+	time.Sleep(20 * time.Millisecond)
+
+	if seen, _ := repo.MarkProcessed("wamid.1"); seen {
+		t.Error("Expected the message to be forgotten after the TTL")
+	}
+}
+
+func TestInMemoryMessageDedupRepository_Cleanup(t *testing.T) {
+	repo := NewInMemoryMessageDedupRepository(10 * time.Millisecond)
+	repo.MarkProcessed("wamid.old")
+	time.Sleep(20 * time.Millisecond)
+	repo.MarkProcessed("wamid.new")
+
+	repo.cleanupExpired()
+	repo.mutex.Lock()
+	_, oldKept := repo.seen["wamid.old"]
+	_, newKept := repo.seen["wamid.new"]
+	repo.mutex.Unlock()
+	if oldKept || !newKept {
+		t.Errorf("Expected only the expired message to be forgotten, got old=%v new=%v", oldKept, newKept)
+	}
+
+	// The background loop forgets expired messages on its own
+	repo.startCleanup(5 * time.Millisecond)
+	defer repo.StopCleanup()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		repo.mutex.Lock()
+		remaining := len(repo.seen)
+		repo.mutex.Unlock()
+		if remaining == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the cleanup loop to forget every expired message, %d left", remaining)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestInMemoryMessageDedupRepository_KeepReply(t *testing.T) {
+	repo := NewInMemoryMessageDedupRepository(time.Hour)
+	repo.MarkProcessed("wamid.1")
+
+	response := &models.WhatsAppResponse{To: "user123", Type: "text", Text: models.TextContent{Body: "Recibido"}}
+	repo.KeepReply("wamid.1", response)
+	// Replies to messages never seen are not kept
+	repo.KeepReply("wamid.2", response)
+
+	kept, err := repo.TakeReply("wamid.1")
+	if err != nil || kept == nil || kept.Text.Body != "Recibido" {
+		t.Fatalf("Expected the kept reply, got %+v err=%v", kept, err)
+	}
+	if kept, _ := repo.TakeReply("wamid.1"); kept != nil {
+		t.Errorf("Expected the reply to be taken once, got %+v", kept)
+	}
+	if kept, _ := repo.TakeReply("wamid.2"); kept != nil {
+		t.Errorf("Expected no reply for an unknown message, got %+v", kept)
+	}
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+
+	"chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/domain/models"
+)
+
+// MessageStatusRepository tracks the delivery status of outbound messages
+type MessageStatusRepository interface {
+	// Update applies a status change, creating the message if it is not tracked yet.
+	// Changes that arrive out of order never move a message back to an earlier status.
+	Update(status *models.MessageStatus) error
+	// Get returns the status of a message, or errors.ErrMessageNotFound
+	Get(messageID string) (*models.MessageStatus, error)
+	// ListFailed returns the messages that could not be delivered, most recent first
+	ListFailed() ([]*models.MessageStatus, error)
+}
+
+// deliveryRank orders statuses so late webhooks don't overwrite newer ones
+var deliveryRank = map[string]int{
+	models.DeliveryAccepted:  1,
+	models.DeliverySent:      2,
+	models.DeliveryDelivered: 3,
+	models.DeliveryRead:      4,
+	models.DeliveryFailed:    5,
+}
+
+// MergeMessageStatus applies update to current, a nil current starting a new
+// record. It returns the resulting status and whether anything changed.
+func MergeMessageStatus(current, update *models.MessageStatus) (*models.MessageStatus, bool) {
+	if current == nil {
+		merged := *update
+		return &merged, true
+	}
+
+	merged := *current
+	if merged.UserID == "" {
+		merged.UserID = update.UserID
+	}
+	if merged.AcceptedAt.IsZero() {
+		merged.AcceptedAt = update.AcceptedAt
+	}
+	if deliveryRank[update.Status] <= deliveryRank[current.Status] {
+		return &merged, merged != *current
+	}
+
+	merged.Status = update.Status
+	merged.ErrorCode = update.ErrorCode
+	merged.ErrorTitle = update.ErrorTitle
+	merged.UpdatedAt = update.UpdatedAt
+	return &merged, true
+}
+
+// InMemoryMessageStatusRepository implements MessageStatusRepository using
+// in-memory storage, keeping at most capacity messages
+type InMemoryMessageStatusRepository struct {
+	statuses map[string]*models.MessageStatus
+	order    []string // Message IDs, oldest first, to evict when full
+	capacity int
+	mutex    sync.RWMutex
+}
+
+// NewInMemoryMessageStatusRepository creates a new in-memory message status repository
+func NewInMemoryMessageStatusRepository(capacity int) *InMemoryMessageStatusRepository {
+	return &InMemoryMessageStatusRepository{
+		statuses: make(map[string]*models.MessageStatus),
+		capacity: capacity,
+	}
+}
+
+// Update applies a status change
+func (r *InMemoryMessageStatusRepository) Update(status *models.MessageStatus) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	current, tracked := r.statuses[status.MessageID]
+	merged, _ := MergeMessageStatus(current, status)
+	r.statuses[status.MessageID] = merged
+
+	if !tracked {
+		r.order = append(r.order, status.MessageID)
+		if len(r.order) > r.capacity {
+			delete(r.statuses, r.order[0])
+			r.order = r.order[1:]
+		}
+	}
+	return nil
+}
+
+// Get returns the status of a message
+func (r *InMemoryMessageStatusRepository) Get(messageID string) (*models.MessageStatus, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	status, exists := r.statuses[messageID]
+	if !exists {
+		return nil, errors.ErrMessageNotFound
+	}
+	copied := *status
+	return &copied, nil
+}
+
+// ListFailed returns the messages that could not be delivered, most recent first
+func (r *InMemoryMessageStatusRepository) ListFailed() ([]*models.MessageStatus, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var failed []*models.MessageStatus
+	for _, status := range r.statuses {
+		if status.Status == models.DeliveryFailed {
+			copied := *status
+			failed = append(failed, &copied)
+		}
+	}
+
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].UpdatedAt.After(failed[j].UpdatedAt)
+	})
+	return failed, nil
+}
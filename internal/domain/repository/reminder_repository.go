@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+// ReminderRepository stores the reminders waiting to be sent
+type ReminderRepository interface {
+	// Schedule saves a pending reminder, replacing a pending one with the same ID
+	Schedule(reminder *models.Reminder) error
+	// Cancel drops a pending reminder. Unknown and already sent reminders are ignored.
+	Cancel(id string) error
+	// ListDue returns up to limit pending reminders due at now, earliest first
+	ListDue(now time.Time, limit int) ([]*models.Reminder, error)
+	// Claim marks a pending reminder as sent and reports whether this call did.
+	// Only one of the instances sharing the repository wins each reminder, the
+	// others get false, as they do for cancelled and already sent reminders.
+	Claim(id string, sentAt time.Time) (bool, error)
+}
+
+// InMemoryReminderRepository implements ReminderRepository using in-memory storage.
+// Pending reminders are lost on restart, use a persistent backend in production.
+type InMemoryReminderRepository struct {
+	pending map[string]*models.Reminder
+	mutex   sync.Mutex
+}
+
+// NewInMemoryReminderRepository creates a new in-memory reminder repository
+func NewInMemoryReminderRepository() *InMemoryReminderRepository {
+	return &InMemoryReminderRepository{
+		pending: make(map[string]*models.Reminder),
+	}
+}
+
+// Schedule saves a pending reminder
+func (r *InMemoryReminderRepository) Schedule(reminder *models.Reminder) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stored := *reminder
+	stored.Status = models.ReminderPending
+	r.pending[stored.ID] = &stored
+	return nil
+}
+
+// Cancel drops a pending reminder
+func (r *InMemoryReminderRepository) Cancel(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.pending, id)
+	return nil
+}
+
+// ListDue returns up to limit pending reminders due at now
+func (r *InMemoryReminderRepository) ListDue(now time.Time, limit int) ([]*models.Reminder, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var due []*models.Reminder
+	for _, reminder := range r.pending {
+		if !reminder.DueAt.After(now) {
+			copied := *reminder
+			due = append(due, &copied)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].DueAt.Before(due[j].DueAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// Claim forgets a pending reminder, reporting whether it was still pending
+func (r *InMemoryReminderRepository) Claim(id string, sentAt time.Time) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.pending[id]; !exists {
+		return false, nil
+	}
+	delete(r.pending, id)
+	return true, nil
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"time"
+
+	"chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/domain/models"
+)
+
+// InitialState is the state every new conversation starts in
+const InitialState = "welcome"
+
+// NewUserState returns a fresh conversation state for a user
+func NewUserState(userID string) *models.ChatbotState {
+	now := time.Now()
+	return &models.ChatbotState{
+		UserID:    userID,
+		State:     InitialState,
+		Data:      make(map[string]string),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// IsSessionExpired checks if a session has expired based on its UpdatedAt timestamp
+func IsSessionExpired(state *models.ChatbotState, expirationHours int) bool {
+	expirationDuration := time.Duration(expirationHours) * time.Hour
+	return time.Since(state.UpdatedAt) > expirationDuration
+}
+
+// FlowCatalog serves conversation flows loaded at startup. Repository
+// implementations embed it to satisfy the flow lookups of ChatbotRepository.
+type FlowCatalog struct {
+	flows map[string]*models.ChatbotFlow
+}
+
+// NewFlowCatalog creates a catalog for the given flows
+func NewFlowCatalog(flows map[string]*models.ChatbotFlow) *FlowCatalog {
+	return &FlowCatalog{flows: flows}
+}
+
+// GetFlowByState retrieves the flow configuration for a given state
+func (c *FlowCatalog) GetFlowByState(state string) (*models.ChatbotFlow, error) {
+	flow, exists := c.flows[state]
+	if !exists {
+		return nil, errors.ErrFlowNotFound
+	}
+	return flow, nil
+}
+
+// GetAllFlows retrieves all available flows
+func (c *FlowCatalog) GetAllFlows() (map[string]*models.ChatbotFlow, error) {
+	return c.flows, nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+// TranscriptRepository keeps a record of every message exchanged with patients
+type TranscriptRepository interface {
+	Append(entry *models.TranscriptEntry) error
+	// ListByUser returns the transcript of a user, oldest message first
+	ListByUser(userID string) ([]*models.TranscriptEntry, error)
+	// UpdateDelivery records the delivery status of an outbound entry, and the
+	// WhatsApp message ID unless it is empty. Unknown entries are ignored.
+	UpdateDelivery(userID, entryID, messageID, status string) error
+}
+
+// NewTranscriptEntryID returns a random transcript entry ID
+func NewTranscriptEntryID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// InMemoryTranscriptRepository implements TranscriptRepository using in-memory storage,
+// keeping the most recent capacity messages of each user. Transcripts are lost on
+// restart, use a persistent backend in production.
+type InMemoryTranscriptRepository struct {
+	entries  map[string][]*models.TranscriptEntry
+	capacity int
+	mutex    sync.RWMutex
+}
+
+// NewInMemoryTranscriptRepository creates a new in-memory transcript repository
+// keeping at most capacity messages per user
+func NewInMemoryTranscriptRepository(capacity int) *InMemoryTranscriptRepository {
+	return &InMemoryTranscriptRepository{
+		entries:  make(map[string][]*models.TranscriptEntry),
+		capacity: capacity,
+	}
+}
+
+// Append adds a message to the transcript of its user, discarding the oldest one when full
+func (r *InMemoryTranscriptRepository) Append(entry *models.TranscriptEntry) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entries := append(r.entries[entry.UserID], entry)
+	if len(entries) > r.capacity {
+		entries = entries[len(entries)-r.capacity:]
+	}
+	r.entries[entry.UserID] = entries
+	return nil
+}
+
+// ListByUser returns the transcript of a user, oldest message first
+func (r *InMemoryTranscriptRepository) ListByUser(userID string) ([]*models.TranscriptEntry, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	entries := make([]*models.TranscriptEntry, len(r.entries[userID]))
+	copy(entries, r.entries[userID])
+	return entries, nil
+}
+
+// UpdateDelivery records the delivery status of an outbound entry
+func (r *InMemoryTranscriptRepository) UpdateDelivery(userID, entryID, messageID, status string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entries := r.entries[userID]
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].ID != entryID {
+			continue
+		}
+
+		// Replace the entry, transcripts already listed keep the previous one
+		updated := *entries[i]
+		if messageID != "" {
+			updated.MessageID = messageID
+		}
+		updated.Status = status
+		entries[i] = &updated
+		return nil
+	}
+	return nil
+}
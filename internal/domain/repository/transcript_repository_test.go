@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+func TestInMemoryTranscriptRepository_KeepsRecentMessages(t *testing.T) {
+	repo := NewInMemoryTranscriptRepository(3)
+
+	for i := 1; i <= 5; i++ {
+		repo.Append(&models.TranscriptEntry{UserID: "user123", MessageID: fmt.Sprintf("wamid.%d", i)})
+	}
+	repo.Append(&models.TranscriptEntry{UserID: "user456", MessageID: "wamid.other"})
+
+	entries, _ := repo.ListByUser("user123")
+	if len(entries) != 3 || entries[0].MessageID != "wamid.3" || entries[2].MessageID != "wamid.5" {
+		t.Errorf("Expected the 3 most recent messages, oldest first, got %+v", entries)
+	}
+	if entries, _ := repo.ListByUser("user456"); len(entries) != 1 {
+		t.Errorf("Expected the cap to apply per user, got %d entries", len(entries))
+	}
+}
+
+func TestInMemoryTranscriptRepository_UpdateDelivery(t *testing.T) {
+	repo := NewInMemoryTranscriptRepository(10)
+	repo.Append(&models.TranscriptEntry{ID: "entry1", UserID: "user123", Direction: models.DirectionOutbound, Status: models.DeliveryQueued})
+	listed, _ := repo.ListByUser("user123")
+
+	repo.UpdateDelivery("user123", "entry1", "wamid.1", models.DeliveryAccepted)
+	repo.UpdateDelivery("user123", "missing", "wamid.2", models.DeliveryFailed)
+
+	entries, _ := repo.ListByUser("user123")
+	if len(entries) != 1 || entries[0].MessageID != "wamid.1" || entries[0].Status != models.DeliveryAccepted {
+		t.Errorf("Expected the accepted entry with its message ID, got %+v", entries)
+	}
+	if listed[0].Status != models.DeliveryQueued {
+		t.Errorf("Expected an already listed entry to be left unchanged, got %+v", listed[0])
+	}
+}
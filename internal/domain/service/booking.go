@@ -0,0 +1,396 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	domainerrors "chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
+)
+
+// System flows whose messages introduce each step of the booking conversation
+const (
+	bookingLocationState  = "booking_location"
+	bookingSlotsState     = "booking_slots"
+	bookingNoSlotsState   = "booking_no_slots"
+	bookingPatientState   = "booking_patient"
+	bookingConfirmState   = "booking_confirm"
+	bookingConfirmedState = "booking_confirmed"
+	bookingSlotTakenState = "booking_slot_taken"
+	bookingExistingState  = "booking_existing"
+	bookingCancelledState = "booking_cancelled"
+	bookingInvalidState   = "booking_invalid"
+)
+
+// Steps of the booking conversation
+const (
+	bookingStepManage   = "manage"   // Reschedule or cancel an upcoming appointment
+	bookingStepLocation = "location" // Choose where
+	bookingStepSlot     = "slot"     // Choose when
+	bookingStepPatient  = "patient"  // Name of the patient
+	bookingStepConfirm  = "confirm"  // Confirm the chosen slot
+)
+
+// maxOfferedSlots is how many free slots are listed at once
+const maxOfferedSlots = 8
+
+// Answers accepted when confirming a slot
+var (
+	confirmAnswers = map[string]bool{"si": true, "s": true, "confirmo": true, "confirmar": true, "ok": true}
+	rejectAnswers  = map[string]bool{"no": true, "n": true}
+)
+
+// Calendar tells which slots each location offers
+type Calendar interface {
+	Locations() []models.Location
+	// Timezone is the timezone appointment times are shown in
+	Timezone() *time.Location
+	// Slots returns the slots of a location patients can book at from, earliest first
+	Slots(locationID string, from time.Time) []models.Slot
+}
+
+// WithBooking books appointments in flows marked with booking, instead of
+// collecting their data for staff
+func WithBooking(calendar Calendar, appointments repository.AppointmentRepository) Option {
+	return func(s *chatbotService) {
+		s.calendar = calendar
+		s.appointments = appointments
+	}
+}
+
+// isBookingFlow reports whether the bot books appointments in flow
+func (s *chatbotService) isBookingFlow(flow *models.ChatbotFlow) bool {
+	return flow.Booking && s.calendar != nil
+}
+
+// startBooking enters a booking flow, offering to manage the upcoming
+// appointment of the user if there is one
+func (s *chatbotService) startBooking(userState *models.ChatbotState, flow *models.ChatbotFlow) (*models.WhatsAppResponse, string, error) {
+	userState.FieldIndex = 0
+	userState.Booking = &models.BookingProgress{}
+
+	upcoming, err := s.appointments.ListByUser(userState.UserID, time.Now())
+	if err != nil {
+		return nil, "", err
+	}
+	if len(upcoming) > 0 {
+		userState.Booking.Step = bookingStepManage
+		userState.Booking.Existing = upcoming[0]
+		return s.newTextResponse(userState, s.bookingPrompt(userState.Booking)), flow.State, nil
+	}
+
+	return s.askLocation(userState, flow, "")
+}
+
+// handleBookingState handles an answer in the booking conversation
+func (s *chatbotService) handleBookingState(userState *models.ChatbotState, flow *models.ChatbotFlow, message string) (*models.WhatsAppResponse, string, error) {
+	progress := userState.Booking
+	if progress == nil {
+		return s.startBooking(userState, flow)
+	}
+
+	switch progress.Step {
+	case bookingStepManage:
+		switch choice(message) {
+		case 1:
+			return s.askLocation(userState, flow, "")
+		case 2:
+			return s.cancelAppointment(userState, flow)
+		}
+	case bookingStepLocation:
+		if location, ok := s.findLocation(message); ok {
+			progress.LocationID = location.ID
+			return s.offerSlots(userState, flow, "")
+		}
+	case bookingStepSlot:
+		if n := choice(message); n >= 1 && n <= len(progress.Offered) {
+			slot := progress.Offered[n-1]
+			progress.Slot = &slot
+			progress.Step = bookingStepPatient
+			return s.newTextResponse(userState, s.bookingPrompt(progress)), flow.State, nil
+		}
+	case bookingStepPatient:
+		if name, ok := validateField(models.DataField{Type: models.FieldTypeName}, message); ok {
+			progress.PatientName = name
+			progress.Step = bookingStepConfirm
+			return s.newTextResponse(userState, s.bookingPrompt(progress)), flow.State, nil
+		}
+		body := fieldErrorMessage(models.DataField{Type: models.FieldTypeName}) + "\n\n" + s.bookingPrompt(progress)
+		return s.rejectInput(userState, flow, message, s.newTextResponse(userState, body))
+	case bookingStepConfirm:
+		answer := strings.TrimSpace(normalizeForMatching(message))
+		switch {
+		case confirmAnswers[answer]:
+			return s.bookAppointment(userState, flow)
+		case rejectAnswers[answer]:
+			return s.offerSlots(userState, flow, "")
+		}
+	default:
+		return s.startBooking(userState, flow)
+	}
+
+	body := s.systemMessage(bookingInvalidState, "⚠️ No entendimos tu respuesta.") + "\n\n" + s.bookingPrompt(progress)
+	return s.rejectInput(userState, flow, message, s.newTextResponse(userState, body))
+}
+
+// askLocation asks where the appointment should be, or goes straight to the
+// free slots when there is a single location
+func (s *chatbotService) askLocation(userState *models.ChatbotState, flow *models.ChatbotFlow, lead string) (*models.WhatsAppResponse, string, error) {
+	progress := userState.Booking
+	progress.Step = bookingStepLocation
+	progress.LocationID = ""
+
+	if locations := s.calendar.Locations(); len(locations) == 1 {
+		progress.LocationID = locations[0].ID
+		return s.offerSlots(userState, flow, lead)
+	}
+
+	return s.newTextResponse(userState, joinParagraphs(lead, s.bookingPrompt(progress))), flow.State, nil
+}
+
+// offerSlots lists the free slots of the chosen location
+func (s *chatbotService) offerSlots(userState *models.ChatbotState, flow *models.ChatbotFlow, lead string) (*models.WhatsAppResponse, string, error) {
+	progress := userState.Booking
+
+	free, err := s.freeSlots(progress.LocationID, time.Now())
+	if err != nil {
+		return nil, "", err
+	}
+	if len(free) == 0 {
+		noSlots := fmt.Sprintf("%s\n📍 %s", s.systemMessage(bookingNoSlotsState,
+			"😔 No quedan turnos disponibles en este consultorio en los próximos días."), s.locationName(progress.LocationID))
+		if len(s.calendar.Locations()) == 1 {
+			// Nothing else to choose from, leave the booking flow
+			return s.finishBooking(userState, flow, joinParagraphs(lead, noSlots), nil)
+		}
+		return s.askLocation(userState, flow, joinParagraphs(lead, noSlots))
+	}
+
+	progress.Step = bookingStepSlot
+	progress.Offered = free
+	progress.Slot = nil
+	return s.newTextResponse(userState, joinParagraphs(lead, s.bookingPrompt(progress))), flow.State, nil
+}
+
+// freeSlots returns the first slots of a location nobody has booked yet
+func (s *chatbotService) freeSlots(locationID string, now time.Time) ([]models.Slot, error) {
+	slots := s.calendar.Slots(locationID, now)
+	if len(slots) == 0 {
+		return nil, nil
+	}
+
+	booked, err := s.appointments.ListBooked(slots[0].Start, slots[len(slots)-1].End)
+	if err != nil {
+		return nil, err
+	}
+	taken := make(map[int64]bool, len(booked))
+	for _, appointment := range booked {
+		if appointment.LocationID == locationID {
+			taken[appointment.Start.Unix()] = true
+		}
+	}
+
+	var free []models.Slot
+	for _, slot := range slots {
+		if !taken[slot.Start.Unix()] {
+			free = append(free, slot)
+			if len(free) == maxOfferedSlots {
+				break
+			}
+		}
+	}
+	return free, nil
+}
+
+// bookAppointment reserves the chosen slot, offering the remaining ones when
+// another patient took it first
+func (s *chatbotService) bookAppointment(userState *models.ChatbotState, flow *models.ChatbotFlow) (*models.WhatsAppResponse, string, error) {
+	progress := userState.Booking
+	now := time.Now()
+
+	appointment := &models.Appointment{
+		UserID:       userState.UserID,
+		PatientName:  progress.PatientName,
+		LocationID:   progress.LocationID,
+		LocationName: s.locationName(progress.LocationID),
+		Start:        progress.Slot.Start,
+		End:          progress.Slot.End,
+		CreatedAt:    now,
+	}
+	err := s.appointments.Book(appointment)
+	if errors.Is(err, domainerrors.ErrSlotTaken) {
+		return s.offerSlots(userState, flow, s.systemMessage(bookingSlotTakenState,
+			"⚠️ Ese horario acaba de ser reservado por otro paciente. Elegí otro, por favor."))
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if s.reminders != nil {
+		s.reminders.AppointmentBooked(appointment)
+	}
+
+	// The new appointment is secured, only now give up the old one
+	data := map[string]string{
+		"turno":       s.formatSlotTime(appointment.Start),
+		"consultorio": appointment.LocationName,
+		"paciente":    appointment.PatientName,
+	}
+	if progress.Existing != nil {
+		if _, err := s.appointments.Cancel(progress.Existing.ID, now); err != nil && !errors.Is(err, domainerrors.ErrAppointmentNotFound) {
+			return nil, "", err
+		}
+		if s.reminders != nil {
+			s.reminders.AppointmentCancelled(progress.Existing)
+		}
+		data["turno_reprogramado"] = s.formatSlotTime(progress.Existing.Start)
+	}
+
+	body := s.systemMessage(bookingConfirmedState, "✅ ¡Listo! Tu turno quedó confirmado.") + "\n" + s.formatAppointment(appointment)
+	return s.finishBooking(userState, flow, body, data)
+}
+
+// cancelAppointment cancels the upcoming appointment of the user
+func (s *chatbotService) cancelAppointment(userState *models.ChatbotState, flow *models.ChatbotFlow) (*models.WhatsAppResponse, string, error) {
+	existing := userState.Booking.Existing
+
+	cancelled, err := s.appointments.Cancel(existing.ID, time.Now())
+	if errors.Is(err, domainerrors.ErrAppointmentNotFound) {
+		// Cancelled in the meantime, e.g. by staff
+		cancelled = existing
+	} else if err != nil {
+		return nil, "", err
+	}
+	if s.reminders != nil {
+		s.reminders.AppointmentCancelled(cancelled)
+	}
+
+	body := s.systemMessage(bookingCancelledState, "🗑️ Cancelamos tu turno.") + "\n" + s.formatAppointment(cancelled)
+	return s.finishBooking(userState, flow, body, map[string]string{
+		"turno_cancelado": s.formatSlotTime(cancelled.Start),
+		"consultorio":     cancelled.LocationName,
+	})
+}
+
+// finishBooking leaves the booking flow, reporting data to staff when an
+// appointment was booked or cancelled
+func (s *chatbotService) finishBooking(userState *models.ChatbotState, flow *models.ChatbotFlow, body string, data map[string]string) (*models.WhatsAppResponse, string, error) {
+	nextFlow, err := s.repo.GetFlowByState(flow.NextState)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(data) > 0 {
+		if userState.Data == nil {
+			userState.Data = make(map[string]string)
+		}
+		for key, value := range data {
+			userState.Data[key] = value
+		}
+		s.notifyRequestCompleted(userState, flow, time.Now())
+	}
+
+	userState.History = nil
+	return s.enterFlow(userState, nextFlow, body+"\n\n"+nextFlow.Message)
+}
+
+// bookingPrompt returns the question of the current booking step
+func (s *chatbotService) bookingPrompt(progress *models.BookingProgress) string {
+	var b strings.Builder
+
+	switch progress.Step {
+	case bookingStepManage:
+		b.WriteString(s.systemMessage(bookingExistingState, "📅 Ya tenés un turno reservado:"))
+		b.WriteString("\n" + s.formatAppointment(progress.Existing))
+		b.WriteString("\n\n1. Reprogramarlo\n2. Cancelarlo")
+	case bookingStepLocation:
+		b.WriteString(s.systemMessage(bookingLocationState, "📍 ¿En qué consultorio querés el turno?"))
+		for i, location := range s.calendar.Locations() {
+			fmt.Fprintf(&b, "\n%d. %s", i+1, location.Name)
+		}
+	case bookingStepSlot:
+		b.WriteString(s.systemMessage(bookingSlotsState, "🗓️ Estos son los próximos turnos disponibles. Respondé con el número del que prefieras:"))
+		fmt.Fprintf(&b, "\n📍 %s", s.locationName(progress.LocationID))
+		for i, slot := range progress.Offered {
+			fmt.Fprintf(&b, "\n%d. %s", i+1, s.formatSlotTime(slot.Start))
+		}
+	case bookingStepPatient:
+		b.WriteString(s.systemMessage(bookingPatientState, "👶 ¿Cuál es el nombre del paciente?"))
+	case bookingStepConfirm:
+		b.WriteString(s.systemMessage(bookingConfirmState, "¿Confirmás este turno? Respondé SI para reservarlo o NO para elegir otro horario."))
+		fmt.Fprintf(&b, "\n📅 %s\n📍 %s\n👶 %s", s.formatSlotTime(progress.Slot.Start), s.locationName(progress.LocationID), progress.PatientName)
+	}
+
+	return b.String()
+}
+
+// findLocation matches an answer with a location, by number or by name
+func (s *chatbotService) findLocation(answer string) (models.Location, bool) {
+	locations := s.calendar.Locations()
+	if n := choice(answer); n >= 1 && n <= len(locations) {
+		return locations[n-1], true
+	}
+
+	normalized := strings.TrimSpace(normalizeForMatching(answer))
+	if normalized == "" {
+		return models.Location{}, false
+	}
+	for _, location := range locations {
+		if strings.Contains(normalizeForMatching(location.Name), " "+normalized+" ") {
+			return location, true
+		}
+	}
+	return models.Location{}, false
+}
+
+// locationName returns the name of a location, or its ID if it is no longer in the calendar
+func (s *chatbotService) locationName(id string) string {
+	for _, location := range s.calendar.Locations() {
+		if location.ID == id {
+			return location.Name
+		}
+	}
+	return id
+}
+
+// choice parses a numbered answer such as "2" or "2.", returning 0 otherwise
+func choice(answer string) int {
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(answer), "."))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// formatAppointment describes an appointment to the patient
+func (s *chatbotService) formatAppointment(appointment *models.Appointment) string {
+	description := fmt.Sprintf("📅 %s\n📍 %s", s.formatSlotTime(appointment.Start), appointment.LocationName)
+	if appointment.PatientName != "" {
+		description += "\n👶 " + appointment.PatientName
+	}
+	return description
+}
+
+// formatSlotTime describes the start of a slot in the timezone of the calendar
+func (s *chatbotService) formatSlotTime(t time.Time) string {
+	return FormatSlotTime(t.In(s.calendar.Timezone()))
+}
+
+// FormatSlotTime describes the start of a slot in Spanish, e.g. "martes 14/10 16:20"
+func FormatSlotTime(t time.Time) string {
+	return fmt.Sprintf("%s %s %s", weekdayNames[t.Weekday()], t.Format("02/01"), t.Format("15:04"))
+}
+
+// joinParagraphs joins the non-empty texts with a blank line
+func joinParagraphs(texts ...string) string {
+	var paragraphs []string
+	for _, text := range texts {
+		if text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+// afterHoursState is the system flow whose message is shown while the practice is closed
+const afterHoursState = "after_hours"
+
+// BusinessHours tells when staff is available to answer patients
+type BusinessHours interface {
+	IsOpen(t time.Time) bool
+	CallbackWindow(t time.Time) (start, end time.Time, ok bool)
+}
+
+// AfterHoursMode controls how the after-hours message is combined with the bot's replies
+type AfterHoursMode string
+
+// After-hours modes
+const (
+	// AfterHoursAppend keeps the conversation going and adds the message when
+	// it starts and when a request is completed
+	AfterHoursAppend AfterHoursMode = "append"
+	// AfterHoursReplace answers every message with the after-hours message only
+	AfterHoursReplace AfterHoursMode = "replace"
+)
+
+var weekdayNames = [...]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"}
+
+// isClosed reports whether the practice is closed at t
+func (s *chatbotService) isClosed(t time.Time) bool {
+	return s.businessHours != nil && !s.businessHours.IsOpen(t)
+}
+
+// callbackWindow returns when staff is expected to answer a request made at t
+func (s *chatbotService) callbackWindow(t time.Time) *models.CallbackWindow {
+	if s.businessHours == nil {
+		return nil
+	}
+
+	start, end, ok := s.businessHours.CallbackWindow(t)
+	if !ok {
+		return nil
+	}
+	return &models.CallbackWindow{From: start, Until: end, AfterHours: !s.businessHours.IsOpen(t)}
+}
+
+// afterHoursNotice returns the after-hours message with the next opening, or
+// an empty string while the practice is open
+func (s *chatbotService) afterHoursNotice(t time.Time) string {
+	if !s.isClosed(t) {
+		return ""
+	}
+
+	notice := s.systemMessage(afterHoursState, "🌙 En este momento estamos fuera del horario de atención.")
+	if window := s.callbackWindow(t); window != nil {
+		notice += fmt.Sprintf("\nTe responderemos a partir del %s.", formatOpening(window.From))
+	}
+	return notice
+}
+
+// afterHoursReply returns the after-hours message as the only reply when the
+// practice is closed and the service is configured to replace its replies
+func (s *chatbotService) afterHoursReply(userState *models.ChatbotState) *models.WhatsAppResponse {
+	if s.afterHoursMode != AfterHoursReplace {
+		return nil
+	}
+
+	notice := s.afterHoursNotice(time.Now())
+	if notice == "" {
+		return nil
+	}
+
+	response := s.newTextResponse(userState, notice)
+	annotateStates(response, userState.State, userState.State)
+	return response
+}
+
+// appendAfterHoursNotice adds the after-hours message to a reply in append mode, falling back
+// to plain text when an interactive menu body would grow past its limit
+func (s *chatbotService) appendAfterHoursNotice(response *models.WhatsAppResponse, t time.Time) {
+	if s.afterHoursMode != AfterHoursAppend {
+		return
+	}
+	notice := s.afterHoursNotice(t)
+	if notice == "" {
+		return
+	}
+
+	response.Text.Body += "\n\n" + notice
+	if response.Interactive == nil {
+		return
+	}
+	if utf8.RuneCountInString(response.Text.Body) > maxInteractiveBody {
+		response.Type = "text"
+		response.Interactive = nil
+		return
+	}
+	response.Interactive.Body.Body = response.Text.Body
+}
+
+// FormatCallbackWindow describes a callback window in Spanish, e.g. "lunes 20/10 de 09:00 a 13:00"
+func FormatCallbackWindow(window *models.CallbackWindow) string {
+	return fmt.Sprintf("%s %s de %s a %s",
+		weekdayNames[window.From.Weekday()], window.From.Format("02/01"),
+		window.From.Format("15:04"), window.Until.Format("15:04"))
+}
+
+// formatOpening describes an opening time in Spanish, e.g. "lunes 20/10 a las 09:00"
+func formatOpening(t time.Time) string {
+	return fmt.Sprintf("%s %s a las %s", weekdayNames[t.Weekday()], t.Format("02/01"), t.Format("15:04"))
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -9,22 +10,154 @@ import (
 	"chatbot-wsp/internal/domain/repository"
 )
 
+// Well-known states every flow definition relies on
+const (
+	initialState          = repository.InitialState
+	invalidOptionState    = "invalid_option"
+	mediaReceivedState    = "media_received"
+	mediaNotExpectedState = "media_not_expected"
+	handoffStartedState   = "handoff_started"
+)
+
+// mediaDataSuffix is appended to the data request key to store media references
+const mediaDataSuffix = "_adjuntos"
+
 // ChatbotService defines the interface for chatbot business logic
 type ChatbotService interface {
 	ProcessMessage(userID, message string) (*models.WhatsAppResponse, error)
+	ProcessMedia(userID string, media *models.MediaReference) (*models.WhatsAppResponse, error)
 	GetWelcomeMessage() *models.WhatsAppResponse
+	StartHandoff(userID string) (*models.ChatbotState, error)
+	EndHandoff(userID string) (*models.ChatbotState, error)
+	ListSessions() ([]*models.ChatbotState, error)
+	GetSession(userID string) (*models.ChatbotState, error)
+	SetState(userID, state string) (*models.ChatbotState, error)
+	ResetSession(userID string) error
+}
+
+// Notifier is told about conversation events staff should follow up on.
+// Implementations must not block message processing.
+type Notifier interface {
+	NotifyRequestCompleted(event *models.RequestCompletedEvent)
+	NotifyEmergency(event *models.EmergencyEvent)
+	NotifyEscalation(event *models.EscalationEvent)
+}
+
+// Reminders schedules the messages patients get ahead of an appointment or a
+// callback. Implementations must not block message processing.
+type Reminders interface {
+	AppointmentBooked(appointment *models.Appointment)
+	AppointmentCancelled(appointment *models.Appointment)
+	CallbackPending(userID string, window *models.CallbackWindow)
+}
+
+// MetricsRecorder counts conversation events
+type MetricsRecorder interface {
+	StateTransition(from, to string)
 }
 
 // chatbotService implements ChatbotService
 type chatbotService struct {
-	repo repository.ChatbotRepository
+	repo               repository.ChatbotRepository
+	interactiveMenus   bool
+	handoffTimeout     time.Duration
+	notifier           Notifier
+	metrics            MetricsRecorder
+	businessHours      BusinessHours
+	afterHoursMode     AfterHoursMode
+	emergency          *emergencyDetector
+	commands           *commandSet
+	maxInvalidAttempts int
+	calendar           Calendar
+	appointments       repository.AppointmentRepository
+	reminders          Reminders
+}
+
+// Option configures optional behaviour of the chatbot service
+type Option func(*chatbotService)
+
+// WithInteractiveMenus sends menus as WhatsApp reply buttons or list messages
+// instead of plain text
+func WithInteractiveMenus() Option {
+	return func(s *chatbotService) {
+		s.interactiveMenus = true
+	}
+}
+
+// WithHandoffTimeout returns a conversation to the bot once it has been
+// handed to staff for longer than timeout
+func WithHandoffTimeout(timeout time.Duration) Option {
+	return func(s *chatbotService) {
+		s.handoffTimeout = timeout
+	}
+}
+
+// WithNotifier reports completed requests to staff
+func WithNotifier(notifier Notifier) Option {
+	return func(s *chatbotService) {
+		s.notifier = notifier
+	}
+}
+
+// WithReminders schedules reminders of booked appointments and of requests
+// staff will answer once the practice opens
+func WithReminders(reminders Reminders) Option {
+	return func(s *chatbotService) {
+		s.reminders = reminders
+	}
+}
+
+// WithMetrics counts state transitions
+func WithMetrics(metrics MetricsRecorder) Option {
+	return func(s *chatbotService) {
+		s.metrics = metrics
+	}
+}
+
+// WithBusinessHours tells patients when the practice is closed and tags
+// completed requests with the expected callback window
+func WithBusinessHours(hours BusinessHours, mode AfterHoursMode) Option {
+	return func(s *chatbotService) {
+		s.businessHours = hours
+		s.afterHoursMode = mode
+	}
+}
+
+// WithEmergencyKeywords replaces DefaultEmergencyKeywords. An empty list
+// disables emergency detection.
+func WithEmergencyKeywords(keywords []string) Option {
+	return func(s *chatbotService) {
+		s.emergency = newEmergencyDetector(keywords)
+	}
+}
+
+// WithCommands replaces the synonyms of the global commands, see DefaultCommands
+func WithCommands(commands map[Command][]string) Option {
+	return func(s *chatbotService) {
+		s.commands = newCommandSet(commands)
+	}
+}
+
+// WithInvalidInputEscalation hands the conversation to staff after
+// maxAttempts consecutive answers that are not understood
+func WithInvalidInputEscalation(maxAttempts int) Option {
+	return func(s *chatbotService) {
+		s.maxInvalidAttempts = maxAttempts
+	}
 }
 
 // NewChatbotService creates a new chatbot service
-func NewChatbotService(repo repository.ChatbotRepository) ChatbotService {
-	return &chatbotService{
-		repo: repo,
+func NewChatbotService(repo repository.ChatbotRepository, opts ...Option) ChatbotService {
+	s := &chatbotService{
+		repo:           repo,
+		handoffTimeout: 12 * time.Hour, // Default to 12 hours
+		emergency:      newEmergencyDetector(DefaultEmergencyKeywords),
+		commands:       newCommandSet(DefaultCommands),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // ProcessMessage processes incoming messages and returns appropriate responses
@@ -35,116 +168,434 @@ func (s *chatbotService) ProcessMessage(userID, message string) (*models.WhatsAp
 		return nil, err
 	}
 
+	// Emergencies are answered before anything else, even during a handoff or after hours
+	if keyword, ok := s.emergency.Detect(message); ok {
+		return s.handleEmergency(userState, message, keyword)
+	}
+
+	// Staff is answering in person, don't reply over them
+	if s.inHandoff(userState) {
+		return nil, s.touch(userState)
+	}
+
+	// The bot only answers with the after-hours message while the practice is closed
+	if response := s.afterHoursReply(userState); response != nil {
+		return response, s.touch(userState)
+	}
+
 	// Process message based on current state
+	previousState := userState.State
+	invalidAttempts := userState.InvalidAttempts
 	response, newState, err := s.processMessageByState(userState, message)
 	if err != nil {
 		return nil, err
 	}
 
+	// Any understood answer ends a streak of invalid ones
+	if userState.InvalidAttempts == invalidAttempts {
+		userState.InvalidAttempts = 0
+	}
+
 	// Update user state
-	userState.State = newState
-	userState.UpdatedAt = time.Now()
-	if err := s.repo.SaveUserState(userState); err != nil {
+	if err := s.transition(userState, previousState, newState); err != nil {
 		return nil, err
 	}
 
+	if previousState == initialState {
+		s.appendAfterHoursNotice(response, time.Now())
+	}
+	annotateStates(response, previousState, newState)
 	return response, nil
 }
 
-// processMessageByState handles message processing based on current state
-func (s *chatbotService) processMessageByState(userState *models.ChatbotState, message string) (*models.WhatsAppResponse, string, error) {
-	message = strings.TrimSpace(strings.ToUpper(message))
-
-	switch userState.State {
-	case "welcome":
-		return s.handleWelcomeState(userState, message)
-	case "option_a", "option_b", "option_c", "option_d":
-		return s.handleOptionState(userState, message)
-	case "collecting_data":
-		return s.handleDataCollectionState(userState, message)
+// ProcessMedia attaches a stored media file to the data requested in the current state
+func (s *chatbotService) ProcessMedia(userID string, media *models.MediaReference) (*models.WhatsAppResponse, error) {
+	userState, err := s.repo.GetUserState(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.inHandoff(userState) {
+		return nil, s.touch(userState)
+	}
+
+	if response := s.afterHoursReply(userState); response != nil {
+		return response, s.touch(userState)
+	}
+
+	previousState := userState.State
+	response, newState, err := s.processMediaByState(userState, media)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.transition(userState, previousState, newState); err != nil {
+		return nil, err
+	}
+
+	annotateStates(response, previousState, newState)
+	return response, nil
+}
+
+// processMediaByState stores a media file where the current state expects it
+func (s *chatbotService) processMediaByState(userState *models.ChatbotState, media *models.MediaReference) (*models.WhatsAppResponse, string, error) {
+	flow, err := s.repo.GetFlowByState(userState.State)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch {
+	case s.isBookingFlow(flow):
+		return s.newTextResponse(userState, s.systemMessage(mediaNotExpectedState,
+			"📎 Recibimos tu archivo, pero en este momento no estamos esperando adjuntos.")+"\n\n"+s.currentPrompt(userState, flow)), flow.State, nil
+	case len(flow.Fields) > 0:
+		return s.handleFieldMedia(userState, flow, media)
+	case flow.DataRequest != "":
+		appendMediaRef(userState, flow.DataRequest+mediaDataSuffix, media)
+		return s.newTextResponse(userState, s.systemMessage(mediaReceivedState,
+			"📎 Archivo recibido. Podés enviar más archivos o escribir el resto de la información solicitada.")), flow.State, nil
 	default:
-		return s.handleWelcomeState(userState, message)
+		// Nothing to attach the file to, remind the user what we are waiting for
+		return s.newFlowResponse(userState, flow, s.systemMessage(mediaNotExpectedState,
+			"📎 Recibimos tu archivo, pero en este momento no estamos esperando adjuntos.")+"\n\n"+flow.Message), flow.State, nil
 	}
 }
 
-// handleWelcomeState processes messages in welcome state
-func (s *chatbotService) handleWelcomeState(userState *models.ChatbotState, message string) (*models.WhatsAppResponse, string, error) {
-	// Check if message is a valid option
-	if isValidOption(message) {
-		userState.Option = message
-		flow, err := s.repo.GetFlowByState("option_" + strings.ToLower(message))
+// processMessageByState handles message processing based on the flow of the current state
+func (s *chatbotService) processMessageByState(userState *models.ChatbotState, message string) (*models.WhatsAppResponse, string, error) {
+	message = strings.TrimSpace(message)
+
+	flow, err := s.repo.GetFlowByState(userState.State)
+	if err != nil {
+		// The state no longer exists in the flow definition, start over
+		flow, err = s.repo.GetFlowByState(initialState)
 		if err != nil {
 			return nil, "", err
 		}
+		userState.State = initialState
+	}
 
-		response := &models.WhatsAppResponse{
-			MessagingProduct: "whatsapp",
-			To:               userState.UserID,
-			Type:             "text",
-		}
-		response.Text.Body = flow.Message
+	// Global commands work in any state
+	if command, ok := s.commands.Match(message); ok {
+		return s.handleCommand(userState, flow, command)
+	}
 
-		return response, "collecting_data", nil
+	switch {
+	case s.isBookingFlow(flow):
+		return s.handleBookingState(userState, flow, message)
+	case len(flow.Options) > 0:
+		return s.handleMenuState(userState, flow, message)
+	case len(flow.Fields) > 0:
+		return s.handleFieldState(userState, flow, message)
+	case flow.DataRequest != "":
+		return s.handleDataRequestState(userState, flow, message)
+	default:
+		return s.handleMessageOnlyState(userState, flow, message)
 	}
+}
 
-	response := &models.WhatsAppResponse{
-		MessagingProduct: "whatsapp",
-		To:               userState.UserID,
-		Type:             "text",
+// handleMenuState moves the user to the state of the selected option
+func (s *chatbotService) handleMenuState(userState *models.ChatbotState, flow *models.ChatbotFlow, message string) (*models.WhatsAppResponse, string, error) {
+	option := findOption(flow, message)
+	if option == nil {
+		return s.rejectInput(userState, flow, message, s.invalidOptionResponse(userState, flow))
+	}
+
+	userState.Option = option.ID
+	nextFlow, err := s.repo.GetFlowByState(option.NextState)
+	if err != nil {
+		return nil, "", err
+	}
+
+	pushHistory(userState, flow.State)
+	if s.isBookingFlow(nextFlow) {
+		return s.startBooking(userState, nextFlow)
+	}
+	return s.enterFlow(userState, nextFlow, nextFlow.Message)
+}
+
+// handleDataRequestState stores the user's answer and moves to the flow's next state
+func (s *chatbotService) handleDataRequestState(userState *models.ChatbotState, flow *models.ChatbotFlow, message string) (*models.WhatsAppResponse, string, error) {
+	if userState.Data == nil {
+		userState.Data = make(map[string]string)
 	}
+	userState.Data[flow.DataRequest] = message
 
-	return response, "welcome", nil
+	return s.completeFlow(userState, flow)
 }
 
-// handleOptionState processes messages when user has selected an option
-func (s *chatbotService) handleOptionState(userState *models.ChatbotState, message string) (*models.WhatsAppResponse, string, error) {
-	// Collect data based on the selected option
-	dataKey := s.getDataKeyForOption(userState.Option)
-	userState.Data[dataKey] = message
+// handleFieldState validates the answer to the current field and asks the next one
+func (s *chatbotService) handleFieldState(userState *models.ChatbotState, flow *models.ChatbotFlow, message string) (*models.WhatsAppResponse, string, error) {
+	field := currentField(userState, flow)
 
-	// Move to collecting data state
-	flow, err := s.repo.GetFlowByState("collecting_data")
+	value, valid := validateField(field, message)
+	if !valid {
+		return s.rejectInput(userState, flow, message, s.newTextResponse(userState, fieldErrorMessage(field)+"\n\n"+field.Prompt))
+	}
+
+	if userState.Data == nil {
+		userState.Data = make(map[string]string)
+	}
+	userState.Data[field.Key] = value
+
+	return s.advanceField(userState, flow)
+}
+
+// handleFieldMedia stores a file for a media field, or keeps it aside when a text answer is expected
+func (s *chatbotService) handleFieldMedia(userState *models.ChatbotState, flow *models.ChatbotFlow, media *models.MediaReference) (*models.WhatsAppResponse, string, error) {
+	field := currentField(userState, flow)
+
+	if field.Type != models.FieldTypeMedia {
+		appendMediaRef(userState, field.Key+mediaDataSuffix, media)
+		return s.newTextResponse(userState, s.systemMessage(mediaReceivedState,
+			"📎 Archivo recibido. Podés enviar más archivos o escribir el resto de la información solicitada.")+"\n\n"+field.Prompt), flow.State, nil
+	}
+
+	appendMediaRef(userState, field.Key, media)
+	return s.advanceField(userState, flow)
+}
+
+// advanceField asks the next field of the flow, or leaves the flow once every field is answered
+func (s *chatbotService) advanceField(userState *models.ChatbotState, flow *models.ChatbotFlow) (*models.WhatsAppResponse, string, error) {
+	userState.FieldIndex++
+	if userState.FieldIndex < len(flow.Fields) {
+		return s.newTextResponse(userState, flow.Fields[userState.FieldIndex].Prompt), flow.State, nil
+	}
+
+	return s.completeFlow(userState, flow)
+}
+
+// completeFlow moves to the next state once the data of a flow is collected,
+// handing the conversation to staff when the flow asks for it
+func (s *chatbotService) completeFlow(userState *models.ChatbotState, flow *models.ChatbotFlow) (*models.WhatsAppResponse, string, error) {
+	nextFlow, err := s.repo.GetFlowByState(flow.NextState)
 	if err != nil {
 		return nil, "", err
 	}
 
-	response := &models.WhatsAppResponse{
-		MessagingProduct: "whatsapp",
-		To:               userState.UserID,
-		Type:             "text",
+	now := time.Now()
+	s.notifyRequestCompleted(userState, flow, now)
+
+	// The request is done, going back must not reopen it
+	userState.History = nil
+
+	if !flow.Handoff {
+		response, state, err := s.enterFlow(userState, nextFlow, s.formatDataCollectionMessage(nextFlow, userState))
+		if err == nil {
+			s.appendAfterHoursNotice(response, now)
+		}
+		return response, state, err
 	}
-	response.Text.Body = s.formatDataCollectionMessage(flow, userState)
 
-	return response, "collecting_data", nil
+	// The bot stays silent from now on, so don't show the next menu
+	startHandoff(userState)
+	userState.FieldIndex = 0
+	body := s.systemMessage(handoffStartedState,
+		"👩‍⚕️ Gracias, recibimos tu información. La Dra. continuará la conversación personalmente.") + formatDataSummary(userState)
+
+	response := s.newTextResponse(userState, body)
+	s.appendAfterHoursNotice(response, now)
+	return response, nextFlow.State, nil
 }
 
-// handleDataCollectionState processes messages after data collection
-func (s *chatbotService) handleDataCollectionState(userState *models.ChatbotState, message string) (*models.WhatsAppResponse, string, error) {
-	// Check if user wants to select another option
-	if isValidOption(message) {
-		userState.Option = message
-		flow, err := s.repo.GetFlowByState("option_" + strings.ToLower(message))
-		if err != nil {
-			return nil, "", err
-		}
+// enterFlow moves the user into a flow, asking its first field if it collects structured data
+func (s *chatbotService) enterFlow(userState *models.ChatbotState, flow *models.ChatbotFlow, body string) (*models.WhatsAppResponse, string, error) {
+	userState.FieldIndex = 0
+	userState.Booking = nil
+	if len(flow.Fields) > 0 {
+		body += "\n\n" + flow.Fields[0].Prompt
+	}
 
-		response := &models.WhatsAppResponse{
-			MessagingProduct: "whatsapp",
-			To:               userState.UserID,
-			Type:             "text",
-		}
-		response.Text.Body = flow.Message
+	return s.newFlowResponse(userState, flow, body), flow.State, nil
+}
+
+// handleMessageOnlyState leaves a state that expects no input and handles the
+// message in the state that follows it
+func (s *chatbotService) handleMessageOnlyState(userState *models.ChatbotState, flow *models.ChatbotFlow, message string) (*models.WhatsAppResponse, string, error) {
+	next := flow.NextState
+	if next == "" {
+		next = initialState
+	}
+	if next == flow.State {
+		return s.newFlowResponse(userState, flow, flow.Message), flow.State, nil
+	}
+
+	userState.State = next
+	return s.processMessageByState(userState, message)
+}
+
+// invalidOptionResponse repeats the current menu after the invalid option warning
+func (s *chatbotService) invalidOptionResponse(userState *models.ChatbotState, flow *models.ChatbotFlow) *models.WhatsAppResponse {
+	body := s.systemMessage(invalidOptionState, "⚠️ Por favor, ingresá una opción válida.") + "\n\n" + flow.Message
+	return s.newFlowResponse(userState, flow, body)
+}
 
-		return response, "collecting_data", nil
+// systemMessage returns the message of a system flow, or fallback when the
+// flow definition does not customize it
+func (s *chatbotService) systemMessage(state, fallback string) string {
+	if flow, err := s.repo.GetFlowByState(state); err == nil {
+		return flow.Message
 	}
+	return fallback
+}
+
+// newFlowResponse builds the reply for entering a flow, as an interactive menu
+// when enabled and the flow has options. The text body is always filled so the
+// reply can still be logged and shown as plain text.
+func (s *chatbotService) newFlowResponse(userState *models.ChatbotState, flow *models.ChatbotFlow, body string) *models.WhatsAppResponse {
+	response := s.newTextResponse(userState, body)
+
+	if s.interactiveMenus {
+		if menu := buildInteractiveMenu(flow, body); menu != nil {
+			response.Type = "interactive"
+			response.Interactive = menu
+		}
+	}
+
+	return response
+}
 
+// newTextResponse builds a text reply for the user
+func (s *chatbotService) newTextResponse(userState *models.ChatbotState, body string) *models.WhatsAppResponse {
 	response := &models.WhatsAppResponse{
 		MessagingProduct: "whatsapp",
 		To:               userState.UserID,
 		Type:             "text",
 	}
+	response.Text.Body = body
+
+	return response
+}
+
+// notifyRequestCompleted reports the data collected by a flow, reminding the
+// patient of the callback when the request was made after hours
+func (s *chatbotService) notifyRequestCompleted(userState *models.ChatbotState, flow *models.ChatbotFlow, completedAt time.Time) {
+	window := s.callbackWindow(completedAt)
+
+	// Booked appointments get their own reminder, nobody calls back
+	if s.reminders != nil && window != nil && window.AfterHours && !s.isBookingFlow(flow) {
+		s.reminders.CallbackPending(userState.UserID, window)
+	}
+
+	if s.notifier == nil {
+		return
+	}
+
+	data := make(map[string]string, len(userState.Data))
+	for key, value := range userState.Data {
+		data[key] = value
+	}
+
+	s.notifier.NotifyRequestCompleted(&models.RequestCompletedEvent{
+		UserID:         userState.UserID,
+		State:          flow.State,
+		Option:         userState.Option,
+		Data:           data,
+		CompletedAt:    completedAt,
+		CallbackWindow: window,
+	})
+}
+
+// StartHandoff pauses the bot for a user so staff can answer in person
+func (s *chatbotService) StartHandoff(userID string) (*models.ChatbotState, error) {
+	userState, err := s.repo.GetUserState(userID)
+	if err != nil {
+		return nil, err
+	}
 
-	return response, "welcome", nil
+	startHandoff(userState)
+	if err := s.touch(userState); err != nil {
+		return nil, err
+	}
+
+	return userState, nil
+}
+
+// EndHandoff returns a user to the bot, keeping the conversation state
+func (s *chatbotService) EndHandoff(userID string) (*models.ChatbotState, error) {
+	userState, err := s.repo.GetUserState(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	endHandoff(userState)
+	if err := s.touch(userState); err != nil {
+		return nil, err
+	}
+
+	return userState, nil
+}
+
+// ListSessions returns the active sessions, most recently active first
+func (s *chatbotService) ListSessions() ([]*models.ChatbotState, error) {
+	return s.repo.ListUserStates()
+}
+
+// GetSession returns the conversation state of a user. Users without an
+// active session report a fresh state at the initial flow.
+func (s *chatbotService) GetSession(userID string) (*models.ChatbotState, error) {
+	return s.repo.GetUserState(userID)
+}
+
+// SetState moves a user to another flow, keeping the collected data. The
+// flow's message is not sent; the user's next message is handled there.
+func (s *chatbotService) SetState(userID, state string) (*models.ChatbotState, error) {
+	if _, err := s.repo.GetFlowByState(state); err != nil {
+		return nil, err
+	}
+
+	userState, err := s.repo.GetUserState(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	userState.FieldIndex = 0
+	userState.History = nil
+	userState.Booking = nil
+	if err := s.transition(userState, userState.State, state); err != nil {
+		return nil, err
+	}
+
+	return userState, nil
+}
+
+// ResetSession deletes the session of a user, so the next message starts over
+func (s *chatbotService) ResetSession(userID string) error {
+	return s.repo.DeleteUserState(userID)
+}
+
+// inHandoff reports whether staff is answering the user, ending handoffs
+// older than the configured timeout
+func (s *chatbotService) inHandoff(userState *models.ChatbotState) bool {
+	if !userState.Handoff {
+		return false
+	}
+
+	if s.handoffTimeout > 0 && time.Since(userState.HandoffAt) > s.handoffTimeout {
+		endHandoff(userState)
+		return false
+	}
+
+	return true
+}
+
+// transition saves the user in its new state, counting the change of state
+func (s *chatbotService) transition(userState *models.ChatbotState, from, to string) error {
+	userState.State = to
+	if err := s.touch(userState); err != nil {
+		return err
+	}
+
+	if s.metrics != nil && from != to {
+		s.metrics.StateTransition(from, to)
+	}
+	return nil
+}
+
+// touch saves the user state without changing the conversation
+func (s *chatbotService) touch(userState *models.ChatbotState) error {
+	userState.UpdatedAt = time.Now()
+	return s.repo.SaveUserState(userState)
 }
 
 // GetWelcomeMessage returns the initial welcome message
@@ -154,9 +605,7 @@ func (s *chatbotService) GetWelcomeMessage() *models.WhatsAppResponse {
 		return &models.WhatsAppResponse{
 			MessagingProduct: "whatsapp",
 			Type:             "text",
-			Text: struct {
-				Body string `json:"body"`
-			}{
+			Text: models.TextContent{
 				Body: "¡Hola! Bienvenido a nuestro servicio.",
 			},
 		}
@@ -165,9 +614,7 @@ func (s *chatbotService) GetWelcomeMessage() *models.WhatsAppResponse {
 	return &models.WhatsAppResponse{
 		MessagingProduct: "whatsapp",
 		Type:             "text",
-		Text: struct {
-			Body string `json:"body"`
-		}{
+		Text: models.TextContent{
 			Body: s.formatWelcomeMessage(flow),
 		},
 	}
@@ -175,31 +622,55 @@ func (s *chatbotService) GetWelcomeMessage() *models.WhatsAppResponse {
 
 // Helper functions
 
-func isValidOption(message string) bool {
-	validOptions := []string{"A", "B", "C", "D"}
-	for _, option := range validOptions {
-		if message == option {
-			return true
-		}
+// currentField returns the field being asked in the flow
+func currentField(userState *models.ChatbotState, flow *models.ChatbotFlow) models.DataField {
+	if userState.FieldIndex < 0 || userState.FieldIndex >= len(flow.Fields) {
+		userState.FieldIndex = 0
 	}
-	return false
+	return flow.Fields[userState.FieldIndex]
 }
 
-func (s *chatbotService) getDataKeyForOption(option string) string {
-	switch option {
-	case "A":
-		return "datos_consulta_medica"
-	case "B":
-		return "datos_lectura_estudios"
-	case "C":
-		return "datos_turno"
-	case "D":
-		return "datos_babyhome"
-	default:
-		return "data"
+// annotateStates records the conversation states around a reply
+func annotateStates(response *models.WhatsAppResponse, before, after string) {
+	if response != nil {
+		response.StateBefore = before
+		response.StateAfter = after
 	}
 }
 
+func startHandoff(userState *models.ChatbotState) {
+	userState.Handoff = true
+	userState.HandoffAt = time.Now()
+}
+
+func endHandoff(userState *models.ChatbotState) {
+	userState.Handoff = false
+	userState.HandoffAt = time.Time{}
+}
+
+// appendMediaRef adds a media reference to the data stored under key
+func appendMediaRef(userState *models.ChatbotState, key string, media *models.MediaReference) {
+	if userState.Data == nil {
+		userState.Data = make(map[string]string)
+	}
+	if existing := userState.Data[key]; existing != "" {
+		userState.Data[key] = existing + "\n" + media.Ref
+	} else {
+		userState.Data[key] = media.Ref
+	}
+}
+
+// findOption returns the option of the flow matching the message, ignoring case
+func findOption(flow *models.ChatbotFlow, message string) *models.ChatbotOption {
+	for i := range flow.Options {
+		option := &flow.Options[i]
+		if strings.EqualFold(message, option.ID) || (option.Label != "" && strings.EqualFold(message, option.Label)) {
+			return option
+		}
+	}
+	return nil
+}
+
 func (s *chatbotService) formatWelcomeMessage(flow *models.ChatbotFlow) string {
 	// El mensaje ya está formateado correctamente en el repositorio
 	return flow.Message
@@ -207,15 +678,25 @@ func (s *chatbotService) formatWelcomeMessage(flow *models.ChatbotFlow) string {
 
 func (s *chatbotService) formatDataCollectionMessage(flow *models.ChatbotFlow, userState *models.ChatbotState) string {
 	// El mensaje ya está formateado correctamente en el repositorio
-	message := flow.Message
+	return flow.Message + formatDataSummary(userState)
+}
 
-	// Add collected data summary
-	if len(userState.Data) > 0 {
-		message += "\n\n📋 Datos recopilados:\n"
-		for key, value := range userState.Data {
-			message += fmt.Sprintf("• %s: %s\n", key, value)
-		}
+// formatDataSummary lists the data collected from the user, sorted by key
+func formatDataSummary(userState *models.ChatbotState) string {
+	if len(userState.Data) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(userState.Data))
+	for key := range userState.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	summary := "\n\n📋 Datos recopilados:\n"
+	for _, key := range keys {
+		summary += fmt.Sprintf("• %s: %s\n", key, userState.Data[key])
 	}
 
-	return message
+	return summary
 }
@@ -7,6 +7,7 @@ import (
 
 	"chatbot-wsp/internal/domain/errors"
 	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
 )
 
 // mockRepository is a mock implementation of ChatbotRepository
@@ -46,24 +47,28 @@ D. Consulta sobre BabyHome
 		State:       "option_a",
 		Message:     "La consulta telefónica es un acto médico y tiene un valor de $15.000 ARS (no cubierta por obra social).",
 		DataRequest: "datos_consulta_medica",
+		NextState:   "collecting_data",
 	}
 
 	repo.flows["option_b"] = &models.ChatbotFlow{
 		State:       "option_b",
 		Message:     "Por favor enviá: Fotos claras o PDF de los estudios",
 		DataRequest: "datos_lectura_estudios",
+		NextState:   "collecting_data",
 	}
 
 	repo.flows["option_c"] = &models.ChatbotFlow{
 		State:       "option_c",
 		Message:     "Para turnos comunicarse a los siguientes números",
 		DataRequest: "datos_turno",
+		NextState:   "collecting_data",
 	}
 
 	repo.flows["option_d"] = &models.ChatbotFlow{
 		State:       "option_d",
 		Message:     "¡Qué alegría que te interese BabyHome!",
 		DataRequest: "datos_babyhome",
+		NextState:   "collecting_data",
 	}
 
 	repo.flows["collecting_data"] = &models.ChatbotFlow{
@@ -85,6 +90,7 @@ D. Consulta sobre BabyHome`,
 	repo.flows["invalid_option"] = &models.ChatbotFlow{
 		State:   "invalid_option",
 		Message: `⚠️ Por favor, ingresa una opción válida (A, B, C o D).`,
+		System:  true,
 	}
 
 	return repo
@@ -122,6 +128,29 @@ func (m *mockRepository) SaveUserState(state *models.ChatbotState) error {
 	return nil
 }
 
+func (m *mockRepository) ListUserStates() ([]*models.ChatbotState, error) {
+	var states []*models.ChatbotState
+	for _, state := range m.userStates {
+		if !m.isSessionExpired(state) {
+			states = append(states, state)
+		}
+	}
+	return states, nil
+}
+
+func (m *mockRepository) CountUserStates() (int, error) {
+	states, err := m.ListUserStates()
+	return len(states), err
+}
+
+func (m *mockRepository) DeleteUserState(userID string) error {
+	if _, exists := m.userStates[userID]; !exists {
+		return errors.ErrUserNotFound
+	}
+	delete(m.userStates, userID)
+	return nil
+}
+
 func (m *mockRepository) GetFlowByState(state string) (*models.ChatbotFlow, error) {
 	flow, exists := m.flows[state]
 	if !exists {
@@ -160,28 +189,35 @@ func TestChatbotService_ProcessMessage_WelcomeState(t *testing.T) {
 	}{
 		{
 			name:          "Valid option A",
-			userID:        "user123",
+			userID:        "user_a",
 			message:       "A",
-			expectedState: "collecting_data",
+			expectedState: "option_a",
 			expectError:   false,
 		},
 		{
 			name:          "Valid option B",
-			userID:        "user123",
+			userID:        "user_b",
 			message:       "B",
-			expectedState: "collecting_data",
+			expectedState: "option_b",
+			expectError:   false,
+		},
+		{
+			name:          "Valid lowercase option",
+			userID:        "user_d",
+			message:       " d ",
+			expectedState: "option_d",
 			expectError:   false,
 		},
 		{
 			name:          "Invalid option",
-			userID:        "user123",
+			userID:        "user_x",
 			message:       "X",
 			expectedState: "welcome",
 			expectError:   false,
 		},
 		{
 			name:          "Empty message",
-			userID:        "user123",
+			userID:        "user_empty",
 			message:       "",
 			expectedState: "welcome",
 			expectError:   false,
@@ -242,19 +278,25 @@ func TestChatbotService_ProcessMessage_DataCollectionState(t *testing.T) {
 		{
 			name:          "Select another option A",
 			message:       "A",
-			expectedState: "collecting_data",
+			expectedState: "option_a",
 			expectError:   false,
 		},
 		{
-			name:          "Select option B",
-			message:       "B",
+			name:          "Send requested data",
+			message:       "Juan, 3 años, fiebre",
 			expectedState: "collecting_data",
 			expectError:   false,
 		},
 		{
 			name:          "Invalid option",
 			message:       "X",
-			expectedState: "welcome",
+			expectedState: "collecting_data",
+			expectError:   false,
+		},
+		{
+			name:          "Select option B",
+			message:       "B",
+			expectedState: "option_b",
 			expectError:   false,
 		},
 	}
@@ -438,27 +480,1021 @@ func TestChatbotService_SessionExpiration(t *testing.T) {
 	}
 }
 
-func TestIsValidOption(t *testing.T) {
+func TestChatbotService_StoresDataUnderFlowDataRequest(t *testing.T) {
+	repo := newMockRepository()
+	service := NewChatbotService(repo)
+
+	if _, err := service.ProcessMessage("user123", "A"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	response, err := service.ProcessMessage("user123", "Juan, 3 años, fiebre")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	userState, _ := repo.GetUserState("user123")
+	if userState.Data["datos_consulta_medica"] != "Juan, 3 años, fiebre" {
+		t.Errorf("Expected data to be stored verbatim, got %v", userState.Data)
+	}
+
+	if !strings.Contains(response.Text.Body, "Gracias por la información") {
+		t.Errorf("Expected data collection message, got: %s", response.Text.Body)
+	}
+}
+
+func TestChatbotService_NestedSubmenus(t *testing.T) {
+	repo := newMockRepository()
+	repo.flows["welcome"].Options = append(repo.flows["welcome"].Options,
+		models.ChatbotOption{ID: "E", Label: "E", Description: "Otros servicios", NextState: "services"})
+	repo.flows["services"] = &models.ChatbotFlow{
+		State:   "services",
+		Message: "1. Vacunas\n2. Controles",
+		Options: []models.ChatbotOption{
+			{ID: "1", Label: "1", Description: "Vacunas", NextState: "vaccines"},
+			{ID: "2", Label: "2", Description: "Controles", NextState: "welcome"},
+		},
+	}
+	repo.flows["vaccines"] = &models.ChatbotFlow{
+		State:   "vaccines",
+		Message: "1. Calendario oficial\n2. Vacunas especiales",
+		Options: []models.ChatbotOption{
+			{ID: "1", Label: "1", Description: "Calendario oficial", NextState: "vaccine_schedule"},
+			{ID: "2", Label: "2", Description: "Vacunas especiales", NextState: "vaccine_schedule"},
+		},
+	}
+	repo.flows["vaccine_schedule"] = &models.ChatbotFlow{
+		State:       "vaccine_schedule",
+		Message:     "Indicanos la edad del bebé",
+		DataRequest: "edad_vacunas",
+		NextState:   "collecting_data",
+	}
+	service := NewChatbotService(repo)
+
+	steps := []struct {
+		message       string
+		expectedState string
+		expectedBody  string
+	}{
+		{"E", "services", "Vacunas"},
+		{"1", "vaccines", "Calendario oficial"},
+		{"9", "vaccines", "⚠️ Por favor, ingresa una opción válida"},
+		{"2", "vaccine_schedule", "Indicanos la edad del bebé"},
+		{"4 meses", "collecting_data", "Gracias por la información"},
+	}
+
+	for _, step := range steps {
+		response, err := service.ProcessMessage("user123", step.message)
+		if err != nil {
+			t.Fatalf("Unexpected error on message %q: %v", step.message, err)
+		}
+
+		userState, _ := repo.GetUserState("user123")
+		if userState.State != step.expectedState {
+			t.Errorf("After %q expected state %s, got %s", step.message, step.expectedState, userState.State)
+		}
+
+		if !strings.Contains(response.Text.Body, step.expectedBody) {
+			t.Errorf("After %q expected response to contain '%s', got: %s", step.message, step.expectedBody, response.Text.Body)
+		}
+	}
+
+	userState, _ := repo.GetUserState("user123")
+	if userState.Data["edad_vacunas"] != "4 meses" {
+		t.Errorf("Expected nested data to be stored, got %v", userState.Data)
+	}
+}
+
+func TestFindOption(t *testing.T) {
+	flow := newMockRepository().flows["welcome"]
+
 	tests := []struct {
 		option   string
-		expected bool
+		expected string
 	}{
-		{"A", true},
-		{"B", true},
-		{"C", true},
-		{"D", true},
-		{"a", false},
-		{"X", false},
-		{"1", false},
-		{"", false},
+		{"A", "option_a"},
+		{"B", "option_b"},
+		{"C", "option_c"},
+		{"D", "option_d"},
+		{"a", "option_a"},
+		{"X", ""},
+		{"1", ""},
+		{"", ""},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.option, func(t *testing.T) {
-			result := isValidOption(tt.option)
+			var result string
+			if option := findOption(flow, tt.option); option != nil {
+				result = option.NextState
+			}
 			if result != tt.expected {
-				t.Errorf("isValidOption(%s) = %v, expected %v", tt.option, result, tt.expected)
+				t.Errorf("findOption(%s) = %q, expected %q", tt.option, result, tt.expected)
 			}
 		})
 	}
 }
+
+func TestChatbotService_ProcessMedia(t *testing.T) {
+	repo := newMockRepository()
+	service := NewChatbotService(repo)
+
+	// Files sent while a data request is open are attached to it
+	service.ProcessMessage("user123", "B")
+	for _, ref := range []string{"user123/estudio1.pdf", "user123/estudio2.jpg"} {
+		response, err := service.ProcessMedia("user123", &models.MediaReference{Ref: ref, MimeType: "application/pdf"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(response.Text.Body, "Archivo recibido") {
+			t.Errorf("Expected media acknowledgment, got: %s", response.Text.Body)
+		}
+	}
+
+	userState, _ := repo.GetUserState("user123")
+	if userState.State != "option_b" {
+		t.Errorf("Expected media not to advance the conversation, got state %s", userState.State)
+	}
+	if userState.Data["datos_lectura_estudios_adjuntos"] != "user123/estudio1.pdf\nuser123/estudio2.jpg" {
+		t.Errorf("Expected media references to be attached, got %v", userState.Data)
+	}
+
+	// Files sent from a menu are not attached and the menu is repeated
+	response, err := service.ProcessMedia("user456", &models.MediaReference{Ref: "user456/foto.jpg"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(response.Text.Body, "no estamos esperando adjuntos") || !strings.Contains(response.Text.Body, "Chatbot BabyHome") {
+		t.Errorf("Expected unexpected media reply with the menu, got: %s", response.Text.Body)
+	}
+}
+
+func TestChatbotService_CollectsFieldsInOrder(t *testing.T) {
+	repo := newMockRepository()
+	repo.flows["option_a"].DataRequest = ""
+	repo.flows["option_a"].Fields = []models.DataField{
+		{Key: "nombre", Type: models.FieldTypeName, Prompt: "¿Cuál es el nombre del paciente?"},
+		{Key: "edad", Type: models.FieldTypeAge, Prompt: "¿Qué edad tiene?"},
+		{Key: "comprobante", Type: models.FieldTypeMedia, Prompt: "Enviá el comprobante de pago."},
+	}
+	service := NewChatbotService(repo)
+
+	// Entering the flow asks the first field
+	response, _ := service.ProcessMessage("user123", "A")
+	if !strings.Contains(response.Text.Body, "¿Cuál es el nombre del paciente?") {
+		t.Errorf("Expected first field prompt, got: %s", response.Text.Body)
+	}
+
+	steps := []struct {
+		message          string
+		expectedContains string
+		expectedIndex    int
+	}{
+		{message: "123", expectedContains: "nombre válido", expectedIndex: 0},
+		{message: "Juan Pérez", expectedContains: "¿Qué edad tiene?", expectedIndex: 1},
+		{message: "muchos", expectedContains: "edad válida", expectedIndex: 1},
+		{message: "8 meses", expectedContains: "Enviá el comprobante", expectedIndex: 2},
+		{message: "ya pagué", expectedContains: "foto o PDF", expectedIndex: 2},
+	}
+
+	for _, step := range steps {
+		response, err := service.ProcessMessage("user123", step.message)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(response.Text.Body, step.expectedContains) {
+			t.Errorf("After '%s' expected response to contain '%s', got: %s", step.message, step.expectedContains, response.Text.Body)
+		}
+
+		userState, _ := repo.GetUserState("user123")
+		if userState.State != "option_a" || userState.FieldIndex != step.expectedIndex {
+			t.Errorf("After '%s' expected option_a at field %d, got %s at field %d", step.message, step.expectedIndex, userState.State, userState.FieldIndex)
+		}
+	}
+
+	// The media field is answered with a file, completing the flow
+	response, err := service.ProcessMedia("user123", &models.MediaReference{Ref: "user123/comprobante.pdf"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(response.Text.Body, "Datos recopilados") {
+		t.Errorf("Expected data summary, got: %s", response.Text.Body)
+	}
+
+	userState, _ := repo.GetUserState("user123")
+	if userState.State != "collecting_data" || userState.FieldIndex != 0 {
+		t.Errorf("Expected collecting_data with field index reset, got %s at field %d", userState.State, userState.FieldIndex)
+	}
+
+	expected := map[string]string{"nombre": "Juan Pérez", "edad": "8 meses", "comprobante": "user123/comprobante.pdf"}
+	for key, value := range expected {
+		if userState.Data[key] != value {
+			t.Errorf("Expected %s to be '%s', got '%s'", key, value, userState.Data[key])
+		}
+	}
+}
+
+// recordingNotifier keeps the events it is notified about
+type recordingNotifier struct {
+	events      []*models.RequestCompletedEvent
+	emergencies []*models.EmergencyEvent
+	escalations []*models.EscalationEvent
+}
+
+func (n *recordingNotifier) NotifyRequestCompleted(event *models.RequestCompletedEvent) {
+	n.events = append(n.events, event)
+}
+
+func (n *recordingNotifier) NotifyEmergency(event *models.EmergencyEvent) {
+	n.emergencies = append(n.emergencies, event)
+}
+
+func (n *recordingNotifier) NotifyEscalation(event *models.EscalationEvent) {
+	n.escalations = append(n.escalations, event)
+}
+
+func TestChatbotService_NotifiesCompletedRequests(t *testing.T) {
+	repo := newMockRepository()
+	notifier := &recordingNotifier{}
+	service := NewChatbotService(repo, WithNotifier(notifier))
+
+	// Choosing an option does not complete anything yet
+	service.ProcessMessage("user123", "C")
+	if len(notifier.events) != 0 {
+		t.Fatalf("Expected no events before the data is collected, got %d", len(notifier.events))
+	}
+
+	service.ProcessMessage("user123", "Mañana por la tarde")
+	if len(notifier.events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(notifier.events))
+	}
+
+	event := notifier.events[0]
+	if event.UserID != "user123" || event.State != "option_c" || event.Option != "C" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+	if event.Data["datos_turno"] != "Mañana por la tarde" {
+		t.Errorf("Expected event to carry the collected data, got %v", event.Data)
+	}
+}
+
+// recordingMetrics keeps the state transitions it is told about
+type recordingMetrics struct {
+	transitions []string
+}
+
+func (m *recordingMetrics) StateTransition(from, to string) {
+	m.transitions = append(m.transitions, from+"->"+to)
+}
+
+func TestChatbotService_CountsStateTransitions(t *testing.T) {
+	repo := newMockRepository()
+	metrics := &recordingMetrics{}
+	service := NewChatbotService(repo, WithMetrics(metrics))
+
+	service.ProcessMessage("user123", "X") // invalid option, stays in welcome
+	service.ProcessMessage("user123", "C")
+	service.ProcessMessage("user123", "Mañana")
+
+	expected := []string{"welcome->option_c", "option_c->collecting_data"}
+	if strings.Join(metrics.transitions, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected transitions %v, got %v", expected, metrics.transitions)
+	}
+}
+
+func TestChatbotService_Handoff(t *testing.T) {
+	repo := newMockRepository()
+	repo.flows["option_c"].Handoff = true
+	service := NewChatbotService(repo, WithHandoffTimeout(time.Hour))
+
+	// Completing a data request marked for handoff pauses the bot
+	service.ProcessMessage("user123", "C")
+	response, err := service.ProcessMessage("user123", "Mañana por la tarde")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(response.Text.Body, "continuará la conversación personalmente") || !strings.Contains(response.Text.Body, "Mañana por la tarde") {
+		t.Errorf("Expected handoff notice with the collected data, got: %s", response.Text.Body)
+	}
+
+	userState, _ := repo.GetUserState("user123")
+	if !userState.Handoff || userState.State != "collecting_data" {
+		t.Fatalf("Expected handoff in collecting_data, got handoff=%v in %s", userState.Handoff, userState.State)
+	}
+
+	// The bot stays silent while staff answers
+	response, err = service.ProcessMessage("user123", "A")
+	if err != nil || response != nil {
+		t.Errorf("Expected no reply during handoff, got %+v (err %v)", response, err)
+	}
+	response, err = service.ProcessMedia("user123", &models.MediaReference{Ref: "user123/foto.jpg"})
+	if err != nil || response != nil {
+		t.Errorf("Expected no reply to media during handoff, got %+v (err %v)", response, err)
+	}
+
+	userState, _ = repo.GetUserState("user123")
+	if userState.State != "collecting_data" {
+		t.Errorf("Expected state to be kept during handoff, got %s", userState.State)
+	}
+
+	// Ending the handoff returns the user to the bot
+	if _, err := service.EndHandoff("user123"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	response, _ = service.ProcessMessage("user123", "A")
+	if response == nil || !strings.Contains(response.Text.Body, "consulta telefónica") {
+		t.Errorf("Expected the bot to answer after the handoff ended, got %+v", response)
+	}
+
+	// Staff can also take over any conversation
+	state, err := service.StartHandoff("user456")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !state.Handoff {
+		t.Errorf("Expected handoff to be started")
+	}
+	if response, _ := service.ProcessMessage("user456", "hola"); response != nil {
+		t.Errorf("Expected no reply during handoff, got: %s", response.Text.Body)
+	}
+
+	// Handoffs older than the timeout return to the bot
+	userState, _ = repo.GetUserState("user456")
+	userState.HandoffAt = time.Now().Add(-2 * time.Hour)
+	repo.SaveUserState(userState)
+
+	response, _ = service.ProcessMessage("user456", "hola")
+	if response == nil || !strings.Contains(response.Text.Body, "Chatbot BabyHome") {
+		t.Errorf("Expected the welcome menu after the handoff timed out, got %+v", response)
+	}
+	userState, _ = repo.GetUserState("user456")
+	if userState.Handoff {
+		t.Errorf("Expected handoff to be cleared after the timeout")
+	}
+}
+
+func TestChatbotService_AdminSessionControl(t *testing.T) {
+	repo := newMockRepository()
+	service := NewChatbotService(repo)
+
+	service.ProcessMessage("user123", "A")
+
+	sessions, err := service.ListSessions()
+	if err != nil || len(sessions) != 1 || sessions[0].State != "option_a" {
+		t.Fatalf("Expected one session in option_a, got %+v (err %v)", sessions, err)
+	}
+
+	// Forcing a state moves the user without replying
+	state, err := service.SetState("user123", "option_b")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if state.State != "option_b" {
+		t.Errorf("Expected option_b, got %s", state.State)
+	}
+
+	if _, err := service.SetState("user123", "missing"); err != errors.ErrFlowNotFound {
+		t.Errorf("Expected ErrFlowNotFound for an unknown state, got %v", err)
+	}
+
+	// Resetting starts the conversation over
+	if err := service.ResetSession("user123"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := service.ResetSession("user123"); err != errors.ErrUserNotFound {
+		t.Errorf("Expected ErrUserNotFound for a missing session, got %v", err)
+	}
+
+	state, _ = service.GetSession("user123")
+	if state.State != "welcome" {
+		t.Errorf("Expected a fresh session after reset, got %s", state.State)
+	}
+}
+
+func TestValidateField(t *testing.T) {
+	tests := []struct {
+		name          string
+		fieldType     string
+		answer        string
+		expectedValue string
+		expectedValid bool
+	}{
+		{"Name", models.FieldTypeName, "  María José  ", "María José", true},
+		{"Name with digits", models.FieldTypeName, "Juan 2", "", false},
+		{"Age in years", models.FieldTypeAge, "3", "3 años", true},
+		{"Age with unit", models.FieldTypeAge, "8 Meses", "8 meses", true},
+		{"Age of one month", models.FieldTypeAge, "1 mes", "1 mes", true},
+		{"Age of one week", models.FieldTypeAge, "1 semana", "1 semana", true},
+		{"Age of one day", models.FieldTypeAge, "1 dia", "1 día", true},
+		{"Age out of range", models.FieldTypeAge, "200", "", false},
+		{"Age not a number", models.FieldTypeAge, "tres", "", false},
+		{"Date", models.FieldTypeDate, "5/3/2024", "05/03/2024", true},
+		{"Invalid date", models.FieldTypeDate, "31/02/2024", "", false},
+		{"Text", models.FieldTypeText, "Fiebre desde ayer", "Fiebre desde ayer", true},
+		{"Empty text", models.FieldTypeText, " ", "", false},
+		{"Media as text", models.FieldTypeMedia, "adjunto", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, valid := validateField(models.DataField{Key: "campo", Type: tt.fieldType}, tt.answer)
+			if valid != tt.expectedValid {
+				t.Fatalf("Expected valid=%v for '%s', got %v", tt.expectedValid, tt.answer, valid)
+			}
+			if valid && value != tt.expectedValue {
+				t.Errorf("Expected value '%s', got '%s'", tt.expectedValue, value)
+			}
+		})
+	}
+}
+
+func TestNormalizeDate_DayAndMonth(t *testing.T) {
+	tests := []struct {
+		name          string
+		answer        string
+		now           time.Time
+		expectedValue string
+		expectedValid bool
+	}{
+		{"Current year", "5/3", time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC), "05/03/2026", true},
+		{"Leap day in a leap year", "29/02", time.Date(2024, 10, 16, 12, 0, 0, 0, time.UTC), "29/02/2024", true},
+		{"Leap day in a non-leap year", "29/02", time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, valid := normalizeDate(tt.answer, tt.now)
+			if valid != tt.expectedValid || value != tt.expectedValue {
+				t.Errorf("Expected '%s' (valid=%v), got '%s' (valid=%v)", tt.expectedValue, tt.expectedValid, value, valid)
+			}
+		})
+	}
+}
+
+func TestChatbotService_InteractiveMenus(t *testing.T) {
+	repo := newMockRepository()
+	repo.flows["welcome"].Options = append(repo.flows["welcome"].Options,
+		models.ChatbotOption{ID: "E", Label: "E", Title: "Otros", Description: "Otros servicios", NextState: "services"})
+	repo.flows["services"] = &models.ChatbotFlow{
+		State:   "services",
+		Message: "¿Qué servicio te interesa?",
+		Options: []models.ChatbotOption{
+			{ID: "1", Label: "1", Description: "Vacunas", NextState: "welcome"},
+			{ID: "2", Label: "2", Description: "Controles", NextState: "welcome"},
+		},
+	}
+	service := NewChatbotService(repo, WithInteractiveMenus())
+
+	// More than three options are sent as a list
+	response, err := service.ProcessMessage("user123", "hola")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.Type != "interactive" || response.Interactive == nil || response.Interactive.Type != "list" {
+		t.Fatalf("Expected an interactive list, got %+v", response)
+	}
+	rows := response.Interactive.Action.Sections[0].Rows
+	if len(rows) != 5 || rows[0].ID != "A" || rows[4].ID != "E" || rows[4].Title != "Otros" {
+		t.Errorf("Unexpected list rows %+v", rows)
+	}
+	if len([]rune(rows[0].Title)) > maxRowTitleLength {
+		t.Errorf("Expected row title to be truncated, got %q", rows[0].Title)
+	}
+	if !strings.Contains(response.Interactive.Body.Body, "⚠️") {
+		t.Errorf("Expected the invalid option warning in the body, got %q", response.Interactive.Body.Body)
+	}
+
+	// Selecting a row sends its ID, up to three options become reply buttons
+	response, err = service.ProcessMessage("user123", "E")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.Interactive == nil || response.Interactive.Type != "button" || len(response.Interactive.Action.Buttons) != 2 {
+		t.Fatalf("Expected reply buttons, got %+v", response.Interactive)
+	}
+	if button := response.Interactive.Action.Buttons[1]; button.Type != "reply" || button.Reply.ID != "2" || button.Reply.Title != "Controles" {
+		t.Errorf("Unexpected button %+v", button)
+	}
+
+	// States without options stay plain text
+	response, _ = service.ProcessMessage("user456", "A")
+	if response.Type != "text" || response.Interactive != nil {
+		t.Errorf("Expected data request prompts to be plain text, got %s", response.Type)
+	}
+	response, _ = service.ProcessMessage("user456", "Juan, 3 años")
+	if response.Type != "interactive" {
+		t.Errorf("Expected the data collection menu to be interactive, got %s", response.Type)
+	}
+}
+
+// fixedHours is a BusinessHours that is always open or always closed
+type fixedHours struct {
+	open  bool
+	start time.Time
+}
+
+func (h fixedHours) IsOpen(t time.Time) bool {
+	return h.open
+}
+
+func (h fixedHours) CallbackWindow(t time.Time) (time.Time, time.Time, bool) {
+	if h.open {
+		return t, t.Add(time.Hour), true
+	}
+	return h.start, h.start.Add(4 * time.Hour), true
+}
+
+func TestChatbotService_BusinessHours(t *testing.T) {
+	monday := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	closed := fixedHours{start: monday}
+
+	t.Run("append mode adds the notice when the conversation starts and ends", func(t *testing.T) {
+		notifier := &recordingNotifier{}
+		service := NewChatbotService(newMockRepository(), WithBusinessHours(closed, AfterHoursAppend), WithNotifier(notifier))
+
+		response, _ := service.ProcessMessage("user123", "C")
+		if !strings.Contains(response.Text.Body, "fuera del horario") || !strings.Contains(response.Text.Body, "lunes 19/10 a las 09:00") {
+			t.Errorf("Expected the after-hours notice, got %q", response.Text.Body)
+		}
+
+		response, _ = service.ProcessMessage("user123", "Mañana por la tarde")
+		if !strings.Contains(response.Text.Body, "fuera del horario") {
+			t.Errorf("Expected the after-hours notice on completion, got %q", response.Text.Body)
+		}
+
+		if len(notifier.events) != 1 {
+			t.Fatalf("Expected 1 event, got %d", len(notifier.events))
+		}
+		window := notifier.events[0].CallbackWindow
+		if window == nil || !window.From.Equal(monday) || !window.AfterHours {
+			t.Errorf("Expected the request to be tagged with the next opening, got %+v", window)
+		}
+	})
+
+	t.Run("replace mode only answers with the notice", func(t *testing.T) {
+		repo := newMockRepository()
+		service := NewChatbotService(repo, WithBusinessHours(closed, AfterHoursReplace))
+
+		response, err := service.ProcessMessage("user123", "C")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.HasPrefix(response.Text.Body, "🌙") {
+			t.Errorf("Expected only the after-hours notice, got %q", response.Text.Body)
+		}
+		if state, _ := repo.GetUserState("user123"); state.State != "welcome" {
+			t.Errorf("Expected the conversation not to advance, got %s", state.State)
+		}
+	})
+
+	t.Run("open hours leave replies untouched", func(t *testing.T) {
+		notifier := &recordingNotifier{}
+		service := NewChatbotService(newMockRepository(), WithBusinessHours(fixedHours{open: true}, AfterHoursReplace), WithNotifier(notifier))
+
+		response, _ := service.ProcessMessage("user123", "C")
+		if strings.Contains(response.Text.Body, "fuera del horario") {
+			t.Errorf("Expected no after-hours notice, got %q", response.Text.Body)
+		}
+
+		service.ProcessMessage("user123", "Mañana")
+		if window := notifier.events[0].CallbackWindow; window == nil || window.AfterHours {
+			t.Errorf("Expected an in-hours callback window, got %+v", window)
+		}
+	})
+}
+
+func TestEmergencyDetector(t *testing.T) {
+	detector := newEmergencyDetector(DefaultEmergencyKeywords)
+
+	tests := []struct {
+		message string
+		keyword string
+		detect  bool
+	}{
+		{"Mi bebé NO RESPIRA!!", "no respira", true},
+		{"está convulsionando, qué hago", "convulsionando", true},
+		{"Tuvo una convulsión hace 5 minutos", "convulsion", true},
+		{"se puso moradO", "se puso morado", true},
+		{"Hola, quiero un turno", "", false},
+		{"respira bien pero tiene tos", "", false},
+		{"C", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			keyword, detected := detector.Detect(tt.message)
+			if detected != tt.detect || keyword != tt.keyword {
+				t.Errorf("Expected (%q, %v), got (%q, %v)", tt.keyword, tt.detect, keyword, detected)
+			}
+		})
+	}
+
+	if _, detected := newEmergencyDetector(nil).Detect("no respira"); detected {
+		t.Error("Expected an empty keyword list to disable detection")
+	}
+}
+
+func TestChatbotService_Emergency(t *testing.T) {
+	repo := newMockRepository()
+	notifier := &recordingNotifier{}
+	service := NewChatbotService(repo, WithNotifier(notifier))
+
+	service.ProcessMessage("user123", "C")
+	response, err := service.ProcessMessage("user123", "Mi hija no respira bien, está morada")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(response.Text.Body, "107") {
+		t.Errorf("Expected the emergency instructions, got %q", response.Text.Body)
+	}
+
+	state, _ := repo.GetUserState("user123")
+	if !state.Emergency || state.EmergencyAt.IsZero() {
+		t.Errorf("Expected the session to be flagged, got %+v", state)
+	}
+	if state.State != "option_c" || state.Data["datos_turno"] != "" {
+		t.Errorf("Expected the message not to be handled by the current state, got %+v", state)
+	}
+
+	if len(notifier.emergencies) != 1 {
+		t.Fatalf("Expected 1 emergency notification, got %d", len(notifier.emergencies))
+	}
+	if event := notifier.emergencies[0]; event.UserID != "user123" || event.Keyword != "no respira" || event.State != "option_c" {
+		t.Errorf("Unexpected emergency event %+v", event)
+	}
+
+	// Staff being in the conversation does not silence the emergency reply
+	service.StartHandoff("user123")
+	if response, _ := service.ProcessMessage("user123", "CONVULSIONA"); response == nil {
+		t.Error("Expected the emergency reply during a handoff")
+	}
+}
+
+func TestChatbotService_GlobalCommands(t *testing.T) {
+	repo := newMockRepository()
+	repo.flows["welcome"].Options = append(repo.flows["welcome"].Options,
+		models.ChatbotOption{ID: "E", Label: "E", Description: "Otros servicios", NextState: "services"})
+	repo.flows["services"] = &models.ChatbotFlow{
+		State:   "services",
+		Message: "1. Vacunas",
+		Options: []models.ChatbotOption{
+			{ID: "1", Label: "1", Description: "Vacunas", NextState: "option_a"},
+		},
+	}
+	repo.flows["option_a"].DataRequest = ""
+	repo.flows["option_a"].Fields = []models.DataField{
+		{Key: "nombre", Type: models.FieldTypeName, Prompt: "¿Cuál es el nombre del paciente?"},
+		{Key: "edad", Type: models.FieldTypeAge, Prompt: "¿Qué edad tiene?"},
+	}
+	service := NewChatbotService(repo)
+
+	steps := []struct {
+		message          string
+		expectedState    string
+		expectedIndex    int
+		expectedContains string
+	}{
+		{message: "E", expectedState: "services", expectedContains: "1. Vacunas"},
+		{message: "1", expectedState: "option_a", expectedContains: "¿Cuál es el nombre del paciente?"},
+		{message: "Juan", expectedState: "option_a", expectedIndex: 1, expectedContains: "¿Qué edad tiene?"},
+		{message: "ayuda", expectedState: "option_a", expectedIndex: 1, expectedContains: "VOLVER para volver al paso anterior"},
+		{message: "Volver", expectedState: "option_a", expectedIndex: 0, expectedContains: "¿Cuál es el nombre del paciente?"},
+		{message: "atrás", expectedState: "services", expectedContains: "1. Vacunas"},
+		{message: "VOLVER", expectedState: "welcome", expectedContains: "Chatbot BabyHome"},
+		{message: "VOLVER", expectedState: "welcome", expectedContains: "Chatbot BabyHome"},
+		{message: "E", expectedState: "services"},
+		{message: "Menú", expectedState: "welcome", expectedContains: "Chatbot BabyHome"},
+	}
+
+	for _, step := range steps {
+		response, err := service.ProcessMessage("user123", step.message)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(response.Text.Body, step.expectedContains) {
+			t.Errorf("After '%s' expected response to contain '%s', got: %s", step.message, step.expectedContains, response.Text.Body)
+		}
+
+		userState, _ := repo.GetUserState("user123")
+		if userState.State != step.expectedState || userState.FieldIndex != step.expectedIndex {
+			t.Errorf("After '%s' expected %s at field %d, got %s at field %d", step.message, step.expectedState, step.expectedIndex, userState.State, userState.FieldIndex)
+		}
+	}
+
+	// Menu keeps the collected data, restart discards it
+	userState, _ := repo.GetUserState("user123")
+	if userState.Data["nombre"] != "Juan" || len(userState.History) != 0 {
+		t.Errorf("Expected the data to survive the menu command and the history to be cleared, got %+v", userState)
+	}
+	service.ProcessMessage("user123", "reiniciar")
+	userState, _ = repo.GetUserState("user123")
+	if len(userState.Data) != 0 || userState.State != "welcome" {
+		t.Errorf("Expected restart to discard the data, got %+v", userState)
+	}
+}
+
+func TestChatbotService_CustomCommands(t *testing.T) {
+	repo := newMockRepository()
+	service := NewChatbotService(repo, WithCommands(map[Command][]string{CommandMenu: {"principal"}}))
+
+	service.ProcessMessage("user123", "C")
+	service.ProcessMessage("user123", "PRINCIPAL")
+	if userState, _ := repo.GetUserState("user123"); userState.State != "welcome" {
+		t.Errorf("Expected the custom synonym to show the menu, got %s", userState.State)
+	}
+
+	// Default synonyms no longer apply
+	service.ProcessMessage("user123", "C")
+	service.ProcessMessage("user123", "menu")
+	userState, _ := repo.GetUserState("user123")
+	if userState.State != "collecting_data" || userState.Data["datos_turno"] != "menu" {
+		t.Errorf("Expected 'menu' to be stored as data, got %+v", userState)
+	}
+}
+
+func TestChatbotService_EscalatesRepeatedInvalidInput(t *testing.T) {
+	repo := newMockRepository()
+	repo.flows["option_a"].DataRequest = ""
+	repo.flows["option_a"].Fields = []models.DataField{
+		{Key: "edad", Type: models.FieldTypeAge, Prompt: "¿Qué edad tiene?"},
+	}
+	notifier := &recordingNotifier{}
+	service := NewChatbotService(repo, WithNotifier(notifier), WithInvalidInputEscalation(3))
+
+	steps := []struct {
+		message          string
+		expectedContains string
+		expectedAttempts int
+	}{
+		{message: "X", expectedContains: "opción válida", expectedAttempts: 1},
+		{message: "A", expectedContains: "¿Qué edad tiene?", expectedAttempts: 0}, // a valid answer resets the streak
+		{message: "muchos", expectedContains: "edad válida", expectedAttempts: 1},
+		{message: "no sé", expectedContains: "edad válida", expectedAttempts: 2},
+		{message: "???", expectedContains: "continuará la conversación personalmente", expectedAttempts: 0},
+	}
+
+	for _, step := range steps {
+		response, err := service.ProcessMessage("user123", step.message)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(response.Text.Body, step.expectedContains) {
+			t.Errorf("After '%s' expected response to contain '%s', got: %s", step.message, step.expectedContains, response.Text.Body)
+		}
+		if userState, _ := repo.GetUserState("user123"); userState.InvalidAttempts != step.expectedAttempts {
+			t.Errorf("After '%s' expected %d invalid attempts, got %d", step.message, step.expectedAttempts, userState.InvalidAttempts)
+		}
+	}
+
+	userState, _ := repo.GetUserState("user123")
+	if !userState.Handoff {
+		t.Error("Expected the conversation to be handed to staff")
+	}
+	if len(notifier.escalations) != 1 || notifier.escalations[0].LastMessage != "???" || notifier.escalations[0].State != "option_a" {
+		t.Errorf("Expected one escalation for option_a, got %+v", notifier.escalations)
+	}
+
+	// The bot stays silent once staff takes over
+	if response, _ := service.ProcessMessage("user123", "hola?"); response != nil {
+		t.Errorf("Expected no reply during the handoff, got %q", response.Text.Body)
+	}
+}
+
+func TestChatbotService_InvalidInputWithoutEscalation(t *testing.T) {
+	repo := newMockRepository()
+	service := NewChatbotService(repo)
+
+	for i := 0; i < 5; i++ {
+		response, _ := service.ProcessMessage("user123", "X")
+		if !strings.Contains(response.Text.Body, "opción válida") || !strings.Contains(response.Text.Body, "Chatbot BabyHome") {
+			t.Fatalf("Expected the invalid option message followed by the menu, got: %s", response.Text.Body)
+		}
+	}
+
+	if userState, _ := repo.GetUserState("user123"); userState.Handoff {
+		t.Error("Expected no handoff when escalation is disabled")
+	}
+}
+
+// fixedCalendar offers the same slots at every location
+type fixedCalendar struct {
+	locations []models.Location
+	starts    []time.Time
+}
+
+func newFixedCalendar() *fixedCalendar {
+	tomorrow := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	return &fixedCalendar{
+		locations: []models.Location{
+			{ID: "cervantes", Name: "Centro Médico Cervantes"},
+			{ID: "ospep", Name: "Consultorios OSPEP"},
+		},
+		starts: []time.Time{tomorrow, tomorrow.Add(20 * time.Minute), tomorrow.Add(40 * time.Minute)},
+	}
+}
+
+func (c *fixedCalendar) Locations() []models.Location {
+	return c.locations
+}
+
+func (c *fixedCalendar) Timezone() *time.Location {
+	return time.Local
+}
+
+func (c *fixedCalendar) Slots(locationID string, from time.Time) []models.Slot {
+	var slots []models.Slot
+	for _, start := range c.starts {
+		slots = append(slots, models.Slot{LocationID: locationID, Start: start, End: start.Add(20 * time.Minute)})
+	}
+	return slots
+}
+
+func TestChatbotService_BooksAppointments(t *testing.T) {
+	repo := newMockRepository()
+	repo.flows["option_c"].Booking = true
+	calendar := newFixedCalendar()
+	appointments := repository.NewInMemoryAppointmentRepository()
+	notifier := &recordingNotifier{}
+	service := NewChatbotService(repo, WithBooking(calendar, appointments), WithNotifier(notifier))
+
+	steps := []struct {
+		message          string
+		expectedContains string
+	}{
+		{message: "C", expectedContains: "2. Consultorios OSPEP"},
+		{message: "3", expectedContains: "No entendimos"},
+		{message: "ospep", expectedContains: "1. " + formatTestSlot(calendar.starts[0])},
+		{message: "2", expectedContains: "nombre del paciente"},
+		{message: "Juan Pérez", expectedContains: "¿Confirmás este turno?"},
+		{message: "Sí", expectedContains: "Tu turno quedó confirmado"},
+	}
+	for _, step := range steps {
+		response, err := service.ProcessMessage("user123", step.message)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(response.Text.Body, step.expectedContains) {
+			t.Errorf("After '%s' expected response to contain '%s', got: %s", step.message, step.expectedContains, response.Text.Body)
+		}
+	}
+
+	userState, _ := repo.GetUserState("user123")
+	if userState.State != "collecting_data" || userState.Booking != nil {
+		t.Errorf("Expected collecting_data once booked, got %s with %+v", userState.State, userState.Booking)
+	}
+
+	booked, _ := appointments.ListByUser("user123", time.Now())
+	if len(booked) != 1 || booked[0].LocationID != "ospep" || !booked[0].Start.Equal(calendar.starts[1]) || booked[0].PatientName != "Juan Pérez" {
+		t.Fatalf("Expected the second OSPEP slot booked for Juan Pérez, got %+v", booked)
+	}
+	if len(notifier.events) != 1 || notifier.events[0].Data["consultorio"] != "Consultorios OSPEP" {
+		t.Errorf("Expected staff to be told about the booking, got %+v", notifier.events)
+	}
+
+	// Another patient is no longer offered the booked slot
+	service.ProcessMessage("user456", "C")
+	response, _ := service.ProcessMessage("user456", "2")
+	if strings.Contains(response.Text.Body, formatTestSlot(calendar.starts[1])) || !strings.Contains(response.Text.Body, "2. "+formatTestSlot(calendar.starts[2])) {
+		t.Errorf("Expected only the free slots, got: %s", response.Text.Body)
+	}
+
+	// Asking again offers to reschedule or cancel, cancelling frees the slot
+	response, _ = service.ProcessMessage("user123", "C")
+	if !strings.Contains(response.Text.Body, "Ya tenés un turno reservado") {
+		t.Errorf("Expected the upcoming appointment, got: %s", response.Text.Body)
+	}
+	response, _ = service.ProcessMessage("user123", "2")
+	if !strings.Contains(response.Text.Body, "Cancelamos tu turno") {
+		t.Errorf("Expected the cancellation, got: %s", response.Text.Body)
+	}
+	if booked, _ := appointments.ListByUser("user123", time.Now()); len(booked) != 0 {
+		t.Errorf("Expected no appointment left, got %+v", booked)
+	}
+}
+
+func TestChatbotService_ReschedulesAppointment(t *testing.T) {
+	repo := newMockRepository()
+	repo.flows["option_c"].Booking = true
+	calendar := newFixedCalendar()
+	calendar.locations = calendar.locations[:1]
+	appointments := repository.NewInMemoryAppointmentRepository()
+	service := NewChatbotService(repo, WithBooking(calendar, appointments))
+
+	existing := &models.Appointment{UserID: "user123", PatientName: "Juan Pérez", LocationID: "cervantes",
+		LocationName: "Centro Médico Cervantes", Start: calendar.starts[0], End: calendar.starts[0].Add(20 * time.Minute)}
+	appointments.Book(existing)
+
+	// Reschedule, a single location is not asked for
+	for _, message := range []string{"C", "1", "1"} {
+		service.ProcessMessage("user123", message)
+	}
+	response, _ := service.ProcessMessage("user123", "Juan Pérez")
+	if !strings.Contains(response.Text.Body, "¿Confirmás este turno?") {
+		t.Fatalf("Expected the confirmation, got: %s", response.Text.Body)
+	}
+
+	// Someone else takes the chosen slot before the patient confirms
+	chosen := calendar.starts[1]
+	appointments.Book(&models.Appointment{UserID: "user456", LocationID: "cervantes", Start: chosen, End: chosen.Add(20 * time.Minute)})
+	response, _ = service.ProcessMessage("user123", "si")
+	if !strings.Contains(response.Text.Body, "acaba de ser reservado") || !strings.Contains(response.Text.Body, "1. "+formatTestSlot(calendar.starts[2])) {
+		t.Fatalf("Expected the remaining slots after losing the race, got: %s", response.Text.Body)
+	}
+
+	for _, message := range []string{"1", "Juan Pérez", "si"} {
+		response, _ = service.ProcessMessage("user123", message)
+	}
+	if !strings.Contains(response.Text.Body, "Tu turno quedó confirmado") {
+		t.Fatalf("Expected the confirmation, got: %s", response.Text.Body)
+	}
+
+	// The old appointment is given up once the new one is booked
+	booked, _ := appointments.ListByUser("user123", time.Now())
+	if len(booked) != 1 || !booked[0].Start.Equal(calendar.starts[2]) {
+		t.Errorf("Expected only the rescheduled appointment, got %+v", booked)
+	}
+}
+
+func TestChatbotService_BookingFlowWithoutCalendar(t *testing.T) {
+	repo := newMockRepository()
+	repo.flows["option_c"].Booking = true
+	service := NewChatbotService(repo)
+
+	response, _ := service.ProcessMessage("user123", "C")
+	if !strings.Contains(response.Text.Body, "Para turnos comunicarse") {
+		t.Errorf("Expected the flow message when booking is not configured, got: %s", response.Text.Body)
+	}
+}
+
+func formatTestSlot(t time.Time) string {
+	return weekdayNames[t.Weekday()] + " " + t.Format("02/01 15:04")
+}
+
+// recordingReminders records the reminders the service asks for
+type recordingReminders struct {
+	booked    []string
+	cancelled []string
+	callbacks []*models.CallbackWindow
+}
+
+func (r *recordingReminders) AppointmentBooked(appointment *models.Appointment) {
+	r.booked = append(r.booked, appointment.ID)
+}
+
+func (r *recordingReminders) AppointmentCancelled(appointment *models.Appointment) {
+	r.cancelled = append(r.cancelled, appointment.ID)
+}
+
+func (r *recordingReminders) CallbackPending(userID string, window *models.CallbackWindow) {
+	r.callbacks = append(r.callbacks, window)
+}
+
+func TestChatbotService_SchedulesReminders(t *testing.T) {
+	t.Run("appointments are reminded until cancelled", func(t *testing.T) {
+		repo := newMockRepository()
+		repo.flows["option_c"].Booking = true
+		reminders := &recordingReminders{}
+		appointments := repository.NewInMemoryAppointmentRepository()
+		service := NewChatbotService(repo, WithBooking(newFixedCalendar(), appointments), WithReminders(reminders))
+
+		for _, message := range []string{"C", "1", "1", "Juan Pérez", "si"} {
+			service.ProcessMessage("user123", message)
+		}
+		booked, _ := appointments.ListByUser("user123", time.Now())
+		if len(booked) != 1 || len(reminders.booked) != 1 || reminders.booked[0] != booked[0].ID {
+			t.Fatalf("Expected a reminder for the booked appointment, got %+v", reminders.booked)
+		}
+
+		service.ProcessMessage("user123", "C")
+		service.ProcessMessage("user123", "2")
+		if len(reminders.cancelled) != 1 || reminders.cancelled[0] != booked[0].ID {
+			t.Errorf("Expected the reminder cancelled with the appointment, got %+v", reminders.cancelled)
+		}
+		if len(reminders.callbacks) != 0 {
+			t.Errorf("Expected no callback reminder for a booking, got %d", len(reminders.callbacks))
+		}
+	})
+
+	t.Run("requests made after hours are reminded", func(t *testing.T) {
+		monday := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+		reminders := &recordingReminders{}
+		service := NewChatbotService(newMockRepository(), WithBusinessHours(fixedHours{start: monday}, AfterHoursAppend), WithReminders(reminders))
+
+		service.ProcessMessage("user123", "C")
+		service.ProcessMessage("user123", "Mañana por la tarde")
+		if len(reminders.callbacks) != 1 || !reminders.callbacks[0].From.Equal(monday) {
+			t.Errorf("Expected a callback reminder at the next opening, got %+v", reminders.callbacks)
+		}
+	})
+
+	t.Run("requests made during hours are not reminded", func(t *testing.T) {
+		reminders := &recordingReminders{}
+		service := NewChatbotService(newMockRepository(), WithBusinessHours(fixedHours{open: true}, AfterHoursAppend), WithReminders(reminders))
+
+		service.ProcessMessage("user123", "C")
+		service.ProcessMessage("user123", "Mañana por la tarde")
+		if len(reminders.callbacks) != 0 {
+			t.Errorf("Expected no callback reminder, got %d", len(reminders.callbacks))
+		}
+	})
+}
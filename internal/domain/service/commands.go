@@ -0,0 +1,158 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+// Command is a reserved word the patient can send in any state
+type Command string
+
+// Global commands
+const (
+	CommandMenu    Command = "menu"    // Show the main menu, keeping the collected data
+	CommandBack    Command = "back"    // Return to the previous field or state
+	CommandRestart Command = "restart" // Discard the collected data and start over
+	CommandHelp    Command = "help"    // Explain the commands and repeat the current question
+)
+
+// helpState is the system flow whose message replaces the generated command help
+const helpState = "help"
+
+// maxHistory bounds the navigation history stored in a session
+const maxHistory = 20
+
+// DefaultCommands are the Spanish synonyms of each global command. They are
+// matched against the whole message, ignoring case and accents.
+var DefaultCommands = map[Command][]string{
+	CommandMenu:    {"MENU", "MENÚ", "OPCIONES"},
+	CommandBack:    {"VOLVER", "ATRAS", "ATRÁS"},
+	CommandRestart: {"INICIO", "REINICIAR", "EMPEZAR DE NUEVO"},
+	CommandHelp:    {"AYUDA"},
+}
+
+// commandOrder is the order commands are listed in the help message
+var commandOrder = []Command{CommandMenu, CommandBack, CommandRestart, CommandHelp}
+
+// commandSet recognizes global commands
+type commandSet struct {
+	synonyms map[string]Command // Normalized synonym to command
+	labels   map[Command]string // Synonym shown in the help message
+}
+
+func newCommandSet(commands map[Command][]string) *commandSet {
+	c := &commandSet{
+		synonyms: make(map[string]Command),
+		labels:   make(map[Command]string),
+	}
+	for command, synonyms := range commands {
+		for _, synonym := range synonyms {
+			normalized := strings.TrimSpace(normalizeForMatching(synonym))
+			if normalized == "" {
+				continue
+			}
+			c.synonyms[normalized] = command
+			if _, exists := c.labels[command]; !exists {
+				c.labels[command] = strings.ToUpper(strings.TrimSpace(synonym))
+			}
+		}
+	}
+	return c
+}
+
+// Match returns the command the whole message stands for
+func (c *commandSet) Match(message string) (Command, bool) {
+	command, ok := c.synonyms[strings.TrimSpace(normalizeForMatching(message))]
+	return command, ok
+}
+
+// help lists the configured commands
+func (c *commandSet) help() string {
+	descriptions := map[Command]string{
+		CommandMenu:    "para ver el menú principal",
+		CommandBack:    "para volver al paso anterior",
+		CommandRestart: "para empezar de nuevo",
+		CommandHelp:    "para ver esta ayuda",
+	}
+
+	var b strings.Builder
+	b.WriteString("ℹ️ Podés escribir en cualquier momento:")
+	for _, command := range commandOrder {
+		if label, ok := c.labels[command]; ok {
+			fmt.Fprintf(&b, "\n• %s %s", label, descriptions[command])
+		}
+	}
+	return b.String()
+}
+
+// handleCommand runs a global command
+func (s *chatbotService) handleCommand(userState *models.ChatbotState, flow *models.ChatbotFlow, command Command) (*models.WhatsAppResponse, string, error) {
+	switch command {
+	case CommandBack:
+		return s.goBack(userState, flow)
+	case CommandRestart:
+		userState.Option = ""
+		userState.Data = make(map[string]string)
+		return s.showMainMenu(userState)
+	case CommandHelp:
+		body := s.systemMessage(helpState, s.commands.help()) + "\n\n" + s.currentPrompt(userState, flow)
+		return s.newFlowResponse(userState, flow, body), flow.State, nil
+	default:
+		return s.showMainMenu(userState)
+	}
+}
+
+// goBack asks the previous field again, or returns to the previous state of the navigation history
+func (s *chatbotService) goBack(userState *models.ChatbotState, flow *models.ChatbotFlow) (*models.WhatsAppResponse, string, error) {
+	if len(flow.Fields) > 0 && userState.FieldIndex > 0 {
+		userState.FieldIndex--
+		return s.newTextResponse(userState, flow.Fields[userState.FieldIndex].Prompt), flow.State, nil
+	}
+
+	if len(userState.History) == 0 {
+		return s.showMainMenu(userState)
+	}
+
+	previous := userState.History[len(userState.History)-1]
+	userState.History = userState.History[:len(userState.History)-1]
+
+	previousFlow, err := s.repo.GetFlowByState(previous)
+	if err != nil {
+		// The state no longer exists in the flow definition
+		return s.showMainMenu(userState)
+	}
+
+	return s.enterFlow(userState, previousFlow, previousFlow.Message)
+}
+
+// showMainMenu returns the user to the initial state, forgetting the navigation history
+func (s *chatbotService) showMainMenu(userState *models.ChatbotState) (*models.WhatsAppResponse, string, error) {
+	flow, err := s.repo.GetFlowByState(initialState)
+	if err != nil {
+		return nil, "", err
+	}
+
+	userState.History = nil
+	return s.enterFlow(userState, flow, flow.Message)
+}
+
+// pushHistory records a state the user navigated away from
+func pushHistory(userState *models.ChatbotState, state string) {
+	userState.History = append(userState.History, state)
+	if len(userState.History) > maxHistory {
+		userState.History = userState.History[len(userState.History)-maxHistory:]
+	}
+}
+
+// currentPrompt returns the question the user is expected to answer in the current state
+func (s *chatbotService) currentPrompt(userState *models.ChatbotState, flow *models.ChatbotFlow) string {
+	if s.isBookingFlow(flow) && userState.Booking != nil {
+		return s.bookingPrompt(userState.Booking)
+	}
+	if len(flow.Fields) > 0 {
+		return currentField(userState, flow).Prompt
+	}
+	return flow.Message
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+// emergencyState is the system flow whose message is sent when an emergency is detected
+const emergencyState = "emergency"
+
+// DefaultEmergencyKeywords are the phrases that describe an emergency in a
+// pediatric practice. They are matched as whole words, ignoring case and accents.
+var DefaultEmergencyKeywords = []string{
+	"no respira",
+	"no puede respirar",
+	"le cuesta respirar",
+	"dificultad para respirar",
+	"se ahoga",
+	"se esta ahogando",
+	"convulsiona",
+	"convulsionando",
+	"convulsion",
+	"convulsiones",
+	"inconsciente",
+	"no reacciona",
+	"se desmayo",
+	"desmayado",
+	"labios morados",
+	"esta morado",
+	"se puso morado",
+	"sangra mucho",
+	"mucha sangre",
+	"se golpeo la cabeza",
+	"se trago",
+	"intoxicacion",
+	"envenenamiento",
+	"emergencia",
+}
+
+// accentReplacer removes the accents used in Spanish
+var accentReplacer = strings.NewReplacer(
+	"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n",
+)
+
+// emergencyDetector finds emergency keywords in patient messages
+type emergencyDetector struct {
+	keywords []string // Normalized, padded with spaces to match whole words
+	original []string
+}
+
+func newEmergencyDetector(keywords []string) *emergencyDetector {
+	d := &emergencyDetector{}
+	for _, keyword := range keywords {
+		if normalized := normalizeForMatching(keyword); strings.TrimSpace(normalized) != "" {
+			d.keywords = append(d.keywords, normalized)
+			d.original = append(d.original, keyword)
+		}
+	}
+	return d
+}
+
+// Detect returns the first keyword contained in message
+func (d *emergencyDetector) Detect(message string) (string, bool) {
+	if d == nil || len(d.keywords) == 0 {
+		return "", false
+	}
+
+	text := normalizeForMatching(message)
+	for i, keyword := range d.keywords {
+		if strings.Contains(text, keyword) {
+			return d.original[i], true
+		}
+	}
+	return "", false
+}
+
+// normalizeForMatching lowercases text, removes accents and punctuation and
+// collapses whitespace, padding the result with spaces so words can be
+// matched by surrounding them with spaces
+func normalizeForMatching(text string) string {
+	text = accentReplacer.Replace(strings.ToLower(text))
+
+	var b strings.Builder
+	b.WriteByte(' ')
+	space := true
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			space = false
+		} else if !space {
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+	if !space {
+		b.WriteByte(' ')
+	}
+
+	return b.String()
+}
+
+// handleEmergency answers with the emergency instructions without leaving the
+// current state, flags the session and alerts staff
+func (s *chatbotService) handleEmergency(userState *models.ChatbotState, message, keyword string) (*models.WhatsAppResponse, error) {
+	now := time.Now()
+	userState.Emergency = true
+	userState.EmergencyAt = now
+
+	if s.notifier != nil {
+		s.notifier.NotifyEmergency(&models.EmergencyEvent{
+			UserID:     userState.UserID,
+			State:      userState.State,
+			Message:    message,
+			Keyword:    keyword,
+			DetectedAt: now,
+		})
+	}
+
+	body := s.systemMessage(emergencyState,
+		"🚨 Si tu hijo/a no respira, convulsiona o no reacciona, llamá YA al 107 (SAME) o acudí a la guardia más cercana. No esperes la respuesta por este medio.")
+	response := s.newTextResponse(userState, body)
+	annotateStates(response, userState.State, userState.State)
+
+	return response, s.touch(userState)
+}
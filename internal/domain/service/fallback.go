@@ -0,0 +1,39 @@
+package service
+
+import (
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+// invalidEscalatedState is the system flow whose message is sent when repeated
+// invalid answers hand the conversation to staff
+const invalidEscalatedState = "invalid_escalated"
+
+// rejectInput answers an input the current state does not understand with
+// body, or hands the conversation to staff once the patient has failed too
+// many times in a row
+func (s *chatbotService) rejectInput(userState *models.ChatbotState, flow *models.ChatbotFlow, message string, response *models.WhatsAppResponse) (*models.WhatsAppResponse, string, error) {
+	userState.InvalidAttempts++
+	if s.maxInvalidAttempts <= 0 || userState.InvalidAttempts < s.maxInvalidAttempts {
+		return response, flow.State, nil
+	}
+
+	attempts := userState.InvalidAttempts
+	userState.InvalidAttempts = 0
+	startHandoff(userState)
+
+	if s.notifier != nil {
+		s.notifier.NotifyEscalation(&models.EscalationEvent{
+			UserID:      userState.UserID,
+			State:       flow.State,
+			Attempts:    attempts,
+			LastMessage: message,
+			EscalatedAt: time.Now(),
+		})
+	}
+
+	body := s.systemMessage(invalidEscalatedState,
+		"🤝 Parece que no logramos entendernos. La Dra. continuará la conversación personalmente a la brevedad.")
+	return s.newTextResponse(userState, body), flow.State, nil
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+// Default messages shown when an answer does not match its field type
+var fieldErrorMessages = map[string]string{
+	models.FieldTypeName:  "⚠️ Por favor, ingresá un nombre válido (solo letras).",
+	models.FieldTypeAge:   "⚠️ Por favor, ingresá una edad válida, por ejemplo: 3 años u 8 meses.",
+	models.FieldTypeText:  "⚠️ Por favor, escribí tu respuesta.",
+	models.FieldTypeMedia: "⚠️ Por favor, enviá el archivo como foto o PDF.",
+	models.FieldTypeDate:  "⚠️ Por favor, ingresá una fecha válida con el formato DD/MM/AAAA.",
+}
+
+// dateLayouts are the date formats accepted for date fields
+var dateLayouts = []string{"02/01/2006", "2/1/2006", "02-01-2006", "2-1-2006", "2006-01-02", "02/01/06", "2/1/06"}
+
+// agePattern matches an amount followed by an optional unit, e.g. "3", "3 años", "8 meses"
+var agePattern = regexp.MustCompile(`^(\d{1,3})\s*(años|año|anos|ano|meses|mes|semanas|semana|días|dias|día|dia)?$`)
+
+// validateField checks a text answer and returns the value to store
+func validateField(field models.DataField, answer string) (string, bool) {
+	answer = strings.TrimSpace(answer)
+
+	switch field.Type {
+	case models.FieldTypeName:
+		return answer, isValidName(answer)
+	case models.FieldTypeAge:
+		return normalizeAge(answer)
+	case models.FieldTypeDate:
+		return normalizeDate(answer, time.Now())
+	case models.FieldTypeMedia:
+		// Media fields are answered with a file, not with text
+		return "", false
+	default:
+		return answer, utf8.RuneCountInString(answer) >= 2
+	}
+}
+
+// fieldErrorMessage returns the message shown when an answer is invalid
+func fieldErrorMessage(field models.DataField) string {
+	if field.ErrorMessage != "" {
+		return field.ErrorMessage
+	}
+	if message, exists := fieldErrorMessages[field.Type]; exists {
+		return message
+	}
+	return fieldErrorMessages[models.FieldTypeText]
+}
+
+func isValidName(name string) bool {
+	letters := 0
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r):
+			letters++
+		case r == ' ' || r == '\'' || r == '-' || r == '.':
+		default:
+			return false
+		}
+	}
+	return letters >= 2 && utf8.RuneCountInString(name) <= 100
+}
+
+func normalizeAge(answer string) (string, bool) {
+	match := agePattern.FindStringSubmatch(strings.ToLower(answer))
+	if match == nil {
+		return "", false
+	}
+
+	amount, _ := strconv.Atoi(match[1])
+	unit := match[2]
+	var singular, plural string
+	var limit int
+	switch {
+	case unit == "" || strings.HasPrefix(unit, "a"):
+		singular, plural, limit = "año", "años", 120
+	case strings.HasPrefix(unit, "m"):
+		singular, plural, limit = "mes", "meses", 36
+	case strings.HasPrefix(unit, "s"):
+		singular, plural, limit = "semana", "semanas", 52
+	default:
+		singular, plural, limit = "día", "días", 60
+	}
+
+	if amount > limit {
+		return "", false
+	}
+	if amount == 1 {
+		return "1 " + singular, true
+	}
+	return fmt.Sprintf("%d %s", amount, plural), true
+}
+
+func normalizeDate(answer string, now time.Time) (string, bool) {
+	for _, layout := range dateLayouts {
+		if date, err := time.ParseInLocation(layout, answer, now.Location()); err == nil {
+			return date.Format("02/01/2006"), true
+		}
+	}
+
+	// Day and month only, assume the current year
+	for _, layout := range []string{"02/01", "2/1"} {
+		if date, err := time.ParseInLocation(layout, answer, now.Location()); err == nil {
+			// Reject a day the current year does not have, e.g. 29/02 in a
+			// non-leap year, instead of rolling it over to the next month
+			dated := time.Date(now.Year(), date.Month(), date.Day(), 0, 0, 0, 0, now.Location())
+			if dated.Day() != date.Day() {
+				return "", false
+			}
+			return dated.Format("02/01/2006"), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+// WhatsApp limits for interactive messages
+const (
+	maxReplyButtons      = 3
+	maxButtonTitleLength = 20
+	maxListRows          = 10
+	maxRowTitleLength    = 24
+	maxRowDescLength     = 72
+	maxInteractiveBody   = 1024
+	listButtonLabel      = "Ver opciones"
+)
+
+// buildInteractiveMenu turns the options of a flow into reply buttons or a list.
+// It returns nil when the options do not fit WhatsApp's interactive limits.
+func buildInteractiveMenu(flow *models.ChatbotFlow, body string) *models.InteractiveContent {
+	if len(flow.Options) == 0 || len(flow.Options) > maxListRows || utf8.RuneCountInString(body) > maxInteractiveBody {
+		return nil
+	}
+
+	content := &models.InteractiveContent{
+		Body: models.TextContent{Body: body},
+	}
+
+	if len(flow.Options) <= maxReplyButtons && buttonTitlesFit(flow.Options) {
+		content.Type = "button"
+		for _, option := range flow.Options {
+			content.Action.Buttons = append(content.Action.Buttons, models.InteractiveButton{
+				Type:  "reply",
+				Reply: models.InteractiveReply{ID: option.ID, Title: optionTitle(option)},
+			})
+		}
+		return content
+	}
+
+	section := models.InteractiveSection{}
+	for _, option := range flow.Options {
+		row := models.InteractiveRow{
+			ID:    option.ID,
+			Title: truncate(optionTitle(option), maxRowTitleLength),
+		}
+		if option.Description != "" && option.Description != row.Title {
+			row.Description = truncate(option.Description, maxRowDescLength)
+		}
+		section.Rows = append(section.Rows, row)
+	}
+
+	content.Type = "list"
+	content.Action.Button = listButtonLabel
+	content.Action.Sections = []models.InteractiveSection{section}
+	return content
+}
+
+// optionTitle returns the short title shown for an option
+func optionTitle(option models.ChatbotOption) string {
+	for _, title := range []string{option.Title, option.Description, option.Label, option.ID} {
+		if title = strings.TrimSpace(title); title != "" {
+			return title
+		}
+	}
+	return option.ID
+}
+
+func buttonTitlesFit(options []models.ChatbotOption) bool {
+	for _, option := range options {
+		if utf8.RuneCountInString(optionTitle(option)) > maxButtonTitleLength {
+			return false
+		}
+	}
+	return true
+}
+
+// truncate shortens s to at most max characters, marking the cut with an ellipsis
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
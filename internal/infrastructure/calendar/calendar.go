@@ -0,0 +1,153 @@
+package calendar
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/infrastructure/schedule"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults applied when the definition leaves them out
+const (
+	defaultSlotMinutes = 20
+	defaultDaysAhead   = 14
+)
+
+// Definition represents an appointment calendar file
+type Definition struct {
+	Timezone       string               `yaml:"timezone"`
+	SlotMinutes    int                  `yaml:"slot_minutes"`     // Length of each appointment
+	DaysAhead      int                  `yaml:"days_ahead"`       // How far ahead patients can book
+	MinNoticeHours int                  `yaml:"min_notice_hours"` // Slots starting sooner are not offered
+	Holidays       []string             `yaml:"holidays"`         // Dates formatted as YYYY-MM-DD
+	Locations      []LocationDefinition `yaml:"locations"`
+}
+
+// LocationDefinition holds the consulting hours of a location
+type LocationDefinition struct {
+	ID          string `yaml:"id"`
+	Name        string `yaml:"name"`
+	Hours       string `yaml:"hours"`        // Weekly schedule, e.g. "tue,thu 16:00-19:00"
+	SlotMinutes int    `yaml:"slot_minutes"` // Overrides the calendar's slot length
+}
+
+// location is a location with its parsed consulting hours
+type location struct {
+	models.Location
+	hours      *schedule.WeeklySchedule
+	slotLength time.Duration
+}
+
+// Calendar offers the appointment slots of each location
+type Calendar struct {
+	locations []*location
+	timezone  *time.Location
+	daysAhead int
+	minNotice time.Duration
+}
+
+// Load reads and validates the calendar definition at path
+func Load(path string) (*Calendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calendar definition: %v", err)
+	}
+	return Parse(data)
+}
+
+// Parse builds a calendar from a YAML definition
+func Parse(data []byte) (*Calendar, error) {
+	var definition Definition
+	if err := yaml.Unmarshal(data, &definition); err != nil {
+		return nil, fmt.Errorf("failed to parse calendar definition: %v", err)
+	}
+
+	if definition.SlotMinutes == 0 {
+		definition.SlotMinutes = defaultSlotMinutes
+	}
+	if definition.DaysAhead == 0 {
+		definition.DaysAhead = defaultDaysAhead
+	}
+	if definition.SlotMinutes < 0 || definition.DaysAhead < 0 || definition.MinNoticeHours < 0 {
+		return nil, fmt.Errorf("slot_minutes, days_ahead and min_notice_hours must not be negative")
+	}
+	if len(definition.Locations) == 0 {
+		return nil, fmt.Errorf("calendar has no locations")
+	}
+
+	c := &Calendar{
+		daysAhead: definition.DaysAhead,
+		minNotice: time.Duration(definition.MinNoticeHours) * time.Hour,
+	}
+	seen := make(map[string]bool, len(definition.Locations))
+	for _, def := range definition.Locations {
+		id := strings.TrimSpace(def.ID)
+		if id == "" || def.Name == "" {
+			return nil, fmt.Errorf("every location needs an id and a name")
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("location %q is defined more than once", id)
+		}
+		seen[id] = true
+
+		hours, err := schedule.New(def.Hours, definition.Holidays, definition.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("location %q: %v", id, err)
+		}
+		c.timezone = hours.Location()
+
+		slotMinutes := def.SlotMinutes
+		if slotMinutes <= 0 {
+			slotMinutes = definition.SlotMinutes
+		}
+
+		c.locations = append(c.locations, &location{
+			Location:   models.Location{ID: id, Name: def.Name},
+			hours:      hours,
+			slotLength: time.Duration(slotMinutes) * time.Minute,
+		})
+	}
+
+	return c, nil
+}
+
+// Locations returns the locations in the order they are defined
+func (c *Calendar) Locations() []models.Location {
+	locations := make([]models.Location, len(c.locations))
+	for i, l := range c.locations {
+		locations[i] = l.Location
+	}
+	return locations
+}
+
+// Timezone returns the timezone the consulting hours are given in
+func (c *Calendar) Timezone() *time.Location {
+	return c.timezone
+}
+
+// Slots returns every slot of a location patients can book at from, earliest
+// first. Whether a slot is already booked is up to the caller.
+func (c *Calendar) Slots(locationID string, from time.Time) []models.Slot {
+	for _, l := range c.locations {
+		if l.ID != locationID {
+			continue
+		}
+
+		var slots []models.Slot
+		for _, start := range l.hours.Slots(from.Add(c.minNotice), from.AddDate(0, 0, c.daysAhead), l.slotLength) {
+			slots = append(slots, models.Slot{
+				LocationID: l.ID,
+				Start:      start,
+				End:        start.Add(l.slotLength),
+			})
+		}
+		return slots
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoad_Example(t *testing.T) {
+	c, err := Load("../../../appointments.example.yaml")
+	if err != nil {
+		t.Fatalf("Example calendar is invalid: %v", err)
+	}
+
+	locations := c.Locations()
+	if len(locations) != 2 || locations[0].Name != "Centro Médico Cervantes" || locations[1].Name != "Consultorios OSPEP" {
+		t.Errorf("Expected both practices, got %+v", locations)
+	}
+}
+
+func TestCalendar_Slots(t *testing.T) {
+	c, err := Parse([]byte(`
+timezone: America/Argentina/Buenos_Aires
+slot_minutes: 30
+days_ahead: 8
+min_notice_hours: 2
+locations:
+  - id: cervantes
+    name: Centro Médico Cervantes
+    hours: "tue 16:00-18:00"
+  - id: ospep
+    name: Consultorios OSPEP
+    hours: "mon 09:00-10:00"
+    slot_minutes: 60
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	location, _ := time.LoadLocation("America/Argentina/Buenos_Aires")
+	// Tuesday 15:00, the 16:00 and 16:30 slots are too soon
+	from := time.Date(2026, 10, 13, 15, 0, 0, 0, location)
+
+	slots := c.Slots("cervantes", from)
+	if len(slots) != 6 {
+		t.Fatalf("Expected 2 slots today and 4 next tuesday, got %d: %+v", len(slots), slots)
+	}
+	if !slots[0].Start.Equal(time.Date(2026, 10, 13, 17, 0, 0, 0, location)) || slots[0].End.Sub(slots[0].Start) != 30*time.Minute {
+		t.Errorf("Expected the first slot at 17:00 lasting 30 minutes, got %+v", slots[0])
+	}
+	if slots[0].LocationID != "cervantes" {
+		t.Errorf("Expected slots of cervantes, got %s", slots[0].LocationID)
+	}
+
+	if slots := c.Slots("ospep", from); len(slots) != 1 || slots[0].End.Sub(slots[0].Start) != time.Hour {
+		t.Errorf("Expected a single one hour slot on monday, got %+v", slots)
+	}
+	if slots := c.Slots("unknown", from); slots != nil {
+		t.Errorf("Expected no slots for an unknown location, got %+v", slots)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition string
+	}{
+		{"No locations", `slot_minutes: 20`},
+		{"Missing name", `locations: [{id: a, hours: "mon 09:00-10:00"}]`},
+		{"Duplicate location", `locations: [{id: a, name: A, hours: "mon 09:00-10:00"}, {id: a, name: B, hours: "tue 09:00-10:00"}]`},
+		{"Invalid hours", `locations: [{id: a, name: A, hours: "someday 09:00"}]`},
+		{"Negative slot length", `{slot_minutes: -5, locations: [{id: a, name: A, hours: "mon 09:00-10:00"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.definition)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -1,19 +1,32 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	WhatsApp WhatsAppConfig
-	AWS      AWSConfig
-	Logging  LoggingConfig
-	Session  SessionConfig
+	Server        ServerConfig
+	WhatsApp      WhatsAppConfig
+	AWS           AWSConfig
+	Logging       LoggingConfig
+	Session       SessionConfig
+	Flows         FlowsConfig
+	Outbound      OutboundConfig
+	Storage       StorageConfig
+	Media         MediaConfig
+	Admin         AdminConfig
+	Notification  NotificationConfig
+	BusinessHours BusinessHoursConfig
+	Emergency     EmergencyConfig
+	Commands      CommandsConfig
+	Appointments  AppointmentsConfig
+	Reminders     RemindersConfig
 }
 
 // ServerConfig holds server configuration
@@ -24,11 +37,22 @@ type ServerConfig struct {
 
 // WhatsAppConfig holds WhatsApp Business API configuration
 type WhatsAppConfig struct {
-	VerifyToken   string
-	AccessToken   string
-	WebhookURL    string
-	PhoneNumberID string
-	MyPhoneNumber string
+	VerifyToken    string
+	AccessToken    string
+	AppSecret      string // Used to verify the X-Hub-Signature-256 header of webhooks
+	SkipSignature  bool   // Disables webhook signature verification, for local development only
+	WebhookURL     string
+	PhoneNumberID  string
+	MyPhoneNumber  string
+	DeliveryMode   string   // "live" replies to each sender, "sandbox" only to allowed numbers
+	SandboxNumbers []string // Numbers allowed to receive replies in sandbox mode besides MyPhoneNumber
+	APIBaseURL     string   // Graph API host, overridden to point at a fake server in tests
+	APIVersion     string
+
+	// Approved template sent instead of messages to users who haven't written in 24 hours
+	WindowTemplate         string // Template name, empty sends the messages as they are
+	WindowTemplateLanguage string
+	WindowTemplateWithText bool // Pass the replaced message as the template's body variable
 }
 
 // AWSConfig holds AWS configuration
@@ -45,10 +69,107 @@ type LoggingConfig struct {
 
 // SessionConfig holds session management configuration
 type SessionConfig struct {
-	ExpirationHours    int // Hours after which a session expires
-	CleanupIntervalMin int // Minutes between cleanup runs
+	ExpirationHours       int // Hours after which a session expires
+	CleanupIntervalMin    int // Minutes between cleanup runs
+	DedupTTLHours         int // Hours a processed message ID is remembered to ignore redeliveries
+	HandoffTimeoutMinutes int // Minutes after which a conversation handed to staff returns to the bot
+	MaxInvalidAttempts    int // Consecutive invalid answers before the conversation is handed to staff; 0 disables it
+}
+
+// FlowsConfig holds conversation flow configuration
+type FlowsConfig struct {
+	File             string // Path to a YAML or JSON flow definition; empty uses the bundled defaults
+	InteractiveMenus bool   // Send menus as reply buttons or list messages instead of plain text
+}
+
+// OutboundConfig holds configuration for the asynchronous outbound message queue
+type OutboundConfig struct {
+	Workers          int // Number of concurrent senders
+	QueueSize        int // Maximum number of messages waiting to be sent
+	MaxAttempts      int // Attempts before a message is dead-lettered
+	InitialBackoffMs int // Delay before the first retry, doubled on each attempt
+	MaxBackoffMs     int // Upper bound for the retry delay
+}
+
+// StorageConfig holds configuration for the session storage backend
+type StorageConfig struct {
+	Backend        string // "memory", "sqlite" or "redis"
+	SQLitePath     string // Database file used by the sqlite backend
+	RedisAddr      string // host:port of the Redis protocol server used by the redis backend
+	RedisPassword  string
+	RedisDB        int
+	RedisKeyPrefix string // Prepended to every key so several apps can share a server
+}
+
+// MediaConfig holds configuration for media received from patients
+type MediaConfig struct {
+	StorageDir string // Directory where images, documents, audio and video are stored
+}
+
+// AdminConfig holds configuration for the admin API
+type AdminConfig struct {
+	APIToken string // Bearer token required by admin endpoints; empty disables them
+}
+
+// NotificationConfig holds configuration for staff notifications
+type NotificationConfig struct {
+	StaffNumber string // WhatsApp number that receives a summary of each completed request
+	WebhookURL  string // URL that receives completed requests as JSON
+}
+
+// BusinessHoursConfig holds the opening hours of the practice
+type BusinessHoursConfig struct {
+	Schedule       string   // Weekly schedule, e.g. "mon-fri 09:00-18:00; sat 09:00-12:00"; empty disables business hours
+	Holidays       []string // Dates the practice is closed, formatted as YYYY-MM-DD
+	Timezone       string   // IANA timezone the schedule is expressed in
+	AfterHoursMode string   // "append" adds the after-hours message to replies, "replace" sends only that message
 }
 
+// EmergencyConfig holds configuration for the emergency keyword detector
+type EmergencyConfig struct {
+	Keywords []string // Phrases that trigger the emergency reply; empty uses the built-in Spanish list
+	Disabled bool     // Turns emergency detection off
+}
+
+// CommandsConfig holds the synonyms of the global commands; an empty list keeps the built-in synonyms
+type CommandsConfig struct {
+	Menu    []string // Show the main menu
+	Back    []string // Return to the previous step
+	Restart []string // Discard the collected data and start over
+	Help    []string // Explain the commands
+}
+
+// AppointmentsConfig holds configuration for booking appointments in the chat
+type AppointmentsConfig struct {
+	File string // Path to the YAML calendar of locations and consulting hours; empty disables booking
+}
+
+// RemindersConfig holds configuration for the reminders sent to patients
+type RemindersConfig struct {
+	AppointmentHours int  // Hours before an appointment its reminder is sent; 0 disables appointment reminders
+	Callbacks        bool // Remind patients who wrote after hours when staff starts answering
+	IntervalSeconds  int  // How often due reminders are looked for
+}
+
+// Storage backends
+const (
+	StorageBackendMemory = "memory"
+	StorageBackendSQLite = "sqlite"
+	StorageBackendRedis  = "redis"
+)
+
+// After-hours modes
+const (
+	AfterHoursModeAppend  = "append"
+	AfterHoursModeReplace = "replace"
+)
+
+// Delivery modes for outbound WhatsApp messages
+const (
+	DeliveryModeLive    = "live"
+	DeliveryModeSandbox = "sandbox"
+)
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	// Load .env file if it exists
@@ -62,11 +183,21 @@ func Load() (*Config, error) {
 			Host: getEnv("HOST", "0.0.0.0"),
 		},
 		WhatsApp: WhatsAppConfig{
-			VerifyToken:   getEnv("WHATSAPP_VERIFY_TOKEN", ""),
-			AccessToken:   getEnv("WHATSAPP_ACCESS_TOKEN", ""),
-			WebhookURL:    getEnv("WHATSAPP_WEBHOOK_URL", ""),
-			PhoneNumberID: getEnv("WHATSAPP_PHONE_NUMBER_ID", ""),
-			MyPhoneNumber: getEnv("MY_PHONE_NUMBER", ""),
+			VerifyToken:    getEnv("WHATSAPP_VERIFY_TOKEN", ""),
+			AccessToken:    getEnv("WHATSAPP_ACCESS_TOKEN", ""),
+			AppSecret:      getEnv("WHATSAPP_APP_SECRET", ""),
+			SkipSignature:  getEnvAsBool("WHATSAPP_SKIP_SIGNATURE_VERIFICATION", false),
+			WebhookURL:     getEnv("WHATSAPP_WEBHOOK_URL", ""),
+			PhoneNumberID:  getEnv("WHATSAPP_PHONE_NUMBER_ID", ""),
+			MyPhoneNumber:  getEnv("MY_PHONE_NUMBER", ""),
+			DeliveryMode:   strings.ToLower(getEnv("WHATSAPP_DELIVERY_MODE", DeliveryModeLive)),
+			SandboxNumbers: getEnvAsList("WHATSAPP_SANDBOX_NUMBERS"),
+			APIBaseURL:     getEnv("WHATSAPP_API_BASE_URL", "https://graph.facebook.com"),
+			APIVersion:     getEnv("WHATSAPP_API_VERSION", "v17.0"),
+
+			WindowTemplate:         getEnv("WHATSAPP_WINDOW_TEMPLATE", ""),
+			WindowTemplateLanguage: getEnv("WHATSAPP_WINDOW_TEMPLATE_LANGUAGE", "es_AR"),
+			WindowTemplateWithText: getEnvAsBool("WHATSAPP_WINDOW_TEMPLATE_WITH_TEXT", true),
 		},
 		AWS: AWSConfig{
 			Region:          getEnv("AWS_REGION", "us-east-1"),
@@ -77,9 +208,111 @@ func Load() (*Config, error) {
 			Level: getEnv("LOG_LEVEL", "info"),
 		},
 		Session: SessionConfig{
-			ExpirationHours:    getEnvAsInt("SESSION_EXPIRATION_HOURS", 24),     // 24 hours default
-			CleanupIntervalMin: getEnvAsInt("SESSION_CLEANUP_INTERVAL_MIN", 30), // 30 minutes default
+			ExpirationHours:       getEnvAsInt("SESSION_EXPIRATION_HOURS", 24),     // 24 hours default
+			CleanupIntervalMin:    getEnvAsInt("SESSION_CLEANUP_INTERVAL_MIN", 30), // 30 minutes default
+			DedupTTLHours:         getEnvAsInt("MESSAGE_DEDUP_TTL_HOURS", 24),      // 24 hours default
+			HandoffTimeoutMinutes: getEnvAsInt("HANDOFF_TIMEOUT_MINUTES", 720),     // 12 hours default
+			MaxInvalidAttempts:    getEnvAsInt("MAX_INVALID_ATTEMPTS", 3),
+		},
+		Flows: FlowsConfig{
+			File:             getEnv("FLOWS_FILE", ""),
+			InteractiveMenus: getEnvAsBool("INTERACTIVE_MENUS", true),
 		},
+		Outbound: OutboundConfig{
+			Workers:          getEnvAsInt("OUTBOUND_WORKERS", 4),
+			QueueSize:        getEnvAsInt("OUTBOUND_QUEUE_SIZE", 500),
+			MaxAttempts:      getEnvAsInt("OUTBOUND_MAX_ATTEMPTS", 5),
+			InitialBackoffMs: getEnvAsInt("OUTBOUND_INITIAL_BACKOFF_MS", 500),
+			MaxBackoffMs:     getEnvAsInt("OUTBOUND_MAX_BACKOFF_MS", 30000),
+		},
+		Storage: StorageConfig{
+			Backend:        strings.ToLower(getEnv("STORAGE_BACKEND", StorageBackendMemory)),
+			SQLitePath:     getEnv("SQLITE_PATH", "data/chatbot.db"),
+			RedisAddr:      getEnv("REDIS_ADDR", "localhost:6379"),
+			RedisPassword:  getEnv("REDIS_PASSWORD", ""),
+			RedisDB:        getEnvAsInt("REDIS_DB", 0),
+			RedisKeyPrefix: getEnv("REDIS_KEY_PREFIX", "chatbot-wsp:"),
+		},
+		Media: MediaConfig{
+			StorageDir: getEnv("MEDIA_STORAGE_DIR", "data/media"),
+		},
+		Admin: AdminConfig{
+			APIToken: getEnv("ADMIN_API_TOKEN", ""),
+		},
+		Notification: NotificationConfig{
+			StaffNumber: getEnv("STAFF_WHATSAPP_NUMBER", ""),
+			WebhookURL:  getEnv("NOTIFICATION_WEBHOOK_URL", ""),
+		},
+		BusinessHours: BusinessHoursConfig{
+			Schedule:       getEnv("BUSINESS_HOURS", ""),
+			Holidays:       getEnvAsList("BUSINESS_HOLIDAYS"),
+			Timezone:       getEnv("BUSINESS_TIMEZONE", "America/Argentina/Buenos_Aires"),
+			AfterHoursMode: strings.ToLower(getEnv("AFTER_HOURS_MODE", AfterHoursModeAppend)),
+		},
+		Emergency: EmergencyConfig{
+			Keywords: getEnvAsList("EMERGENCY_KEYWORDS"),
+			Disabled: getEnvAsBool("EMERGENCY_DETECTION_DISABLED", false),
+		},
+		Commands: CommandsConfig{
+			Menu:    getEnvAsList("COMMANDS_MENU"),
+			Back:    getEnvAsList("COMMANDS_BACK"),
+			Restart: getEnvAsList("COMMANDS_RESTART"),
+			Help:    getEnvAsList("COMMANDS_HELP"),
+		},
+		Appointments: AppointmentsConfig{
+			File: getEnv("APPOINTMENTS_FILE", ""),
+		},
+		Reminders: RemindersConfig{
+			AppointmentHours: getEnvAsInt("APPOINTMENT_REMINDER_HOURS", 24),
+			Callbacks:        getEnvAsBool("CALLBACK_REMINDERS", true),
+			IntervalSeconds:  getEnvAsInt("REMINDER_INTERVAL_SECONDS", 60),
+		},
+	}
+
+	if config.WhatsApp.AppSecret == "" && !config.WhatsApp.SkipSignature {
+		return nil, fmt.Errorf("WHATSAPP_APP_SECRET is required to verify webhook signatures")
+	}
+
+	switch config.WhatsApp.DeliveryMode {
+	case DeliveryModeLive:
+	case DeliveryModeSandbox:
+		if config.WhatsApp.MyPhoneNumber == "" {
+			return nil, fmt.Errorf("MY_PHONE_NUMBER is required when WHATSAPP_DELIVERY_MODE is %q", DeliveryModeSandbox)
+		}
+	default:
+		return nil, fmt.Errorf("invalid WHATSAPP_DELIVERY_MODE %q (expected %q or %q)", config.WhatsApp.DeliveryMode, DeliveryModeLive, DeliveryModeSandbox)
+	}
+
+	switch config.Storage.Backend {
+	case StorageBackendMemory, StorageBackendSQLite, StorageBackendRedis:
+	default:
+		return nil, fmt.Errorf("invalid STORAGE_BACKEND %q (expected %q, %q or %q)", config.Storage.Backend, StorageBackendMemory, StorageBackendSQLite, StorageBackendRedis)
+	}
+
+	if config.Outbound.Workers < 1 {
+		return nil, fmt.Errorf("OUTBOUND_WORKERS must be at least 1, got %d", config.Outbound.Workers)
+	}
+	if config.Outbound.MaxAttempts < 1 {
+		return nil, fmt.Errorf("OUTBOUND_MAX_ATTEMPTS must be at least 1, got %d", config.Outbound.MaxAttempts)
+	}
+	if config.Outbound.QueueSize < 1 {
+		return nil, fmt.Errorf("OUTBOUND_QUEUE_SIZE must be at least 1, got %d", config.Outbound.QueueSize)
+	}
+	if config.Outbound.InitialBackoffMs < 1 {
+		return nil, fmt.Errorf("OUTBOUND_INITIAL_BACKOFF_MS must be at least 1, got %d", config.Outbound.InitialBackoffMs)
+	}
+	if config.Outbound.MaxBackoffMs < config.Outbound.InitialBackoffMs {
+		return nil, fmt.Errorf("OUTBOUND_MAX_BACKOFF_MS must be at least OUTBOUND_INITIAL_BACKOFF_MS (%d), got %d", config.Outbound.InitialBackoffMs, config.Outbound.MaxBackoffMs)
+	}
+
+	switch config.BusinessHours.AfterHoursMode {
+	case AfterHoursModeAppend, AfterHoursModeReplace:
+	default:
+		return nil, fmt.Errorf("invalid AFTER_HOURS_MODE %q (expected %q or %q)", config.BusinessHours.AfterHoursMode, AfterHoursModeAppend, AfterHoursModeReplace)
+	}
+
+	if config.Reminders.IntervalSeconds < 1 {
+		return nil, fmt.Errorf("REMINDER_INTERVAL_SECONDS must be at least 1, got %d", config.Reminders.IntervalSeconds)
 	}
 
 	return config, nil
@@ -112,3 +345,14 @@ func getEnvAsBool(key string, fallback bool) bool {
 	}
 	return fallback
 }
+
+// getEnvAsList gets a comma separated environment variable as a list of trimmed values
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
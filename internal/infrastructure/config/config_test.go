@@ -0,0 +1,114 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// setRequiredEnv sets the settings Load cannot start without
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("WHATSAPP_APP_SECRET", "secret")
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.WhatsApp.DeliveryMode != DeliveryModeLive {
+		t.Errorf("Expected delivery mode %q, got %q", DeliveryModeLive, cfg.WhatsApp.DeliveryMode)
+	}
+	if cfg.WhatsApp.AppSecret != "secret" {
+		t.Errorf("Expected the app secret to be read, got %q", cfg.WhatsApp.AppSecret)
+	}
+}
+
+func TestLoad_DeliveryMode(t *testing.T) {
+	tests := []struct {
+		name          string
+		env           map[string]string
+		expectedError string
+	}{
+		{
+			name: "sandbox with my number",
+			env:  map[string]string{"WHATSAPP_DELIVERY_MODE": "Sandbox", "MY_PHONE_NUMBER": "5490000000000", "WHATSAPP_SANDBOX_NUMBERS": "5491111111111, 5492222222222"},
+		},
+		{
+			name:          "sandbox without my number",
+			env:           map[string]string{"WHATSAPP_DELIVERY_MODE": "sandbox"},
+			expectedError: "MY_PHONE_NUMBER is required",
+		},
+		{
+			name:          "unknown mode",
+			env:           map[string]string{"WHATSAPP_DELIVERY_MODE": "staging"},
+			expectedError: "invalid WHATSAPP_DELIVERY_MODE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, err := Load()
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("Expected error containing '%s', got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if cfg.WhatsApp.DeliveryMode != DeliveryModeSandbox {
+				t.Errorf("Expected delivery mode %q, got %q", DeliveryModeSandbox, cfg.WhatsApp.DeliveryMode)
+			}
+			if len(cfg.WhatsApp.SandboxNumbers) != 2 || cfg.WhatsApp.SandboxNumbers[1] != "5492222222222" {
+				t.Errorf("Expected the trimmed sandbox numbers, got %v", cfg.WhatsApp.SandboxNumbers)
+			}
+		})
+	}
+}
+
+func TestLoad_RejectsInvalidOutboundQueue(t *testing.T) {
+	tests := []struct {
+		key           string
+		value         string
+		expectedError string
+	}{
+		{"OUTBOUND_WORKERS", "0", "OUTBOUND_WORKERS must be at least 1"},
+		{"OUTBOUND_WORKERS", "-2", "OUTBOUND_WORKERS must be at least 1"},
+		{"OUTBOUND_MAX_ATTEMPTS", "0", "OUTBOUND_MAX_ATTEMPTS must be at least 1"},
+		{"OUTBOUND_QUEUE_SIZE", "0", "OUTBOUND_QUEUE_SIZE must be at least 1"},
+		{"OUTBOUND_INITIAL_BACKOFF_MS", "0", "OUTBOUND_INITIAL_BACKOFF_MS must be at least 1"},
+		{"OUTBOUND_MAX_BACKOFF_MS", "100", "OUTBOUND_MAX_BACKOFF_MS must be at least OUTBOUND_INITIAL_BACKOFF_MS (500)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv(tt.key, tt.value)
+
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("Expected error containing '%s', got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestLoad_RejectsInvalidReminderInterval(t *testing.T) {
+	for _, value := range []string{"0", "-30"} {
+		t.Run(value, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv("REMINDER_INTERVAL_SECONDS", value)
+
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), "REMINDER_INTERVAL_SECONDS must be at least 1") {
+				t.Errorf("Expected error about REMINDER_INTERVAL_SECONDS, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package delivery
+
+import (
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
+	"chatbot-wsp/internal/infrastructure/logger"
+	"chatbot-wsp/internal/infrastructure/metrics"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Tracker follows outbound messages from the moment they are queued, records
+// them in the recipient's transcript, and tracks their status once the
+// WhatsApp API accepts them until their status callbacks report them
+// delivered, read or failed
+type Tracker struct {
+	statuses    repository.MessageStatusRepository
+	transcripts repository.TranscriptRepository
+	metrics     *metrics.Metrics
+}
+
+// NewTracker creates a new delivery tracker
+func NewTracker(statuses repository.MessageStatusRepository, transcripts repository.TranscriptRepository, metrics *metrics.Metrics) *Tracker {
+	return &Tracker{
+		statuses:    statuses,
+		transcripts: transcripts,
+		metrics:     metrics,
+	}
+}
+
+// MessageQueued records a message in the recipient's transcript, so it is kept
+// even if it is never delivered
+func (t *Tracker) MessageQueued(response *models.WhatsAppResponse) {
+	entry := &models.TranscriptEntry{
+		ID:          repository.NewTranscriptEntryID(),
+		UserID:      response.To,
+		Direction:   models.DirectionOutbound,
+		Type:        response.Type,
+		Text:        response.Text.Body,
+		StateBefore: response.StateBefore,
+		StateAfter:  response.StateAfter,
+		Status:      models.DeliveryQueued,
+		Timestamp:   time.Now(),
+	}
+	if err := t.transcripts.Append(entry); err != nil {
+		logger.GetLogger().WithFields(logrus.Fields{
+			"user_id": response.To,
+			"error":   err.Error(),
+		}).Error("Failed to record transcript entry")
+		return
+	}
+
+	response.TranscriptID = entry.ID
+}
+
+// MessageAccepted records the ID the WhatsApp API assigned to a message in the
+// recipient's transcript, and starts tracking its status
+func (t *Tracker) MessageAccepted(response *models.WhatsAppResponse, messageID string) {
+	now := time.Now()
+	t.updateTranscript(response, messageID, models.DeliveryAccepted)
+
+	if messageID == "" {
+		return
+	}
+	err := t.statuses.Update(&models.MessageStatus{
+		MessageID:  messageID,
+		UserID:     response.To,
+		Status:     models.DeliveryAccepted,
+		AcceptedAt: now,
+		UpdatedAt:  now,
+	})
+	if err != nil {
+		logger.GetLogger().WithFields(logrus.Fields{
+			"message_id": messageID,
+			"error":      err.Error(),
+		}).Error("Failed to track message status")
+	}
+}
+
+// MessageFailed records in the recipient's transcript that a message was never delivered
+func (t *Tracker) MessageFailed(response *models.WhatsAppResponse, err error) {
+	t.updateTranscript(response, "", models.DeliveryFailed)
+}
+
+func (t *Tracker) updateTranscript(response *models.WhatsAppResponse, messageID, status string) {
+	if response.TranscriptID == "" {
+		return
+	}
+
+	if err := t.transcripts.UpdateDelivery(response.To, response.TranscriptID, messageID, status); err != nil {
+		logger.GetLogger().WithFields(logrus.Fields{
+			"user_id":    response.To,
+			"message_id": messageID,
+			"error":      err.Error(),
+		}).Error("Failed to record transcript delivery")
+	}
+}
+
+// UpdateStatus applies a status callback received from WhatsApp
+func (t *Tracker) UpdateStatus(status *models.MessageStatus) error {
+	t.metrics.MessageStatus(status.Status)
+
+	if status.Status == models.DeliveryFailed {
+		logger.GetLogger().WithFields(logrus.Fields{
+			"message_id":  status.MessageID,
+			"user_id":     status.UserID,
+			"error_code":  status.ErrorCode,
+			"error_title": status.ErrorTitle,
+		}).Error("WhatsApp could not deliver message")
+	}
+
+	return t.statuses.Update(status)
+}
@@ -0,0 +1,80 @@
+package delivery
+
+import (
+	"errors"
+	"testing"
+
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
+	"chatbot-wsp/internal/infrastructure/metrics"
+)
+
+func TestTracker_MessageAccepted(t *testing.T) {
+	statuses := repository.NewInMemoryMessageStatusRepository(10)
+	transcripts := repository.NewInMemoryTranscriptRepository(100)
+	tracker := NewTracker(statuses, transcripts, metrics.New())
+
+	response := &models.WhatsAppResponse{To: "user123", Type: "text", StateAfter: "welcome"}
+	response.Text.Body = "Bienvenido"
+	tracker.MessageQueued(response)
+
+	entries, _ := transcripts.ListByUser("user123")
+	if len(entries) != 1 || entries[0].Status != models.DeliveryQueued || entries[0].MessageID != "" || entries[0].ID != response.TranscriptID {
+		t.Fatalf("Expected the reply in the transcript as soon as it is queued, got %+v", entries)
+	}
+
+	tracker.MessageAccepted(response, "wamid.1")
+
+	status, err := statuses.Get("wamid.1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if status.Status != models.DeliveryAccepted || status.UserID != "user123" || status.AcceptedAt.IsZero() {
+		t.Errorf("Expected accepted message of user123, got %+v", status)
+	}
+
+	entries, _ = transcripts.ListByUser("user123")
+	if len(entries) != 1 || entries[0].MessageID != "wamid.1" || entries[0].Text != "Bienvenido" || entries[0].Status != models.DeliveryAccepted {
+		t.Errorf("Expected the accepted reply in the transcript with its message ID, got %+v", entries)
+	}
+}
+
+func TestTracker_MessageFailed(t *testing.T) {
+	statuses := repository.NewInMemoryMessageStatusRepository(10)
+	transcripts := repository.NewInMemoryTranscriptRepository(100)
+	tracker := NewTracker(statuses, transcripts, metrics.New())
+
+	response := &models.WhatsAppResponse{To: "user123", Type: "text"}
+	response.Text.Body = "Bienvenido"
+	tracker.MessageQueued(response)
+	tracker.MessageFailed(response, errors.New("rejected"))
+
+	entries, _ := transcripts.ListByUser("user123")
+	if len(entries) != 1 || entries[0].Status != models.DeliveryFailed || entries[0].Text != "Bienvenido" {
+		t.Errorf("Expected the failed reply kept in the transcript, got %+v", entries)
+	}
+	if failed, _ := statuses.ListFailed(); len(failed) != 0 {
+		t.Errorf("Expected no status tracked for a message WhatsApp never accepted, got %+v", failed)
+	}
+}
+
+func TestTracker_UpdateStatus(t *testing.T) {
+	statuses := repository.NewInMemoryMessageStatusRepository(10)
+	appMetrics := metrics.New()
+	tracker := NewTracker(statuses, repository.NewInMemoryTranscriptRepository(100), appMetrics)
+
+	response := &models.WhatsAppResponse{To: "user123", Type: "text"}
+	tracker.MessageAccepted(response, "wamid.1")
+
+	if err := tracker.UpdateStatus(&models.MessageStatus{MessageID: "wamid.1", Status: models.DeliveryFailed, ErrorCode: 131026}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	failed, _ := statuses.ListFailed()
+	if len(failed) != 1 || failed[0].UserID != "user123" {
+		t.Errorf("Expected the failed message of user123, got %+v", failed)
+	}
+	if stats := appMetrics.Stats(); stats.DeliveryFailures != 1 {
+		t.Errorf("Expected 1 delivery failure, got %v", stats.DeliveryFailures)
+	}
+}
@@ -0,0 +1,270 @@
+package flows
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
+
+	"gopkg.in/yaml.v3"
+)
+
+// InitialState is the state every new conversation starts in
+const InitialState = repository.InitialState
+
+// maxOptionTitleLength is the longest row title WhatsApp accepts in list messages
+const maxOptionTitleLength = 24
+
+// knownFieldTypes lists the field types the chatbot knows how to validate
+var knownFieldTypes = map[string]bool{
+	models.FieldTypeName:  true,
+	models.FieldTypeAge:   true,
+	models.FieldTypeText:  true,
+	models.FieldTypeMedia: true,
+	models.FieldTypeDate:  true,
+}
+
+//go:embed default.yaml
+var defaultDefinition []byte
+
+// Definition represents a flow definition file
+type Definition struct {
+	Flows []models.ChatbotFlow `json:"flows" yaml:"flows"`
+}
+
+// ValidationError reports every inconsistency found in a flow definition
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid flow definition (%d problems):\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// Load reads and validates the flow definition at path. An empty path loads
+// the default flows bundled with the binary.
+func Load(path string) (map[string]*models.ChatbotFlow, error) {
+	if path == "" {
+		return Parse(defaultDefinition, "yaml")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read flow definition: %v", err)
+	}
+
+	format := "yaml"
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		format = "json"
+	}
+
+	return Parse(data, format)
+}
+
+// Parse decodes a flow definition in the given format ("yaml" or "json")
+// and validates it
+func Parse(data []byte, format string) (map[string]*models.ChatbotFlow, error) {
+	var def Definition
+
+	switch format {
+	// Unknown fields are rejected, so a misspelled key is reported instead
+	// of silently dropping part of a flow
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&def); err != nil {
+			return nil, fmt.Errorf("failed to parse flow definition: %v", err)
+		}
+	case "yaml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&def); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to parse flow definition: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported flow definition format %q", format)
+	}
+
+	return Build(def.Flows)
+}
+
+// Build indexes flows by state after validating them
+func Build(list []models.ChatbotFlow) (map[string]*models.ChatbotFlow, error) {
+	var problems []string
+
+	flows := make(map[string]*models.ChatbotFlow, len(list))
+	for i := range list {
+		flow := list[i]
+		if flow.State == "" {
+			problems = append(problems, fmt.Sprintf("flow #%d has no state name", i+1))
+			continue
+		}
+		if _, exists := flows[flow.State]; exists {
+			problems = append(problems, fmt.Sprintf("state %q is defined more than once", flow.State))
+			continue
+		}
+		flows[flow.State] = &flow
+	}
+
+	problems = append(problems, Validate(flows)...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+
+	return flows, nil
+}
+
+// Validate checks that every transition points to an existing state and that
+// every non-system state is reachable from the initial state
+func Validate(flows map[string]*models.ChatbotFlow) []string {
+	var problems []string
+
+	if _, exists := flows[InitialState]; !exists {
+		problems = append(problems, fmt.Sprintf("initial state %q is not defined", InitialState))
+	}
+
+	for _, name := range sortedStates(flows) {
+		flow := flows[name]
+
+		if strings.TrimSpace(flow.Message) == "" {
+			problems = append(problems, fmt.Sprintf("state %q has an empty message", name))
+		}
+
+		seen := make(map[string]bool)
+		for _, option := range flow.Options {
+			if option.ID == "" {
+				problems = append(problems, fmt.Sprintf("state %q has an option without id", name))
+				continue
+			}
+			if seen[strings.ToUpper(option.ID)] {
+				problems = append(problems, fmt.Sprintf("state %q defines option %q more than once", name, option.ID))
+			}
+			seen[strings.ToUpper(option.ID)] = true
+
+			if utf8.RuneCountInString(option.Title) > maxOptionTitleLength {
+				problems = append(problems, fmt.Sprintf("option %q of state %q has a title longer than %d characters", option.ID, name, maxOptionTitleLength))
+			}
+
+			if _, exists := flows[option.NextState]; !exists {
+				problems = append(problems, fmt.Sprintf("option %q of state %q points to unknown state %q", option.ID, name, option.NextState))
+			}
+		}
+
+		if flow.NextState != "" {
+			if _, exists := flows[flow.NextState]; !exists {
+				problems = append(problems, fmt.Sprintf("state %q points to unknown next state %q", name, flow.NextState))
+			}
+		}
+
+		if flow.DataRequest != "" && flow.NextState == "" {
+			problems = append(problems, fmt.Sprintf("state %q requests data but has no next_state", name))
+		}
+		if flow.DataRequest != "" && len(flow.Options) > 0 {
+			problems = append(problems, fmt.Sprintf("state %q cannot have both options and a data_request", name))
+		}
+
+		if flow.Booking && flow.NextState == "" {
+			problems = append(problems, fmt.Sprintf("state %q books appointments but has no next_state", name))
+		}
+		if flow.Booking && (len(flow.Options) > 0 || len(flow.Fields) > 0) {
+			problems = append(problems, fmt.Sprintf("state %q cannot combine booking with options or fields", name))
+		}
+
+		if flow.Handoff && flow.DataRequest == "" && len(flow.Fields) == 0 {
+			problems = append(problems, fmt.Sprintf("state %q hands off to staff but collects no data", name))
+		}
+
+		problems = append(problems, validateFields(flow)...)
+	}
+
+	reachable := reachableStates(flows)
+	for _, name := range sortedStates(flows) {
+		if !reachable[name] && !flows[name].System {
+			problems = append(problems, fmt.Sprintf("state %q is unreachable from %q", name, InitialState))
+		}
+	}
+
+	return problems
+}
+
+// validateFields checks the structured data fields of a flow
+func validateFields(flow *models.ChatbotFlow) []string {
+	if len(flow.Fields) == 0 {
+		return nil
+	}
+
+	var problems []string
+	name := flow.State
+
+	if flow.NextState == "" {
+		problems = append(problems, fmt.Sprintf("state %q collects fields but has no next_state", name))
+	}
+	if len(flow.Options) > 0 || flow.DataRequest != "" {
+		problems = append(problems, fmt.Sprintf("state %q cannot combine fields with options or a data_request", name))
+	}
+
+	seen := make(map[string]bool)
+	for i, field := range flow.Fields {
+		if field.Key == "" {
+			problems = append(problems, fmt.Sprintf("field #%d of state %q has no key", i+1, name))
+			continue
+		}
+		if seen[field.Key] {
+			problems = append(problems, fmt.Sprintf("state %q defines field %q more than once", name, field.Key))
+		}
+		seen[field.Key] = true
+
+		if !knownFieldTypes[field.Type] {
+			problems = append(problems, fmt.Sprintf("field %q of state %q has unknown type %q", field.Key, name, field.Type))
+		}
+		if strings.TrimSpace(field.Prompt) == "" {
+			problems = append(problems, fmt.Sprintf("field %q of state %q has an empty prompt", field.Key, name))
+		}
+	}
+
+	return problems
+}
+
+// reachableStates walks every transition starting at the initial state
+func reachableStates(flows map[string]*models.ChatbotFlow) map[string]bool {
+	reachable := make(map[string]bool)
+	pending := []string{InitialState}
+
+	for len(pending) > 0 {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		flow, exists := flows[name]
+		if !exists || reachable[name] {
+			continue
+		}
+		reachable[name] = true
+
+		for _, option := range flow.Options {
+			pending = append(pending, option.NextState)
+		}
+		if flow.NextState != "" {
+			pending = append(pending, flow.NextState)
+		}
+	}
+
+	return reachable
+}
+
+func sortedStates(flows map[string]*models.ChatbotFlow) []string {
+	names := make([]string, 0, len(flows))
+	for name := range flows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,217 @@
+package flows
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad_DefaultFlows(t *testing.T) {
+	flows, err := Load("")
+	if err != nil {
+		t.Fatalf("Unexpected error loading default flows: %v", err)
+	}
+
+	for _, state := range []string{"welcome", "option_a", "option_b", "option_c", "option_d", "collecting_data", "invalid_option"} {
+		if _, exists := flows[state]; !exists {
+			t.Errorf("Expected default flows to define state %s", state)
+		}
+	}
+
+	if !strings.HasPrefix(flows["welcome"].Message, "🤖 Chatbot BabyHome") {
+		t.Errorf("Unexpected welcome message: %s", flows["welcome"].Message)
+	}
+
+	if len(flows["welcome"].Options) != 4 {
+		t.Errorf("Expected 4 welcome options, got %d", len(flows["welcome"].Options))
+	}
+}
+
+func TestLoad_JSONFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flows.json")
+	content := `{
+		"flows": [
+			{"state": "welcome", "message": "Hola", "options": [{"id": "A", "label": "A", "next_state": "ask"}]},
+			{"state": "ask", "message": "Contanos", "data_request": "datos", "next_state": "welcome"}
+		]
+	}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+
+	flows, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if flows["ask"].DataRequest != "datos" {
+		t.Errorf("Expected data request 'datos', got '%s'", flows["ask"].DataRequest)
+	}
+}
+
+func TestParse_ReportsInconsistencies(t *testing.T) {
+	tests := []struct {
+		name            string
+		definition      string
+		expectedProblem string
+	}{
+		{
+			name: "Missing initial state",
+			definition: `
+flows:
+  - state: menu
+    message: Hola`,
+			expectedProblem: `initial state "welcome" is not defined`,
+		},
+		{
+			name: "Option points to unknown state",
+			definition: `
+flows:
+  - state: welcome
+    message: Hola
+    options:
+      - id: A
+        next_state: option_z`,
+			expectedProblem: `option "A" of state "welcome" points to unknown state "option_z"`,
+		},
+		{
+			name: "Unreachable state",
+			definition: `
+flows:
+  - state: welcome
+    message: Hola
+  - state: orphan
+    message: Nadie llega acá`,
+			expectedProblem: `state "orphan" is unreachable from "welcome"`,
+		},
+		{
+			name: "Data request without next state",
+			definition: `
+flows:
+  - state: welcome
+    message: Hola
+    data_request: datos`,
+			expectedProblem: `state "welcome" requests data but has no next_state`,
+		},
+		{
+			name: "Duplicated state",
+			definition: `
+flows:
+  - state: welcome
+    message: Hola
+  - state: welcome
+    message: Chau`,
+			expectedProblem: `state "welcome" is defined more than once`,
+		},
+		{
+			name: "Field with unknown type",
+			definition: `
+flows:
+  - state: welcome
+    message: Hola
+    fields:
+      - key: telefono
+        type: phone
+        prompt: ¿Tu teléfono?
+    next_state: welcome`,
+			expectedProblem: `field "telefono" of state "welcome" has unknown type "phone"`,
+		},
+		{
+			name: "Duplicated field key",
+			definition: `
+flows:
+  - state: welcome
+    message: Hola
+    fields:
+      - key: nombre
+        type: name
+        prompt: ¿Tu nombre?
+      - key: nombre
+        type: text
+        prompt: ¿Tu apellido?
+    next_state: welcome`,
+			expectedProblem: `state "welcome" defines field "nombre" more than once`,
+		},
+		{
+			name: "Fields without next state",
+			definition: `
+flows:
+  - state: welcome
+    message: Hola
+    fields:
+      - key: nombre
+        type: name
+        prompt: ¿Tu nombre?`,
+			expectedProblem: `state "welcome" collects fields but has no next_state`,
+		},
+		{
+			name: "Booking without next state",
+			definition: `
+flows:
+  - state: welcome
+    message: Hola
+    booking: true`,
+			expectedProblem: `state "welcome" books appointments but has no next_state`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.definition), "yaml")
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected validation error, got %v", err)
+			}
+
+			if !strings.Contains(err.Error(), tt.expectedProblem) {
+				t.Errorf("Expected report to contain '%s', got: %s", tt.expectedProblem, err.Error())
+			}
+		})
+	}
+}
+
+func TestParse_SystemStatesAreExemptFromReachability(t *testing.T) {
+	definition := `
+flows:
+  - state: welcome
+    message: Hola
+  - state: invalid_option
+    message: Opción inválida
+    system: true`
+
+	if _, err := Parse([]byte(definition), "yaml"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestParse_RejectsUnknownFields(t *testing.T) {
+	tests := []struct {
+		format     string
+		definition string
+	}{
+		{
+			format: "yaml",
+			definition: `
+flows:
+  - state: welcome
+    message: Hola
+    next_stat: welcome`,
+		},
+		{
+			format:     "json",
+			definition: `{"flows": [{"state": "welcome", "message": "Hola", "next_stat": "welcome"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			_, err := Parse([]byte(tt.definition), tt.format)
+			if err == nil || !strings.Contains(err.Error(), "next_stat") {
+				t.Errorf("Expected the misspelled field to be reported, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	domainerrors "chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
+	"chatbot-wsp/internal/domain/service"
+	"chatbot-wsp/internal/infrastructure/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AdminHandler handles requests made by staff to manage conversations
+type AdminHandler struct {
+	chatbotService service.ChatbotService
+	transcripts    repository.TranscriptRepository
+	statuses       repository.MessageStatusRepository
+	appointments   repository.AppointmentRepository
+	reminders      service.Reminders
+	deadLetters    repository.DeadLetterRepository
+	timezone       *time.Location
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(chatbotService service.ChatbotService, transcripts repository.TranscriptRepository, statuses repository.MessageStatusRepository,
+	appointments repository.AppointmentRepository, deadLetters repository.DeadLetterRepository) *AdminHandler {
+	return &AdminHandler{
+		chatbotService: chatbotService,
+		transcripts:    transcripts,
+		statuses:       statuses,
+		appointments:   appointments,
+		deadLetters:    deadLetters,
+		timezone:       time.Local,
+	}
+}
+
+// SetReminders drops the reminders of the appointments staff cancels
+func (h *AdminHandler) SetReminders(reminders service.Reminders) {
+	h.reminders = reminders
+}
+
+// SetTimezone sets the timezone of the appointment calendar, in which the
+// agenda days start. It is the local timezone by default.
+func (h *AdminHandler) SetTimezone(timezone *time.Location) {
+	h.timezone = timezone
+}
+
+// Agenda range used when the request leaves it out, and the longest one allowed
+const (
+	defaultAgendaDays = 7
+	maxAgendaDays     = 90
+)
+
+// setStateRequest is the body of a forced state transition
+type setStateRequest struct {
+	State string `json:"state" binding:"required"`
+}
+
+// ListSessions returns the active sessions
+func (h *AdminHandler) ListSessions(c *gin.Context) {
+	sessions, err := h.chatbotService.ListSessions()
+	if err != nil {
+		h.respondError(c, "Failed to list sessions", err)
+		return
+	}
+
+	if sessions == nil {
+		sessions = []*models.ChatbotState{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":    len(sessions),
+		"sessions": sessions,
+	})
+}
+
+// GetSession returns the conversation state and data of a user
+func (h *AdminHandler) GetSession(c *gin.Context) {
+	state, err := h.chatbotService.GetSession(c.Param("user_id"))
+	if err != nil {
+		h.respondError(c, "Failed to get session", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// SetState forces a user into another conversation state
+func (h *AdminHandler) SetState(c *gin.Context) {
+	var request setStateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": "error",
+			"error":  "Invalid JSON, expected {\"state\": \"<state>\"}",
+		})
+		return
+	}
+
+	userID := c.Param("user_id")
+	state, err := h.chatbotService.SetState(userID, request.State)
+	if err != nil {
+		h.respondError(c, "Failed to set session state", err)
+		return
+	}
+
+	logger.GetLogger().WithFields(logrus.Fields{
+		"user_id": userID,
+		"state":   request.State,
+	}).Info("Session state forced by admin")
+
+	c.JSON(http.StatusOK, state)
+}
+
+// DeleteSession resets the conversation of a user
+func (h *AdminHandler) DeleteSession(c *gin.Context) {
+	userID := c.Param("user_id")
+	if err := h.chatbotService.ResetSession(userID); err != nil {
+		h.respondError(c, "Failed to delete session", err)
+		return
+	}
+
+	logger.GetLogger().WithField("user_id", userID).Info("Session deleted by admin")
+	c.Status(http.StatusNoContent)
+}
+
+// transcriptCSVHeader lists the columns of a CSV transcript export
+var transcriptCSVHeader = []string{"timestamp", "direction", "message_id", "type", "text", "media_ref", "state_before", "state_after", "status"}
+
+// ExportTranscript returns the transcript of a user as JSON, or as CSV with ?format=csv
+func (h *AdminHandler) ExportTranscript(c *gin.Context) {
+	userID := c.Param("user_id")
+
+	entries, err := h.transcripts.ListByUser(userID)
+	if err != nil {
+		h.respondError(c, "Failed to list transcript", err)
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		if entries == nil {
+			entries = []*models.TranscriptEntry{}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"user_id": userID,
+			"count":   len(entries),
+			"entries": entries,
+		})
+	case "csv":
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "transcript-"+userID+".csv"))
+		c.Status(http.StatusOK)
+
+		if err := writeTranscriptCSV(c.Writer, entries); err != nil {
+			logger.GetLogger().WithError(err).Error("Failed to write transcript CSV")
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": "error",
+			"error":  "Unsupported format, expected json or csv",
+		})
+	}
+}
+
+// writeTranscriptCSV writes a header and a row per transcript entry
+func writeTranscriptCSV(w io.Writer, entries []*models.TranscriptEntry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(transcriptCSVHeader); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		err := writer.Write([]string{
+			entry.Timestamp.Format(time.RFC3339),
+			csvCell(entry.Direction),
+			csvCell(entry.MessageID),
+			csvCell(entry.Type),
+			csvCell(entry.Text),
+			csvCell(entry.MediaRef),
+			csvCell(entry.StateBefore),
+			csvCell(entry.StateAfter),
+			csvCell(entry.Status),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvCell quotes values a spreadsheet would run as a formula, since patients
+// choose the text of their messages
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// ListFailedMessages returns the replies WhatsApp could not deliver
+func (h *AdminHandler) ListFailedMessages(c *gin.Context) {
+	messages, err := h.statuses.ListFailed()
+	if err != nil {
+		h.respondError(c, "Failed to list failed messages", err)
+		return
+	}
+
+	if messages == nil {
+		messages = []*models.MessageStatus{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":    len(messages),
+		"messages": messages,
+	})
+}
+
+// ListDeadLetters returns the replies the outbound queue gave up on without
+// WhatsApp accepting them, oldest first
+func (h *AdminHandler) ListDeadLetters(c *gin.Context) {
+	letters, err := h.deadLetters.List()
+	if err != nil {
+		h.respondError(c, "Failed to list dead letters", err)
+		return
+	}
+
+	if letters == nil {
+		letters = []*models.DeadLetter{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":   len(letters),
+		"letters": letters,
+	})
+}
+
+// GetMessageStatus returns the delivery status of a message sent to a user
+func (h *AdminHandler) GetMessageStatus(c *gin.Context) {
+	status, err := h.statuses.Get(c.Param("message_id"))
+	if err != nil {
+		h.respondError(c, "Failed to get message status", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// ListAppointments returns the agenda of booked appointments, earliest first.
+// It covers ?days= days (7 by default) starting at ?from=YYYY-MM-DD (today by default).
+func (h *AdminHandler) ListAppointments(c *gin.Context) {
+	now := time.Now().In(h.timezone)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, h.timezone)
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", value, h.timezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status": "error",
+				"error":  "Invalid from, expected YYYY-MM-DD",
+			})
+			return
+		}
+		from = parsed
+	}
+
+	days := defaultAgendaDays
+	if value := c.Query("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxAgendaDays {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status": "error",
+				"error":  fmt.Sprintf("Invalid days, expected a number between 1 and %d", maxAgendaDays),
+			})
+			return
+		}
+		days = parsed
+	}
+	to := from.AddDate(0, 0, days)
+
+	appointments, err := h.appointments.ListBooked(from, to)
+	if err != nil {
+		h.respondError(c, "Failed to list appointments", err)
+		return
+	}
+
+	if appointments == nil {
+		appointments = []*models.Appointment{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":         from.Format("2006-01-02"),
+		"to":           to.Format("2006-01-02"),
+		"count":        len(appointments),
+		"appointments": appointments,
+	})
+}
+
+// CancelAppointment cancels a booked appointment, freeing its slot
+func (h *AdminHandler) CancelAppointment(c *gin.Context) {
+	appointment, err := h.appointments.Cancel(c.Param("appointment_id"), time.Now())
+	if err != nil {
+		h.respondError(c, "Failed to cancel appointment", err)
+		return
+	}
+	if h.reminders != nil {
+		h.reminders.AppointmentCancelled(appointment)
+	}
+
+	logger.GetLogger().WithFields(logrus.Fields{
+		"appointment_id": appointment.ID,
+		"user_id":        appointment.UserID,
+	}).Info("Appointment cancelled by staff")
+
+	c.JSON(http.StatusOK, appointment)
+}
+
+// StartHandoff pauses the bot for a user so staff can answer in person
+func (h *AdminHandler) StartHandoff(c *gin.Context) {
+	h.updateHandoff(c, "start", h.chatbotService.StartHandoff)
+}
+
+// EndHandoff returns a user to the bot
+func (h *AdminHandler) EndHandoff(c *gin.Context) {
+	h.updateHandoff(c, "end", h.chatbotService.EndHandoff)
+}
+
+func (h *AdminHandler) updateHandoff(c *gin.Context, action string, update func(userID string) (*models.ChatbotState, error)) {
+	userID := c.Param("user_id")
+
+	state, err := update(userID)
+	if err != nil {
+		h.respondError(c, "Failed to update handoff", err)
+		return
+	}
+
+	logger.GetLogger().WithFields(logrus.Fields{
+		"user_id": userID,
+		"action":  action,
+	}).Info("Handoff updated")
+
+	c.JSON(http.StatusOK, state)
+}
+
+// respondError maps domain errors to HTTP status codes
+func (h *AdminHandler) respondError(c *gin.Context, message string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domainerrors.ErrUserNotFound), errors.Is(err, domainerrors.ErrMessageNotFound),
+		errors.Is(err, domainerrors.ErrAppointmentNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, domainerrors.ErrFlowNotFound):
+		status = http.StatusBadRequest
+	default:
+		logger.GetLogger().WithError(err).WithField("user_id", c.Param("user_id")).Error(message)
+	}
+
+	c.JSON(status, gin.H{
+		"status": "error",
+		"error":  err.Error(),
+	})
+}
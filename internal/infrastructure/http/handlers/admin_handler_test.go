@@ -0,0 +1,310 @@
+package handlers_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+func TestAdminHandler_ListDeadLetters(t *testing.T) {
+	app := newTestApp(t)
+	app.graph.FailNext(http.StatusBadRequest, "Invalid parameter")
+
+	app.post(t, messagesPayload(textMessage("wamid.in1", "5491111111111", "hola")))
+	if messages := app.drain(t); len(messages) != 0 {
+		t.Fatalf("Expected the reply to be rejected, got %d messages", len(messages))
+	}
+
+	rec := app.admin(t, http.MethodGet, "/messages/dead-letters", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var body struct {
+		Count   int `json:"count"`
+		Letters []struct {
+			Response struct {
+				To string `json:"to"`
+			} `json:"response"`
+			Attempts  int    `json:"attempts"`
+			LastError string `json:"last_error"`
+		} `json:"letters"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if body.Count != 1 || body.Letters[0].Response.To != "5491111111111" || body.Letters[0].Attempts != 1 || body.Letters[0].LastError == "" {
+		t.Errorf("Expected the rejected reply, got %s", rec.Body.String())
+	}
+}
+
+func TestAdminHandler_RequiresToken(t *testing.T) {
+	app := newTestApp(t)
+
+	for _, header := range []string{"", "Bearer wrong-token", adminToken} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/sessions", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		app.router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 for Authorization %q, got %d", header, rec.Code)
+		}
+	}
+}
+
+func TestAdminHandler_Sessions(t *testing.T) {
+	app := newTestApp(t)
+	app.post(t, messagesPayload(textMessage("wamid.in1", "5491111111111", "hola")))
+
+	var list struct {
+		Count    int                    `json:"count"`
+		Sessions []*models.ChatbotState `json:"sessions"`
+	}
+	rec := app.admin(t, http.MethodGet, "/sessions", "")
+	json.Unmarshal(rec.Body.Bytes(), &list)
+	if rec.Code != http.StatusOK || list.Count != 1 || list.Sessions[0].UserID != "5491111111111" {
+		t.Fatalf("Expected the session of the sender, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Staff moves the user to another flow
+	rec = app.admin(t, http.MethodPut, "/sessions/5491111111111/state", `{"state": "option_b"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var state models.ChatbotState
+	rec = app.admin(t, http.MethodGet, "/sessions/5491111111111", "")
+	json.Unmarshal(rec.Body.Bytes(), &state)
+	if rec.Code != http.StatusOK || state.State != "option_b" {
+		t.Errorf("Expected the session in option_b, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"unknown state", `{"state": "missing"}`, http.StatusBadRequest},
+		{"invalid JSON", `{"state":`, http.StatusBadRequest},
+		{"missing state", `{}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := app.admin(t, http.MethodPut, "/sessions/5491111111111/state", tt.body); rec.Code != tt.expected {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.expected, rec.Code)
+		}
+	}
+
+	// Deleting resets the conversation, a second delete finds nothing
+	if rec := app.admin(t, http.MethodDelete, "/sessions/5491111111111", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", rec.Code)
+	}
+	if rec := app.admin(t, http.MethodDelete, "/sessions/5491111111111", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 deleting a missing session, got %d", rec.Code)
+	}
+	if rec := app.admin(t, http.MethodDelete, "/sessions/5490000000000", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown user, got %d", rec.Code)
+	}
+	rec = app.admin(t, http.MethodGet, "/sessions", "")
+	json.Unmarshal(rec.Body.Bytes(), &list)
+	if list.Count != 0 {
+		t.Errorf("Expected no session left, got %s", rec.Body.String())
+	}
+}
+
+func TestAdminHandler_ExportTranscript(t *testing.T) {
+	app := newTestApp(t)
+	app.post(t, messagesPayload(textMessage("wamid.in1", "5491111111111", "hola")))
+	app.post(t, messagesPayload(textMessage("wamid.in2", "5491111111111", "D")))
+	app.post(t, messagesPayload(textMessage("wamid.in3", "5492222222222", "=SUM(A1:A9)")))
+	app.drain(t)
+
+	var transcript struct {
+		UserID  string                    `json:"user_id"`
+		Count   int                       `json:"count"`
+		Entries []*models.TranscriptEntry `json:"entries"`
+	}
+	rec := app.admin(t, http.MethodGet, "/sessions/5491111111111/transcript", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &transcript); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if rec.Code != http.StatusOK || transcript.Count != 4 || len(transcript.Entries) != 4 {
+		t.Fatalf("Expected 2 inbound and 2 outbound messages, got %d %s", rec.Code, rec.Body.String())
+	}
+	if transcript.Entries[0].Direction != models.DirectionInbound || transcript.Entries[0].Text != "hola" {
+		t.Errorf("Expected the first message of the patient first, got %+v", transcript.Entries[0])
+	}
+
+	rec = app.admin(t, http.MethodGet, "/sessions/5491111111111/transcript?format=csv", "")
+	if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/csv") {
+		t.Errorf("Expected a CSV content type, got %q", contentType)
+	}
+	if disposition := rec.Header().Get("Content-Disposition"); !strings.Contains(disposition, "transcript-5491111111111.csv") {
+		t.Errorf("Expected the CSV as an attachment, got %q", disposition)
+	}
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV: %v", err)
+	}
+	if len(records) != 5 || strings.Join(records[0], ",") != "timestamp,direction,message_id,type,text,media_ref,state_before,state_after,status" {
+		t.Fatalf("Expected a header and 4 rows, got %v", records)
+	}
+	if records[1][1] != models.DirectionInbound || records[1][2] != "wamid.in1" || records[1][4] != "hola" {
+		t.Errorf("Expected the first inbound message, got %v", records[1])
+	}
+
+	// Messages a spreadsheet would run as formulas are exported as text
+	rec = app.admin(t, http.MethodGet, "/sessions/5492222222222/transcript?format=csv", "")
+	records, err = csv.NewReader(rec.Body).ReadAll()
+	if err != nil || len(records) < 2 {
+		t.Fatalf("Expected a CSV transcript, got %v (%v)", records, err)
+	}
+	if records[1][4] != "'=SUM(A1:A9)" {
+		t.Errorf("Expected the formula quoted, got %q", records[1][4])
+	}
+
+	// Users without messages export an empty transcript
+	rec = app.admin(t, http.MethodGet, "/sessions/5490000000000/transcript", "")
+	json.Unmarshal(rec.Body.Bytes(), &transcript)
+	if rec.Code != http.StatusOK || transcript.Count != 0 || transcript.Entries == nil {
+		t.Errorf("Expected an empty transcript, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := app.admin(t, http.MethodGet, "/sessions/5491111111111/transcript?format=xml", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unsupported format, got %d", rec.Code)
+	}
+}
+
+func TestAdminHandler_MessageStatuses(t *testing.T) {
+	app := newTestApp(t)
+	app.post(t, messagesPayload(textMessage("wamid.in1", "5491111111111", "hola")))
+	messages := app.drain(t)
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 reply, got %d", len(messages))
+	}
+	replyID := messages[0].ID
+
+	var status models.MessageStatus
+	rec := app.admin(t, http.MethodGet, "/messages/"+replyID, "")
+	json.Unmarshal(rec.Body.Bytes(), &status)
+	if rec.Code != http.StatusOK || status.Status != models.DeliveryAccepted || status.UserID != "5491111111111" {
+		t.Fatalf("Expected the accepted reply, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	var failed struct {
+		Count    int                     `json:"count"`
+		Messages []*models.MessageStatus `json:"messages"`
+	}
+	rec = app.admin(t, http.MethodGet, "/messages/failed", "")
+	json.Unmarshal(rec.Body.Bytes(), &failed)
+	if rec.Code != http.StatusOK || failed.Count != 0 || failed.Messages == nil {
+		t.Fatalf("Expected no failed messages yet, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// WhatsApp reports the reply could not be delivered
+	app.post(t, `{"object": "whatsapp_business_account", "entry": [{"id": "1", "changes": [{"field": "messages", "value": {
+		"messaging_product": "whatsapp", "statuses": [
+			{"id": "`+replyID+`", "recipient_id": "5491111111111", "status": "failed", "timestamp": "1700000005",
+			 "errors": [{"code": 131047, "title": "Re-engagement message"}]}
+		]}}]}]}`)
+
+	rec = app.admin(t, http.MethodGet, "/messages/failed", "")
+	json.Unmarshal(rec.Body.Bytes(), &failed)
+	if failed.Count != 1 || failed.Messages[0].MessageID != replyID || failed.Messages[0].ErrorCode != 131047 {
+		t.Errorf("Expected the reply to have failed with code 131047, got %s", rec.Body.String())
+	}
+
+	if rec := app.admin(t, http.MethodGet, "/messages/wamid.unknown", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown message, got %d", rec.Code)
+	}
+}
+
+func TestAdminHandler_Appointments(t *testing.T) {
+	app := newTestApp(t)
+
+	now := time.Now().In(testTimezone)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, testTimezone)
+	var booked []*models.Appointment
+	for _, days := range []int{2, 5, 10} {
+		start := today.AddDate(0, 0, days).Add(10 * time.Hour)
+		appointment := &models.Appointment{UserID: "5491111111111", PatientName: "Juan Pérez", LocationID: "centro",
+			LocationName: "Consultorio Centro", Start: start, End: start.Add(30 * time.Minute), CreatedAt: now}
+		if err := app.appointments.Book(appointment); err != nil {
+			t.Fatalf("Failed to book appointment: %v", err)
+		}
+		booked = append(booked, appointment)
+	}
+
+	var agenda struct {
+		From         string                `json:"from"`
+		To           string                `json:"to"`
+		Count        int                   `json:"count"`
+		Appointments []*models.Appointment `json:"appointments"`
+	}
+	rec := app.admin(t, http.MethodGet, "/appointments", "")
+	json.Unmarshal(rec.Body.Bytes(), &agenda)
+	if rec.Code != http.StatusOK || agenda.Count != 2 || agenda.From != today.Format("2006-01-02") {
+		t.Fatalf("Expected the appointments of the next 7 days, got %d %s", rec.Code, rec.Body.String())
+	}
+	if agenda.Appointments[0].ID != booked[0].ID || agenda.Appointments[1].ID != booked[1].ID {
+		t.Errorf("Expected the appointments earliest first, got %s", rec.Body.String())
+	}
+
+	rec = app.admin(t, http.MethodGet, "/appointments?from="+booked[2].Start.Format("2006-01-02")+"&days=1", "")
+	json.Unmarshal(rec.Body.Bytes(), &agenda)
+	if rec.Code != http.StatusOK || agenda.Count != 1 || agenda.Appointments[0].ID != booked[2].ID {
+		t.Errorf("Expected the appointment of the requested day, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Days start in the timezone of the calendar, not that of the server
+	late := today.AddDate(0, 0, 20).Add(23*time.Hour + 30*time.Minute)
+	lateAppointment := &models.Appointment{UserID: "5491111111111", LocationID: "centro", Start: late, End: late.Add(30 * time.Minute), CreatedAt: now}
+	if err := app.appointments.Book(lateAppointment); err != nil {
+		t.Fatalf("Failed to book appointment: %v", err)
+	}
+	rec = app.admin(t, http.MethodGet, "/appointments?from="+late.Format("2006-01-02")+"&days=1", "")
+	json.Unmarshal(rec.Body.Bytes(), &agenda)
+	if rec.Code != http.StatusOK || agenda.Count != 1 || agenda.Appointments[0].ID != lateAppointment.ID {
+		t.Errorf("Expected the late appointment on its calendar day, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	for _, query := range []string{"from=10/05/2024", "days=0", "days=91", "days=semana"} {
+		if rec := app.admin(t, http.MethodGet, "/appointments?"+query, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, rec.Code)
+		}
+	}
+
+	// Staff cancels an appointment with a pending reminder
+	app.reminders.AppointmentBooked(booked[0])
+	if due, _ := app.pendingReminders.ListDue(booked[0].Start, 10); len(due) != 1 {
+		t.Fatalf("Expected the reminder to be pending, got %d", len(due))
+	}
+
+	var cancelled models.Appointment
+	rec = app.admin(t, http.MethodDelete, "/appointments/"+booked[0].ID, "")
+	json.Unmarshal(rec.Body.Bytes(), &cancelled)
+	if rec.Code != http.StatusOK || cancelled.Status != models.AppointmentCancelled {
+		t.Fatalf("Expected the appointment cancelled, got %d %s", rec.Code, rec.Body.String())
+	}
+	if due, _ := app.pendingReminders.ListDue(booked[0].Start, 10); len(due) != 0 {
+		t.Errorf("Expected the reminder to be dropped, got %+v", due)
+	}
+
+	rec = app.admin(t, http.MethodGet, "/appointments", "")
+	json.Unmarshal(rec.Body.Bytes(), &agenda)
+	if agenda.Count != 1 || agenda.Appointments[0].ID != booked[1].ID {
+		t.Errorf("Expected the cancelled appointment off the agenda, got %s", rec.Body.String())
+	}
+
+	for _, id := range []string{booked[0].ID, "unknown"} {
+		if rec := app.admin(t, http.MethodDelete, "/appointments/"+id, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 cancelling %s, got %d", id, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+
+	"chatbot-wsp/internal/infrastructure/logger"
+	"chatbot-wsp/internal/infrastructure/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsHandler exposes the service metrics
+type MetricsHandler struct {
+	metrics *metrics.Metrics
+}
+
+// NewMetricsHandler creates a new metrics handler
+func NewMetricsHandler(metrics *metrics.Metrics) *MetricsHandler {
+	return &MetricsHandler{
+		metrics: metrics,
+	}
+}
+
+// GetStats returns statistics about the service as JSON
+func (h *MetricsHandler) GetStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.metrics.Stats())
+}
+
+// GetMetrics returns the metrics in the Prometheus text format
+func (h *MetricsHandler) GetMetrics(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := h.metrics.WritePrometheus(c.Writer); err != nil {
+		logger.GetLogger().WithError(err).Error("Failed to write metrics")
+	}
+}
@@ -0,0 +1,53 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"chatbot-wsp/internal/infrastructure/metrics"
+)
+
+func TestMetricsHandler_CountsWebhookMessages(t *testing.T) {
+	app := newTestApp(t)
+	app.post(t, messagesPayload(textMessage("wamid.in1", "5491111111111", "hola")))
+	app.drain(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/stats", nil)
+	rec := httptest.NewRecorder()
+	app.router.ServeHTTP(rec, req)
+
+	var stats metrics.Stats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if rec.Code != http.StatusOK || stats.MessagesReceived != 1 || stats.MessagesProcessed != 1 || stats.ReceivedByType["text"] != 1 {
+		t.Errorf("Expected the message to be counted, got %d %s", rec.Code, rec.Body.String())
+	}
+	if stats.SentByStatus["200"] != 1 {
+		t.Errorf("Expected the reply to be counted as sent, got %v", stats.SentByStatus)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec = httptest.NewRecorder()
+	app.router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Expected the Prometheus content type, got %q", contentType)
+	}
+	for _, line := range []string{
+		"# TYPE chatbot_messages_received_total counter",
+		`chatbot_messages_received_total{type="text"} 1`,
+		`chatbot_messages_processed_total{type="text"} 1`,
+		`chatbot_messages_sent_total{status="200"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), line+"\n") {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", line, rec.Body.String())
+		}
+	}
+}
@@ -1,39 +1,66 @@
 package handlers
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
 	"chatbot-wsp/internal/domain/service"
 	"chatbot-wsp/internal/infrastructure/logger"
+	"chatbot-wsp/internal/infrastructure/metrics"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
+// MessageQueue schedules replies for asynchronous delivery
+type MessageQueue interface {
+	Enqueue(response *models.WhatsAppResponse) error
+}
+
+// MediaDownloader stores the media attached to an incoming message
+type MediaDownloader interface {
+	Download(message *models.WhatsAppMessage) (*models.MediaReference, error)
+}
+
+// StatusTracker stores the delivery statuses reported for messages we sent
+type StatusTracker interface {
+	UpdateStatus(status *models.MessageStatus) error
+}
+
 // WhatsAppHandler handles WhatsApp webhook requests
 type WhatsAppHandler struct {
 	chatbotService service.ChatbotService
+	outbound       MessageQueue
+	statuses       StatusTracker
+	dedup          repository.MessageDedupRepository
+	lastInbound    repository.LastInboundRepository
+	transcripts    repository.TranscriptRepository
+	media          MediaDownloader
+	metrics        *metrics.Metrics
 	config         *Config
 }
 
 // Config holds configuration for the handler
 type Config struct {
-	VerifyToken   string
-	AccessToken   string
-	PhoneNumberID string
-	MyPhoneNumber string
+	VerifyToken string
 }
 
 // NewWhatsAppHandler creates a new WhatsApp handler
-func NewWhatsAppHandler(chatbotService service.ChatbotService, config *Config) *WhatsAppHandler {
+func NewWhatsAppHandler(chatbotService service.ChatbotService, outbound MessageQueue, statuses StatusTracker, dedup repository.MessageDedupRepository, lastInbound repository.LastInboundRepository, transcripts repository.TranscriptRepository, media MediaDownloader, metrics *metrics.Metrics, config *Config) *WhatsAppHandler {
 	return &WhatsAppHandler{
 		chatbotService: chatbotService,
+		outbound:       outbound,
+		statuses:       statuses,
+		dedup:          dedup,
+		lastInbound:    lastInbound,
+		transcripts:    transcripts,
+		media:          media,
+		metrics:        metrics,
 		config:         config,
 	}
 }
@@ -87,6 +114,8 @@ func (h *WhatsAppHandler) HandleWebhook(c *gin.Context) {
 
 	// Track processing results
 	var processedMessages int
+	var processedStatuses int
+	var duplicateMessages int
 	var errors []string
 	var totalMessages int
 	var chatbotResponses []string
@@ -98,20 +127,42 @@ func (h *WhatsAppHandler) HandleWebhook(c *gin.Context) {
 				// Convert webhook messages to our message format
 				var messages []models.WhatsAppMessage
 				for _, msg := range change.Value.Messages {
-					messages = append(messages, models.WhatsAppMessage{
+					message := models.WhatsAppMessage{
 						ID:   msg.ID,
 						From: msg.From,
 						Text: msg.Text.Body,
 						Type: msg.Type,
-					})
+					}
+					switch msg.Type {
+					case "interactive":
+						// Selections carry the option ID, process it like a typed option
+						if msg.Interactive != nil {
+							message.Text = msg.Interactive.SelectedID()
+						}
+					case "image":
+						message.Media = msg.Image
+					case "document":
+						message.Media = msg.Document
+					case "audio":
+						message.Media = msg.Audio
+					case "video":
+						message.Media = msg.Video
+					}
+					messages = append(messages, message)
 					totalMessages++
 				}
 
 				// Process messages and collect results
-				processed, processingErrors, responses := h.processMessages(messages)
+				processed, duplicates, processingErrors, responses := h.processMessages(messages)
 				processedMessages += processed
+				duplicateMessages += duplicates
 				errors = append(errors, processingErrors...)
 				chatbotResponses = append(chatbotResponses, responses...)
+
+				// Delivery statuses of the replies we sent
+				statuses, statusErrors := h.processStatuses(change.Value.Statuses)
+				processedStatuses += statuses
+				errors = append(errors, statusErrors...)
 			}
 		}
 	}
@@ -121,6 +172,12 @@ func (h *WhatsAppHandler) HandleWebhook(c *gin.Context) {
 		"status":             "success",
 		"messages_received":  totalMessages,
 		"messages_processed": processedMessages,
+		"messages_duplicate": duplicateMessages,
+		"statuses_processed": processedStatuses,
+	}
+
+	if totalMessages > 0 && duplicateMessages == totalMessages {
+		response["status"] = "duplicate"
 	}
 
 	if len(errors) > 0 {
@@ -138,7 +195,7 @@ func (h *WhatsAppHandler) HandleWebhook(c *gin.Context) {
 }
 
 // processMessages processes incoming messages and returns processing statistics
-func (h *WhatsAppHandler) processMessages(messages []models.WhatsAppMessage) (processed int, errors []string, responses []string) {
+func (h *WhatsAppHandler) processMessages(messages []models.WhatsAppMessage) (processed, duplicates int, errors []string, responses []string) {
 	for _, message := range messages {
 		logger.GetLogger().WithFields(logrus.Fields{
 			"from":       message.From,
@@ -146,120 +203,227 @@ func (h *WhatsAppHandler) processMessages(messages []models.WhatsAppMessage) (pr
 			"type":       message.Type,
 		}).Info("Processing message")
 
-		// Only process text messages
-		if message.Type != "text" {
-			logger.GetLogger().WithField("type", message.Type).Warn("Ignoring non-text message")
-			errors = append(errors, fmt.Sprintf("Message %s: unsupported type %s", message.ID, message.Type))
-			continue
+		// Skip messages already handled in a previous delivery of the webhook
+		if message.ID != "" {
+			seen, err := h.dedup.MarkProcessed(message.ID)
+			if err != nil {
+				logger.GetLogger().WithError(err).Warn("Failed to check message deduplication")
+			} else if seen {
+				logger.GetLogger().WithField("message_id", message.ID).Info("Ignoring duplicate message")
+				h.resendKeptReply(message.ID)
+				duplicates++
+				continue
+			}
 		}
+		h.metrics.MessageReceived(message.Type)
+		h.touchLastInbound(message.From)
 
 		// Process the message
-		response, err := h.chatbotService.ProcessMessage(message.From, message.Text)
+		var response *models.WhatsAppResponse
+		var mediaRef string
+		var err error
+		switch {
+		case message.Type == "text", message.Type == "interactive":
+			response, err = h.chatbotService.ProcessMessage(message.From, message.Text)
+		case message.Media != nil:
+			response, mediaRef, err = h.processMedia(&message)
+		default:
+			logger.GetLogger().WithField("type", message.Type).Warn("Ignoring unsupported message")
+			errors = append(errors, fmt.Sprintf("Message %s: unsupported type %s", message.ID, message.Type))
+			h.metrics.MessageFailed(message.Type)
+			h.recordInbound(&message, "", nil)
+			continue
+		}
+		h.recordInbound(&message, mediaRef, response)
+		// The conversation is only saved once the message was processed, so a
+		// redelivery of a message that failed can safely be processed again
 		if err != nil {
 			errorMsg := fmt.Sprintf("Message %s: failed to process - %v", message.ID, err)
 			logger.GetLogger().WithError(err).Error("Failed to process message")
 			errors = append(errors, errorMsg)
+			h.metrics.MessageFailed(message.Type)
+			h.unmarkProcessed(message.ID)
 			continue
 		}
 
-		// Add response to the list for testing purposes
-		if response != nil && response.Text.Body != "" {
-			responses = append(responses, response.Text.Body)
+		// No reply while staff is answering the user in person
+		if response == nil {
+			processed++
+			h.metrics.MessageProcessed(message.Type)
+			logger.GetLogger().WithField("from", message.From).Info("Conversation handed off - not replying")
+			continue
+		}
+
+		// Never post an empty message to WhatsApp
+		if isEmptyResponse(response) {
+			errorMsg := fmt.Sprintf("Message %s: refusing to send an empty reply", message.ID)
+			logger.GetLogger().WithFields(logrus.Fields{
+				"message_id": message.ID,
+				"from":       message.From,
+			}).Error("Chatbot produced an empty reply")
+			errors = append(errors, errorMsg)
+			h.metrics.MessageFailed(message.Type)
+			continue
 		}
 
-		// Send response back to WhatsApp
-		if err := h.sendMessage(response); err != nil {
-			errorMsg := fmt.Sprintf("Message %s: failed to send response - %v", message.ID, err)
-			logger.GetLogger().WithError(err).Error("Failed to send response")
+		// Add response to the list for testing purposes
+		responses = append(responses, response.Text.Body)
+
+		// Queue response for delivery to WhatsApp
+		if err := h.outbound.Enqueue(response); err != nil {
+			errorMsg := fmt.Sprintf("Message %s: failed to queue response - %v", message.ID, err)
+			logger.GetLogger().WithError(err).Error("Failed to queue response")
 			errors = append(errors, errorMsg)
+			h.metrics.MessageFailed(message.Type)
+			h.keepReply(message.ID, response)
 			continue
 		}
 
 		processed++
+		h.metrics.MessageProcessed(message.Type)
 		logger.GetLogger().WithFields(logrus.Fields{
 			"message_id": message.ID,
 			"from":       message.From,
 		}).Info("Message processed successfully")
 	}
 
-	return processed, errors, responses
+	return processed, duplicates, errors, responses
 }
 
-// sendMessage sends a message to WhatsApp Business API
-func (h *WhatsAppHandler) sendMessage(response *models.WhatsAppResponse) error {
-	// Check if we have the required configuration
-	if h.config.AccessToken == "" || h.config.PhoneNumberID == "" || h.config.MyPhoneNumber == "" {
-		logger.GetLogger().Warn("WhatsApp configuration missing - skipping message send")
-		return fmt.Errorf("WhatsApp configuration incomplete")
+// unmarkProcessed lets a redelivery of a message that failed be processed again
+func (h *WhatsAppHandler) unmarkProcessed(messageID string) {
+	if messageID == "" {
+		return
 	}
+	if err := h.dedup.Unmark(messageID); err != nil {
+		logger.GetLogger().WithError(err).WithField("message_id", messageID).Warn("Failed to unmark message")
+	}
+}
 
-	// Prepare the request payload
-	payload := map[string]interface{}{
-		"messaging_product": response.MessagingProduct,
-		"to":                h.config.MyPhoneNumber,
-		"type":              response.Type,
-		"text": map[string]interface{}{
-			"body": response.Text.Body,
-		},
+// touchLastInbound records that the user opened WhatsApp's service window,
+// even with a message that fails to be processed
+func (h *WhatsAppHandler) touchLastInbound(userID string) {
+	if err := h.lastInbound.Touch(userID, time.Now()); err != nil {
+		logger.GetLogger().WithError(err).WithField("user_id", userID).Warn("Failed to record last inbound message")
 	}
+}
 
-	// Convert to JSON
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		logger.GetLogger().WithError(err).Error("Failed to marshal WhatsApp message payload")
-		return fmt.Errorf("failed to marshal payload: %v", err)
+// keepReply holds a reply that could not be queued. The conversation already
+// moved on, so a redelivery of the message sends this reply instead of
+// processing the message again.
+func (h *WhatsAppHandler) keepReply(messageID string, response *models.WhatsAppResponse) {
+	if messageID == "" {
+		return
+	}
+	if err := h.dedup.KeepReply(messageID, response); err != nil {
+		logger.GetLogger().WithError(err).WithField("message_id", messageID).Error("Failed to keep reply")
 	}
+}
 
-	// Create HTTP request
-	url := fmt.Sprintf("https://graph.facebook.com/v17.0/%s/messages", h.config.PhoneNumberID)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+// resendKeptReply queues the reply kept for a message whose first delivery
+// could not be answered
+func (h *WhatsAppHandler) resendKeptReply(messageID string) {
+	response, err := h.dedup.TakeReply(messageID)
 	if err != nil {
-		logger.GetLogger().WithError(err).Error("Failed to create WhatsApp API request")
-		return fmt.Errorf("failed to create request: %v", err)
+		logger.GetLogger().WithError(err).WithField("message_id", messageID).Error("Failed to take kept reply")
+		return
+	}
+	if response == nil {
+		return
 	}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+h.config.AccessToken)
+	if err := h.outbound.Enqueue(response); err != nil {
+		logger.GetLogger().WithError(err).WithField("message_id", messageID).Error("Failed to queue kept reply")
+		h.keepReply(messageID, response)
+		return
+	}
+	logger.GetLogger().WithFields(logrus.Fields{
+		"message_id": messageID,
+		"to":         response.To,
+	}).Info("Kept reply queued")
+}
 
-	// Send request
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		logger.GetLogger().WithError(err).Error("Failed to send message to WhatsApp API")
-		return fmt.Errorf("failed to send request: %v", err)
+// processStatuses stores the delivery status callbacks of a webhook
+func (h *WhatsAppHandler) processStatuses(statuses []models.WebhookStatus) (processed int, errors []string) {
+	for _, callback := range statuses {
+		status := &models.MessageStatus{
+			MessageID: callback.ID,
+			UserID:    callback.RecipientID,
+			Status:    callback.Status,
+			UpdatedAt: time.Now(),
+		}
+		if seconds, err := strconv.ParseInt(callback.Timestamp, 10, 64); err == nil {
+			status.UpdatedAt = time.Unix(seconds, 0)
+		}
+		if len(callback.Errors) > 0 {
+			status.ErrorCode = callback.Errors[0].Code
+			status.ErrorTitle = callback.Errors[0].Title
+		}
+
+		if err := h.statuses.UpdateStatus(status); err != nil {
+			logger.GetLogger().WithFields(logrus.Fields{
+				"message_id": callback.ID,
+				"status":     callback.Status,
+				"error":      err.Error(),
+			}).Error("Failed to store message status")
+			errors = append(errors, fmt.Sprintf("Status %s of message %s: failed to store - %v", callback.Status, callback.ID, err))
+			continue
+		}
+		processed++
 	}
-	defer resp.Body.Close()
 
-	// Read response
-	body, err := io.ReadAll(resp.Body)
+	return processed, errors
+}
+
+// processMedia stores the media of a message and attaches it to the user's conversation
+func (h *WhatsAppHandler) processMedia(message *models.WhatsAppMessage) (*models.WhatsAppResponse, string, error) {
+	media, err := h.media.Download(message)
 	if err != nil {
-		logger.GetLogger().WithError(err).Error("Failed to read WhatsApp API response")
-		return fmt.Errorf("failed to read response: %v", err)
+		return nil, "", err
 	}
 
-	// Log the response
-	logger.GetLogger().WithFields(logrus.Fields{
-		"status_code": resp.StatusCode,
-		"response":    string(body),
-		"to":          response.To,
-		"type":        response.Type,
-		"body":        response.Text.Body,
-	}).Info("WhatsApp API response")
-
-	// Check if the request was successful
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		logger.GetLogger().Info("Message sent successfully to WhatsApp")
-		return nil
+	response, err := h.chatbotService.ProcessMedia(message.From, media)
+	return response, media.Ref, err
+}
+
+// isEmptyResponse reports whether a reply has nothing to show the user
+func isEmptyResponse(response *models.WhatsAppResponse) bool {
+	if response.Interactive != nil {
+		return strings.TrimSpace(response.Interactive.Body.Body) == ""
 	}
+	return strings.TrimSpace(response.Text.Body) == ""
+}
 
-	// Log error response
-	logger.GetLogger().WithFields(logrus.Fields{
-		"status_code": resp.StatusCode,
-		"response":    string(body),
-	}).Error("WhatsApp API returned error")
+// recordInbound adds a received message to the user's transcript
+func (h *WhatsAppHandler) recordInbound(message *models.WhatsAppMessage, mediaRef string, response *models.WhatsAppResponse) {
+	entry := &models.TranscriptEntry{
+		UserID:    message.From,
+		MessageID: message.ID,
+		Direction: models.DirectionInbound,
+		Type:      message.Type,
+		Text:      message.Text,
+		MediaRef:  mediaRef,
+		Timestamp: time.Now(),
+	}
+	if message.Media != nil && message.Media.Caption != "" {
+		entry.Text = message.Media.Caption
+	}
+	if response != nil {
+		entry.StateBefore = response.StateBefore
+		entry.StateAfter = response.StateAfter
+	}
 
-	return fmt.Errorf("WhatsApp API error: status %d, response: %s", resp.StatusCode, string(body))
+	h.appendTranscript(entry)
+}
+
+func (h *WhatsAppHandler) appendTranscript(entry *models.TranscriptEntry) {
+	if err := h.transcripts.Append(entry); err != nil {
+		logger.GetLogger().WithFields(logrus.Fields{
+			"user_id":   entry.UserID,
+			"direction": entry.Direction,
+			"error":     err.Error(),
+		}).Error("Failed to record transcript entry")
+	}
 }
 
 // GetWelcomeMessage returns the welcome message
@@ -276,16 +440,3 @@ func (h *WhatsAppHandler) HealthCheck(c *gin.Context) {
 		"service":   "chatbot-wsp",
 	})
 }
-
-// GetStats returns basic statistics about the service
-func (h *WhatsAppHandler) GetStats(c *gin.Context) {
-	// In a real implementation, you would collect actual statistics
-	stats := gin.H{
-		"uptime":             time.Since(time.Now()).String(),
-		"messages_processed": 0, // This would be tracked in a real implementation
-		"active_users":       0, // This would be tracked in a real implementation
-		"timestamp":          time.Now().UTC(),
-	}
-
-	c.JSON(http.StatusOK, stats)
-}
@@ -0,0 +1,477 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
+	"chatbot-wsp/internal/domain/service"
+	"chatbot-wsp/internal/infrastructure/delivery"
+	"chatbot-wsp/internal/infrastructure/flows"
+	"chatbot-wsp/internal/infrastructure/http/handlers"
+	"chatbot-wsp/internal/infrastructure/http/routes"
+	"chatbot-wsp/internal/infrastructure/media"
+	"chatbot-wsp/internal/infrastructure/metrics"
+	"chatbot-wsp/internal/infrastructure/outbound"
+	"chatbot-wsp/internal/infrastructure/reminder"
+	"chatbot-wsp/internal/infrastructure/whatsapp"
+	"chatbot-wsp/internal/infrastructure/whatsapp/whatsapptest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testApp wires the webhook handler to a fake Graph API the same way main does
+type testApp struct {
+	router           *gin.Engine
+	graph            *whatsapptest.Server
+	queue            *outbound.Queue
+	replies          *flakyQueue
+	sessions         repository.ChatbotRepository
+	appointments     repository.AppointmentRepository
+	reminders        *reminder.Scheduler
+	pendingReminders repository.ReminderRepository
+	statuses         repository.MessageStatusRepository
+	transcripts      repository.TranscriptRepository
+	deadLetters      *repository.InMemoryDeadLetterRepository
+}
+
+// testTimezone is the timezone of the appointment calendar of the tests, away
+// from UTC so agenda days differ from those of a server running in UTC
+var testTimezone = time.FixedZone("ART", -3*60*60)
+
+// adminToken authorizes the admin requests of the tests
+const adminToken = "admin-token"
+
+func newTestApp(t *testing.T) *testApp {
+	chatbotFlows, err := flows.Load("")
+	if err != nil {
+		t.Fatalf("Failed to load flows: %v", err)
+	}
+
+	graph := whatsapptest.NewServer()
+	t.Cleanup(graph.Close)
+
+	appMetrics := metrics.New()
+	statuses := repository.NewInMemoryMessageStatusRepository(100)
+	transcripts := repository.NewInMemoryTranscriptRepository(100)
+	client := whatsapp.NewGraphClient(graph.Config(), appMetrics)
+	tracker := delivery.NewTracker(statuses, transcripts, appMetrics)
+
+	deadLetters := repository.NewInMemoryDeadLetterRepository(10)
+	queue := outbound.NewQueue(client, deadLetters, &outbound.Config{
+		Workers:        1,
+		QueueSize:      10,
+		MaxAttempts:    1,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	})
+	queue.SetTracker(tracker)
+	lastInbound := repository.NewInMemoryLastInboundRepository(outbound.ServiceWindow)
+	queue.SetWindowFallback(outbound.NewWindowFallback(lastInbound.LastInbound, "seguimiento", "es_AR", true))
+	queue.Start()
+
+	sessions := repository.NewInMemoryChatbotRepository(chatbotFlows)
+	chatbotService := service.NewChatbotService(sessions)
+	replies := &flakyQueue{Queue: queue}
+	handler := handlers.NewWhatsAppHandler(chatbotService, replies, tracker, repository.NewInMemoryMessageDedupRepository(time.Hour), lastInbound,
+		transcripts, media.NewDownloader(client, media.NewLocalBlobStore(t.TempDir())), appMetrics, &handlers.Config{
+			VerifyToken: "verify-token",
+		})
+	appointments := repository.NewInMemoryAppointmentRepository()
+	pendingReminders := repository.NewInMemoryReminderRepository()
+	reminders := reminder.NewScheduler(pendingReminders, appointments, queue, &reminder.Config{AppointmentLead: 24 * time.Hour})
+	adminHandler := handlers.NewAdminHandler(chatbotService, transcripts, statuses, appointments, deadLetters)
+	adminHandler.SetReminders(reminders)
+	adminHandler.SetTimezone(testTimezone)
+	router := routes.SetupRoutes(handler, adminHandler, handlers.NewMetricsHandler(appMetrics), &routes.Config{
+		SkipSignature: true,
+		AdminToken:    adminToken,
+	})
+
+	return &testApp{
+		router:           router,
+		graph:            graph,
+		queue:            queue,
+		replies:          replies,
+		sessions:         sessions,
+		appointments:     appointments,
+		reminders:        reminders,
+		pendingReminders: pendingReminders,
+		statuses:         statuses,
+		transcripts:      transcripts,
+		deadLetters:      deadLetters,
+	}
+}
+
+// flakyQueue is the outbound queue the handler replies through, failing the
+// next failures replies as a full queue would
+type flakyQueue struct {
+	*outbound.Queue
+	mutex    sync.Mutex
+	failures int
+}
+
+func (q *flakyQueue) Enqueue(response *models.WhatsAppResponse) error {
+	q.mutex.Lock()
+	if q.failures > 0 {
+		q.failures--
+		q.mutex.Unlock()
+		return outbound.ErrQueueFull
+	}
+	q.mutex.Unlock()
+
+	return q.Queue.Enqueue(response)
+}
+
+func (q *flakyQueue) failNext(failures int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.failures = failures
+}
+
+// post delivers a webhook payload and returns the decoded response
+func (a *testApp) post(t *testing.T, payload string) (int, map[string]interface{}) {
+	req := httptest.NewRequest(http.MethodPost, "/whatsapp/webhook", bytes.NewBufferString(payload))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+
+	var body map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	return rec.Code, body
+}
+
+// admin sends an authorized admin API request and returns the recorded response
+func (a *testApp) admin(t *testing.T, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/v1/admin"+path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// drain waits until every queued reply reached the fake Graph API
+func (a *testApp) drain(t *testing.T) []*whatsapptest.Message {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := a.queue.Shutdown(ctx); err != nil {
+		t.Fatalf("Outbound queue did not drain: %v", err)
+	}
+	return a.graph.Messages()
+}
+
+// messagesPayload builds a webhook carrying the given messages
+func messagesPayload(messages ...string) string {
+	return `{"object": "whatsapp_business_account", "entry": [{"id": "1", "changes": [{"field": "messages", "value": {
+		"messaging_product": "whatsapp", "messages": [` + strings.Join(messages, ",") + `]}}]}]}`
+}
+
+func textMessage(id, from, body string) string {
+	return `{"id": "` + id + `", "from": "` + from + `", "timestamp": "1700000000", "type": "text", "text": {"body": "` + body + `"}}`
+}
+
+func TestWhatsAppHandler_RepliesThroughGraphAPI(t *testing.T) {
+	app := newTestApp(t)
+
+	code, body := app.post(t, messagesPayload(
+		textMessage("wamid.in1", "5491111111111", "hola"),
+		textMessage("wamid.in2", "5491122222222", "hola"),
+	))
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if body["messages_processed"] != float64(2) {
+		t.Errorf("Expected 2 processed messages, got %v", body["messages_processed"])
+	}
+
+	messages := app.drain(t)
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 replies, got %d", len(messages))
+	}
+
+	recipients := map[string]bool{}
+	for _, message := range messages {
+		recipients[message.To] = true
+		if message.MessagingProduct != "whatsapp" || message.Type != "text" {
+			t.Errorf("Expected a whatsapp text message, got %+v", message)
+		}
+		if message.Text == nil || !strings.Contains(message.Text.Body, "seleccioná una opción") {
+			t.Errorf("Expected the welcome menu, got %+v", message.Text)
+		}
+	}
+	if !recipients["5491111111111"] || !recipients["5491122222222"] {
+		t.Errorf("Expected a reply to each sender, got %v", recipients)
+	}
+
+	// The reply is tracked under the ID the Graph API assigned to it
+	status, err := app.statuses.Get(messages[0].ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if status.Status != models.DeliveryAccepted || status.UserID != messages[0].To {
+		t.Errorf("Expected accepted reply to %s, got %+v", messages[0].To, status)
+	}
+}
+
+func TestWhatsAppHandler_IgnoresDuplicateDeliveries(t *testing.T) {
+	app := newTestApp(t)
+	payload := messagesPayload(textMessage("wamid.in1", "5491111111111", "hola"))
+
+	app.post(t, payload)
+	_, body := app.post(t, payload)
+	if body["status"] != "duplicate" {
+		t.Errorf("Expected duplicate status, got %v", body["status"])
+	}
+
+	if messages := app.drain(t); len(messages) != 1 {
+		t.Errorf("Expected a single reply, got %d", len(messages))
+	}
+}
+
+func TestWhatsAppHandler_ReprocessesFailedMessages(t *testing.T) {
+	app := newTestApp(t)
+	payload := messagesPayload(
+		`{"id": "wamid.in1", "from": "5491111111111", "timestamp": "1700000000", "type": "image", "image": {"id": "media123", "mime_type": "image/jpeg"}}`,
+	)
+
+	// The media can't be downloaded yet, the conversation is left as it was
+	_, body := app.post(t, payload)
+	if body["messages_processed"] != float64(0) {
+		t.Fatalf("Expected the message to fail, got %v", body)
+	}
+
+	app.graph.AddMedia("media123", "image/jpeg", []byte("jpeg-bytes"))
+	_, body = app.post(t, payload)
+	if body["messages_duplicate"] != float64(0) || body["messages_processed"] != float64(1) {
+		t.Errorf("Expected the redelivery of a failed message to be processed again, got %v", body)
+	}
+}
+
+func TestWhatsAppHandler_ResendsUnqueuedReplies(t *testing.T) {
+	app := newTestApp(t)
+	app.post(t, messagesPayload(textMessage("wamid.in1", "5491111111111", "hola")))
+
+	// The reply to the choice can't be queued, but the conversation moved on
+	app.replies.failNext(1)
+	payload := messagesPayload(textMessage("wamid.in2", "5491111111111", "A"))
+	if _, body := app.post(t, payload); body["messages_processed"] != float64(0) {
+		t.Fatalf("Expected the reply to fail, got %v", body)
+	}
+	state, _ := app.sessions.GetUserState("5491111111111")
+	advanced := state.State
+
+	// The redelivery sends the reply without going through the flow again
+	_, body := app.post(t, payload)
+	if body["messages_duplicate"] != float64(1) {
+		t.Errorf("Expected the redelivery to be a duplicate, got %v", body)
+	}
+	state, _ = app.sessions.GetUserState("5491111111111")
+	if state.State != advanced || state.State == "welcome" {
+		t.Errorf("Expected the state to advance once to %s, got %s", advanced, state.State)
+	}
+
+	// Further redeliveries are plain duplicates
+	app.post(t, payload)
+
+	messages := app.drain(t)
+	if len(messages) != 2 || messages[1].Text == nil || messages[0].Text.Body == messages[1].Text.Body {
+		t.Fatalf("Expected the welcome menu and the reply to the choice once, got %+v", messages)
+	}
+}
+
+func TestWhatsAppHandler_FollowsConversation(t *testing.T) {
+	app := newTestApp(t)
+
+	app.post(t, messagesPayload(textMessage("wamid.in1", "5491111111111", "hola")))
+	app.post(t, messagesPayload(textMessage("wamid.in2", "5491111111111", "D")))
+
+	messages := app.drain(t)
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 replies, got %d", len(messages))
+	}
+	if messages[0].Text.Body == messages[1].Text.Body {
+		t.Errorf("Expected option D to answer something other than the menu, got %q twice", messages[0].Text.Body)
+	}
+
+	transcript, _ := app.transcripts.ListByUser("5491111111111")
+	if len(transcript) != 4 {
+		t.Fatalf("Expected 2 inbound and 2 outbound transcript entries, got %d", len(transcript))
+	}
+
+	// Replies are recorded under the ID the Graph API assigned once it accepts them
+	outboundIDs := map[string]bool{}
+	for _, entry := range transcript {
+		if entry.Direction == models.DirectionOutbound {
+			outboundIDs[entry.MessageID] = true
+		}
+	}
+	if !outboundIDs[messages[0].ID] || !outboundIDs[messages[1].ID] {
+		t.Errorf("Expected both replies recorded with their message IDs, got %v", outboundIDs)
+	}
+}
+
+func TestWhatsAppHandler_RecordsRejectedReplies(t *testing.T) {
+	app := newTestApp(t)
+	app.graph.FailNext(http.StatusBadRequest, "Invalid parameter")
+
+	app.post(t, messagesPayload(textMessage("wamid.in1", "5491111111111", "hola")))
+	if messages := app.drain(t); len(messages) != 0 {
+		t.Fatalf("Expected the reply to be rejected, got %d messages", len(messages))
+	}
+
+	transcript, _ := app.transcripts.ListByUser("5491111111111")
+	if len(transcript) != 2 {
+		t.Fatalf("Expected the message and the rejected reply in the transcript, got %+v", transcript)
+	}
+	reply := transcript[1]
+	if reply.Direction != models.DirectionOutbound || reply.Status != models.DeliveryFailed || !strings.Contains(reply.Text, "seleccioná una opción") {
+		t.Errorf("Expected the rejected reply recorded as failed, got %+v", reply)
+	}
+}
+
+func TestWhatsAppHandler_StoresStatuses(t *testing.T) {
+	app := newTestApp(t)
+
+	code, body := app.post(t, `{"object": "whatsapp_business_account", "entry": [{"id": "1", "changes": [{"field": "messages", "value": {
+		"messaging_product": "whatsapp", "statuses": [
+			{"id": "wamid.out1", "recipient_id": "5491111111111", "status": "delivered", "timestamp": "1700000000"},
+			{"id": "wamid.out2", "recipient_id": "5491111111111", "status": "failed", "timestamp": "1700000005",
+			 "errors": [{"code": 131047, "title": "Re-engagement message"}]}
+		]}}]}]}`)
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if body["statuses_processed"] != float64(2) {
+		t.Errorf("Expected 2 processed statuses, got %v", body["statuses_processed"])
+	}
+
+	failed, err := app.statuses.ListFailed()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(failed) != 1 || failed[0].MessageID != "wamid.out2" || failed[0].ErrorCode != 131047 {
+		t.Errorf("Expected wamid.out2 to have failed with code 131047, got %+v", failed)
+	}
+	if !failed[0].UpdatedAt.Equal(time.Unix(1700000005, 0)) {
+		t.Errorf("Expected the webhook timestamp, got %v", failed[0].UpdatedAt)
+	}
+
+	if messages := app.drain(t); len(messages) != 0 {
+		t.Errorf("Expected no reply to status callbacks, got %d", len(messages))
+	}
+}
+
+func TestWhatsAppHandler_ServiceWindowOutlivesSession(t *testing.T) {
+	app := newTestApp(t)
+	app.post(t, messagesPayload(
+		textMessage("wamid.in1", "5491111111111", "hola"),
+		textMessage("wamid.in2", "5491122222222", "hola"),
+	))
+
+	// The first session expires and staff deletes the second one, both
+	// patients wrote less than 24 hours ago
+	state, _ := app.sessions.GetUserState("5491111111111")
+	expired := *state
+	expired.UpdatedAt = time.Now().Add(-25 * time.Hour)
+	app.sessions.SaveUserState(&expired)
+	if rec := app.admin(t, http.MethodDelete, "/sessions/5491122222222", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", rec.Code)
+	}
+
+	for _, response := range []*models.WhatsAppResponse{
+		{MessagingProduct: "whatsapp", To: "5491111111111", Type: "text", Text: models.TextContent{Body: "Recordatorio"}},
+		{MessagingProduct: "whatsapp", To: "5491122222222", Type: "text", Text: models.TextContent{Body: "Recordatorio"}},
+		{MessagingProduct: "whatsapp", To: "5491133333333", Type: "text", Text: models.TextContent{Body: "Recordatorio"}},
+		{MessagingProduct: "whatsapp", To: "5493439999999", Type: "text", Text: models.TextContent{Body: "Nueva solicitud"}, StaffNotification: true},
+	} {
+		if err := app.queue.Enqueue(response); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	sent := map[string]string{}
+	for _, message := range app.drain(t) {
+		// Skip the replies to the first messages
+		if message.Type == "text" && strings.Contains(message.Text.Body, "seleccioná una opción") {
+			continue
+		}
+		sent[message.To] = message.Type
+	}
+	expected := map[string]string{
+		"5491111111111": "text",
+		"5491122222222": "text",
+		"5491133333333": "template", // Never wrote to us
+		"5493439999999": "text",     // Staff notifications are never replaced
+	}
+	for to, messageType := range expected {
+		if sent[to] != messageType {
+			t.Errorf("Expected a %s message to %s, got %q", messageType, to, sent[to])
+		}
+	}
+
+	// Looking up the window doesn't start a session for the staff number
+	if states, _ := app.sessions.ListUserStates(); len(states) != 0 {
+		t.Errorf("Expected no active session, got %d", len(states))
+	}
+}
+
+func TestWhatsAppHandler_DownloadsMedia(t *testing.T) {
+	app := newTestApp(t)
+	app.graph.AddMedia("media123", "image/jpeg", []byte("jpeg-bytes"))
+
+	_, body := app.post(t, messagesPayload(
+		`{"id": "wamid.in1", "from": "5491111111111", "timestamp": "1700000000", "type": "image", "image": {"id": "media123", "mime_type": "image/jpeg"}}`,
+	))
+	if body["messages_processed"] != float64(1) {
+		t.Errorf("Expected the image to be processed, got %v (%v)", body["messages_processed"], body["errors"])
+	}
+
+	transcript, _ := app.transcripts.ListByUser("5491111111111")
+	if len(transcript) == 0 || transcript[0].MediaRef == "" {
+		t.Errorf("Expected the stored media in the transcript, got %+v", transcript)
+	}
+}
+
+func TestWhatsAppHandler_RejectsInvalidJSON(t *testing.T) {
+	app := newTestApp(t)
+
+	code, _ := app.post(t, `{"entry": [`)
+	if code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", code)
+	}
+	if messages := app.drain(t); len(messages) != 0 {
+		t.Errorf("Expected nothing sent, got %d", len(messages))
+	}
+}
+
+func TestWhatsAppHandler_VerifyWebhook(t *testing.T) {
+	app := newTestApp(t)
+
+	tests := []struct {
+		token    string
+		expected int
+	}{
+		{"verify-token", http.StatusOK},
+		{"wrong-token", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/whatsapp/webhook?hub.mode=subscribe&hub.verify_token="+tt.token+"&hub.challenge=42", nil)
+		rec := httptest.NewRecorder()
+		app.router.ServeHTTP(rec, req)
+
+		if rec.Code != tt.expected {
+			t.Errorf("Expected status %d for token %s, got %d", tt.expected, tt.token, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"chatbot-wsp/internal/infrastructure/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AdminAuth rejects requests that don't carry the admin token as a bearer token
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		received, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !found || token == "" || subtle.ConstantTimeCompare([]byte(received), []byte(token)) != 1 {
+			logger.GetLogger().WithFields(logrus.Fields{
+				"path":      c.Request.URL.Path,
+				"client_ip": c.ClientIP(),
+			}).Warn("Unauthorized admin request")
+
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"status": "error",
+				"error":  "Unauthorized",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
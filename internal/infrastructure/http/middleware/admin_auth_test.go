@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminAuth(t *testing.T) {
+	tests := []struct {
+		name           string
+		token          string
+		authorization  string
+		expectedStatus int
+	}{
+		{
+			name:           "Valid token",
+			token:          "admin_token",
+			authorization:  "Bearer admin_token",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Missing header",
+			token:          "admin_token",
+			authorization:  "",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Wrong token",
+			token:          "admin_token",
+			authorization:  "Bearer other_token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Missing bearer scheme",
+			token:          "admin_token",
+			authorization:  "admin_token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Empty configured token rejects everything",
+			token:          "",
+			authorization:  "Bearer ",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.GET("/admin", AdminAuth(tt.token), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
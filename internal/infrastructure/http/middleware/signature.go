@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+
+	"chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/infrastructure/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// SignatureHeader is the header Meta uses to sign webhook payloads
+const SignatureHeader = "X-Hub-Signature-256"
+
+// maxWebhookBodyBytes bounds the payload read for signature verification
+const maxWebhookBodyBytes = 1 << 20
+
+// WebhookSignature rejects requests whose body is not signed with the app secret.
+// The raw body is restored so handlers can still bind it.
+func WebhookSignature(appSecret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBodyBytes))
+		if err != nil {
+			logger.GetLogger().WithError(err).Error("Failed to read webhook body")
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"status": "error",
+				"error":  "Invalid body",
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		if err := VerifySignature(appSecret, body, c.GetHeader(SignatureHeader)); err != nil {
+			logger.GetLogger().WithFields(logrus.Fields{
+				"error":     err.Error(),
+				"client_ip": c.ClientIP(),
+			}).Warn("Webhook signature verification failed")
+
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"status": "error",
+				"error":  err.Error(),
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// VerifySignature checks a "sha256=<hex>" signature of body against the app secret
+func VerifySignature(appSecret string, body []byte, signature string) error {
+	if signature == "" {
+		return errors.ErrMissingSignature
+	}
+
+	digest, found := strings.CutPrefix(signature, "sha256=")
+	if !found || appSecret == "" {
+		return errors.ErrInvalidSignature
+	}
+
+	received, err := hex.DecodeString(digest)
+	if err != nil {
+		return errors.ErrInvalidSignature
+	}
+
+	if !hmac.Equal(received, ComputeSignature(appSecret, body)) {
+		return errors.ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// ComputeSignature returns the HMAC-SHA256 of body using the app secret
+func ComputeSignature(appSecret string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(appSecret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
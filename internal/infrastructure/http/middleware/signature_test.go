@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	domainerrors "chatbot-wsp/internal/domain/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testAppSecret = "test_app_secret"
+
+func loadFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Failed to read fixture %s: %v", name, err)
+	}
+	return data
+}
+
+func newSignedRouter(received *[]byte) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/webhook", WebhookSignature(testAppSecret), func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		*received = body
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestWebhookSignature(t *testing.T) {
+	payload := loadFixture(t, "text_message.json")
+	signature := string(loadFixture(t, "text_message.json.sig"))
+
+	tampered := bytes.Replace(payload, []byte(`"body": "A"`), []byte(`"body": "B"`), 1)
+
+	tests := []struct {
+		name           string
+		body           []byte
+		signature      string
+		expectedStatus int
+	}{
+		{
+			name:           "Valid signature",
+			body:           payload,
+			signature:      signature,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Missing signature",
+			body:           payload,
+			signature:      "",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Tampered body",
+			body:           tampered,
+			signature:      signature,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Signed with another secret",
+			body:           payload,
+			signature:      "sha256=" + hex.EncodeToString(ComputeSignature("other_secret", payload)),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Missing sha256 prefix",
+			body:           payload,
+			signature:      signature[len("sha256="):],
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Malformed digest",
+			body:           payload,
+			signature:      "sha256=not-hex",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received []byte
+			router := newSignedRouter(&received)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.signature != "" {
+				req.Header.Set(SignatureHeader, tt.signature)
+			}
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+
+			if tt.expectedStatus == http.StatusOK && !bytes.Equal(received, tt.body) {
+				t.Errorf("Expected handler to receive the original body")
+			}
+
+			if tt.expectedStatus != http.StatusOK && received != nil {
+				t.Errorf("Expected handler not to be called")
+			}
+		})
+	}
+}
+
+func TestVerifySignature_Errors(t *testing.T) {
+	payload := loadFixture(t, "text_message.json")
+
+	if err := VerifySignature(testAppSecret, payload, ""); !errors.Is(err, domainerrors.ErrMissingSignature) {
+		t.Errorf("Expected ErrMissingSignature, got %v", err)
+	}
+
+	if err := VerifySignature(testAppSecret, payload, "sha256=00"); !errors.Is(err, domainerrors.ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature, got %v", err)
+	}
+
+	signature := string(loadFixture(t, "text_message.json.sig"))
+	if err := VerifySignature("", payload, signature); !errors.Is(err, domainerrors.ErrInvalidSignature) {
+		t.Errorf("Expected an empty secret to reject every signature, got %v", err)
+	}
+}
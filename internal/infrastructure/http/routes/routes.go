@@ -7,8 +7,15 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// Config holds configuration for the routes
+type Config struct {
+	AppSecret     string // Secret used to verify webhook signatures
+	SkipSignature bool   // Accept unsigned webhooks, for local development only
+	AdminToken    string // Bearer token for the admin API; empty disables it
+}
+
 // SetupRoutes configures all routes for the application
-func SetupRoutes(whatsappHandler *handlers.WhatsAppHandler) *gin.Engine {
+func SetupRoutes(whatsappHandler *handlers.WhatsAppHandler, adminHandler *handlers.AdminHandler, metricsHandler *handlers.MetricsHandler, config *Config) *gin.Engine {
 	// Set Gin to release mode for production
 	gin.SetMode(gin.ReleaseMode)
 
@@ -22,14 +29,19 @@ func SetupRoutes(whatsappHandler *handlers.WhatsAppHandler) *gin.Engine {
 	// Health check endpoint
 	router.GET("/health", whatsappHandler.HealthCheck)
 
-	// Stats endpoint
-	router.GET("/stats", whatsappHandler.GetStats)
+	// Stats and Prometheus endpoints
+	router.GET("/stats", metricsHandler.GetStats)
+	router.GET("/metrics", metricsHandler.GetMetrics)
 
 	// WhatsApp webhook endpoints
 	whatsapp := router.Group("/whatsapp")
 	{
 		whatsapp.GET("/webhook", whatsappHandler.VerifyWebhook)
-		whatsapp.POST("/webhook", whatsappHandler.HandleWebhook)
+		if config.SkipSignature {
+			whatsapp.POST("/webhook", whatsappHandler.HandleWebhook)
+		} else {
+			whatsapp.POST("/webhook", middleware.WebhookSignature(config.AppSecret), whatsappHandler.HandleWebhook)
+		}
 		whatsapp.GET("/welcome", whatsappHandler.GetWelcomeMessage)
 	}
 
@@ -37,7 +49,26 @@ func SetupRoutes(whatsappHandler *handlers.WhatsAppHandler) *gin.Engine {
 	api := router.Group("/api/v1")
 	{
 		api.GET("/health", whatsappHandler.HealthCheck)
-		api.GET("/stats", whatsappHandler.GetStats)
+		api.GET("/stats", metricsHandler.GetStats)
+
+		// Admin endpoints, only exposed when a token is configured
+		if config.AdminToken != "" {
+			admin := api.Group("/admin", middleware.AdminAuth(config.AdminToken))
+			{
+				admin.GET("/sessions", adminHandler.ListSessions)
+				admin.GET("/sessions/:user_id", adminHandler.GetSession)
+				admin.PUT("/sessions/:user_id/state", adminHandler.SetState)
+				admin.DELETE("/sessions/:user_id", adminHandler.DeleteSession)
+				admin.GET("/sessions/:user_id/transcript", adminHandler.ExportTranscript)
+				admin.POST("/sessions/:user_id/handoff", adminHandler.StartHandoff)
+				admin.DELETE("/sessions/:user_id/handoff", adminHandler.EndHandoff)
+				admin.GET("/messages/failed", adminHandler.ListFailedMessages)
+				admin.GET("/messages/dead-letters", adminHandler.ListDeadLetters)
+				admin.GET("/messages/:message_id", adminHandler.GetMessageStatus)
+				admin.GET("/appointments", adminHandler.ListAppointments)
+				admin.DELETE("/appointments/:appointment_id", adminHandler.CancelAppointment)
+			}
+		}
 	}
 
 	return router
@@ -0,0 +1,76 @@
+package media
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BlobStore persists media files and returns a reference to retrieve them
+type BlobStore interface {
+	Put(key string, content io.Reader) (string, int64, error)
+	Open(ref string) (io.ReadCloser, error)
+}
+
+// LocalBlobStore stores media files on the local disk
+type LocalBlobStore struct {
+	dir string
+}
+
+// NewLocalBlobStore creates a blob store rooted at dir
+func NewLocalBlobStore(dir string) *LocalBlobStore {
+	return &LocalBlobStore{dir: dir}
+}
+
+// Put writes content under key and returns its reference and size
+func (s *LocalBlobStore) Put(key string, content io.Reader) (string, int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", 0, fmt.Errorf("failed to create media directory: %v", err)
+	}
+
+	// Write to a temporary file first so a failed download never leaves a partial file behind
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create media file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	size, err := io.Copy(tmp, content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to write media file: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, fmt.Errorf("failed to store media file: %v", err)
+	}
+
+	return key, size, nil
+}
+
+// Open returns the content stored under ref
+func (s *LocalBlobStore) Open(ref string) (io.ReadCloser, error) {
+	path, err := s.path(ref)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// path maps a key to a file inside the store directory, rejecting keys escaping it
+func (s *LocalBlobStore) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if clean == "." || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid media key %q", key)
+	}
+	return filepath.Join(s.dir, clean), nil
+}
@@ -0,0 +1,100 @@
+package media
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/infrastructure/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxMediaBytes is the largest file WhatsApp accepts (documents), anything bigger is truncated
+const maxMediaBytes = 100 << 20
+
+// Source fetches the content of media objects received in webhooks
+type Source interface {
+	DownloadMedia(mediaID string) (io.ReadCloser, string, error)
+}
+
+// Downloader copies media received from patients into a blob store
+type Downloader struct {
+	source Source
+	store  BlobStore
+}
+
+// NewDownloader creates a new media downloader
+func NewDownloader(source Source, store BlobStore) *Downloader {
+	return &Downloader{
+		source: source,
+		store:  store,
+	}
+}
+
+// Download fetches the media attached to a message and stores it
+func (d *Downloader) Download(message *models.WhatsAppMessage) (*models.MediaReference, error) {
+	if message.Media == nil || message.Media.ID == "" {
+		return nil, fmt.Errorf("message %s has no media", message.ID)
+	}
+
+	content, mimeType, err := d.source.DownloadMedia(message.Media.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download media: %v", err)
+	}
+	defer content.Close()
+
+	if mimeType == "" {
+		mimeType = message.Media.MimeType
+	}
+
+	key := path.Join(sanitize(message.From), sanitize(message.Media.ID)+extension(mimeType, message.Media.Filename))
+	ref, size, err := d.store.Put(key, io.LimitReader(content, maxMediaBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	logger.GetLogger().WithFields(logrus.Fields{
+		"from":      message.From,
+		"media_id":  message.Media.ID,
+		"ref":       ref,
+		"size":      size,
+		"mime_type": mimeType,
+	}).Info("Media stored")
+
+	return &models.MediaReference{
+		Ref:      ref,
+		MimeType: mimeType,
+		Filename: message.Media.Filename,
+		Caption:  message.Media.Caption,
+		Size:     size,
+	}, nil
+}
+
+// extension picks a file extension from the original filename or the MIME type
+func extension(mimeType, filename string) string {
+	if ext := path.Ext(filename); ext != "" {
+		return sanitize(ext)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(mimeType)
+	if extensions, err := mime.ExtensionsByType(mediaType); err == nil && len(extensions) > 0 {
+		return extensions[0]
+	}
+	return ""
+}
+
+// sanitize keeps only characters safe to use in a file name
+func sanitize(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, value)
+}
@@ -0,0 +1,75 @@
+package media
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+// fakeSource serves media content from memory
+type fakeSource struct {
+	content  map[string]string
+	mimeType string
+}
+
+func (s *fakeSource) DownloadMedia(mediaID string) (io.ReadCloser, string, error) {
+	content, exists := s.content[mediaID]
+	if !exists {
+		return nil, "", fmt.Errorf("media %s not found", mediaID)
+	}
+	return io.NopCloser(strings.NewReader(content)), s.mimeType, nil
+}
+
+func TestDownloader_StoresMedia(t *testing.T) {
+	store := NewLocalBlobStore(t.TempDir())
+	downloader := NewDownloader(&fakeSource{
+		content:  map[string]string{"123456": "%PDF-1.4 estudio"},
+		mimeType: "application/pdf",
+	}, store)
+
+	reference, err := downloader.Download(&models.WhatsAppMessage{
+		ID:   "wamid.1",
+		From: "5493430000000",
+		Type: "document",
+		Media: &models.WebhookMedia{
+			ID:       "123456",
+			MimeType: "application/pdf",
+			Filename: "ecografia.pdf",
+			Caption:  "Ecografía de ayer",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if reference.Ref != "5493430000000/123456.pdf" {
+		t.Errorf("Unexpected reference %s", reference.Ref)
+	}
+	if reference.Size != int64(len("%PDF-1.4 estudio")) || reference.Caption != "Ecografía de ayer" {
+		t.Errorf("Unexpected reference metadata %+v", reference)
+	}
+
+	stored, err := store.Open(reference.Ref)
+	if err != nil {
+		t.Fatalf("Failed to open stored media: %v", err)
+	}
+	defer stored.Close()
+
+	content, _ := io.ReadAll(stored)
+	if string(content) != "%PDF-1.4 estudio" {
+		t.Errorf("Unexpected stored content %q", content)
+	}
+}
+
+func TestLocalBlobStore_RejectsKeysOutsideTheStore(t *testing.T) {
+	store := NewLocalBlobStore(t.TempDir())
+
+	for _, key := range []string{"../escape.txt", "/etc/passwd", "a/../../escape.txt", ".."} {
+		if _, _, err := store.Put(key, strings.NewReader("x")); err == nil {
+			t.Errorf("Expected key %q to be rejected", key)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"io"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+// Metrics collects the counters reported by /stats and /metrics. A nil
+// *Metrics is valid and records nothing.
+type Metrics struct {
+	registry  *Registry
+	startedAt time.Time
+
+	messagesReceived  *CounterVec
+	messagesProcessed *CounterVec
+	messagesFailed    *CounterVec
+	messagesSent      *CounterVec
+	messageStatuses   *CounterVec
+	stateTransitions  *CounterVec
+
+	activeSessions func() (int, error)
+}
+
+// New creates the application metrics
+func New() *Metrics {
+	registry := NewRegistry()
+
+	m := &Metrics{
+		registry:  registry,
+		startedAt: time.Now(),
+		messagesReceived: registry.Counter("chatbot_messages_received_total",
+			"Incoming WhatsApp messages by type.", "type"),
+		messagesProcessed: registry.Counter("chatbot_messages_processed_total",
+			"Incoming WhatsApp messages processed successfully by type.", "type"),
+		messagesFailed: registry.Counter("chatbot_messages_failed_total",
+			"Incoming WhatsApp messages that failed to process by type.", "type"),
+		messagesSent: registry.Counter("chatbot_messages_sent_total",
+			"Send attempts to the WhatsApp API by HTTP status code.", "status"),
+		messageStatuses: registry.Counter("chatbot_message_statuses_total",
+			"Delivery status callbacks for sent messages by status.", "status"),
+		stateTransitions: registry.Counter("chatbot_state_transitions_total",
+			"Conversation state transitions.", "from", "to"),
+	}
+
+	registry.GaugeFunc("chatbot_uptime_seconds", "Seconds since the service started.", func() (float64, error) {
+		return time.Since(m.startedAt).Seconds(), nil
+	})
+	registry.GaugeFunc("chatbot_active_sessions", "Conversations that have not expired.", func() (float64, error) {
+		count, err := m.countActiveSessions()
+		return float64(count), err
+	})
+
+	return m
+}
+
+// SetActiveSessions sets the function used to count active sessions
+func (m *Metrics) SetActiveSessions(count func() (int, error)) {
+	if m == nil {
+		return
+	}
+	m.activeSessions = count
+}
+
+// MessageReceived counts an incoming message
+func (m *Metrics) MessageReceived(messageType string) {
+	if m == nil {
+		return
+	}
+	m.messagesReceived.Inc(messageType)
+}
+
+// MessageProcessed counts a message processed successfully
+func (m *Metrics) MessageProcessed(messageType string) {
+	if m == nil {
+		return
+	}
+	m.messagesProcessed.Inc(messageType)
+}
+
+// MessageFailed counts a message that could not be processed
+func (m *Metrics) MessageFailed(messageType string) {
+	if m == nil {
+		return
+	}
+	m.messagesFailed.Inc(messageType)
+}
+
+// MessageSent counts a send attempt by its HTTP status code, or "error"
+// when the API could not be reached
+func (m *Metrics) MessageSent(status string) {
+	if m == nil {
+		return
+	}
+	m.messagesSent.Inc(status)
+}
+
+// MessageStatus counts a delivery status callback (sent, delivered, read or failed)
+func (m *Metrics) MessageStatus(status string) {
+	if m == nil {
+		return
+	}
+	m.messageStatuses.Inc(status)
+}
+
+// StateTransition counts a conversation moving between states
+func (m *Metrics) StateTransition(from, to string) {
+	if m == nil {
+		return
+	}
+	m.stateTransitions.Inc(from, to)
+}
+
+// Stats summarizes the metrics for the /stats endpoint
+type Stats struct {
+	Uptime            string             `json:"uptime"`
+	UptimeSeconds     float64            `json:"uptime_seconds"`
+	ActiveSessions    *int               `json:"active_sessions"`
+	ActiveSessionsErr string             `json:"active_sessions_error,omitempty"`
+	MessagesReceived  float64            `json:"messages_received"`
+	MessagesProcessed float64            `json:"messages_processed"`
+	MessagesFailed    float64            `json:"messages_failed"`
+	ReceivedByType    map[string]float64 `json:"received_by_type"`
+	FailedByType      map[string]float64 `json:"failed_by_type"`
+	SentByStatus      map[string]float64 `json:"sent_by_status"`
+	DeliveryStatuses  map[string]float64 `json:"delivery_statuses"`
+	DeliveryFailures  float64            `json:"delivery_failures"`
+	StateTransitions  map[string]float64 `json:"state_transitions"`
+}
+
+// Stats returns a snapshot of the metrics
+func (m *Metrics) Stats() *Stats {
+	uptime := time.Since(m.startedAt)
+	statuses := m.messageStatuses.Values()
+
+	stats := &Stats{
+		Uptime:            uptime.Round(time.Second).String(),
+		UptimeSeconds:     uptime.Seconds(),
+		MessagesReceived:  m.messagesReceived.Total(),
+		MessagesProcessed: m.messagesProcessed.Total(),
+		MessagesFailed:    m.messagesFailed.Total(),
+		ReceivedByType:    m.messagesReceived.Values(),
+		FailedByType:      m.messagesFailed.Values(),
+		SentByStatus:      m.messagesSent.Values(),
+		DeliveryStatuses:  statuses,
+		DeliveryFailures:  statuses[models.DeliveryFailed],
+		StateTransitions:  m.stateTransitions.Values(),
+	}
+
+	// Report an unknown count rather than no sessions when counting fails
+	if activeSessions, err := m.countActiveSessions(); err != nil {
+		stats.ActiveSessionsErr = err.Error()
+	} else {
+		stats.ActiveSessions = &activeSessions
+	}
+	return stats
+}
+
+// WritePrometheus writes the metrics in the Prometheus text format
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	return m.registry.WritePrometheus(w)
+}
+
+func (m *Metrics) countActiveSessions() (int, error) {
+	if m.activeSessions == nil {
+		return 0, nil
+	}
+	return m.activeSessions()
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMetrics_WritePrometheus(t *testing.T) {
+	m := New()
+	m.SetActiveSessions(func() (int, error) { return 3, nil })
+
+	m.MessageReceived("text")
+	m.MessageReceived("text")
+	m.MessageReceived("image")
+	m.MessageProcessed("text")
+	m.MessageFailed("image")
+	m.MessageSent("200")
+	m.MessageSent("429")
+	m.StateTransition("welcome", "option_a")
+
+	var out strings.Builder
+	if err := m.WritePrometheus(&out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"# TYPE chatbot_messages_received_total counter",
+		`chatbot_messages_received_total{type="image"} 1`,
+		`chatbot_messages_received_total{type="text"} 2`,
+		`chatbot_messages_failed_total{type="image"} 1`,
+		`chatbot_messages_sent_total{status="429"} 1`,
+		`chatbot_state_transitions_total{from="welcome",to="option_a"} 1`,
+		"# TYPE chatbot_active_sessions gauge",
+		"chatbot_active_sessions 3",
+		"chatbot_uptime_seconds ",
+	}
+	for _, line := range expected {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestMetrics_Stats(t *testing.T) {
+	m := New()
+	m.SetActiveSessions(func() (int, error) { return 2, nil })
+	m.MessageReceived("text")
+	m.MessageProcessed("text")
+	m.StateTransition("welcome", "option_b")
+	m.MessageStatus("delivered")
+	m.MessageStatus("failed")
+
+	stats := m.Stats()
+	if stats.MessagesReceived != 1 || stats.MessagesProcessed != 1 || stats.ActiveSessions == nil || *stats.ActiveSessions != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if stats.StateTransitions["welcome/option_b"] != 1 {
+		t.Errorf("Expected transition welcome/option_b, got %v", stats.StateTransitions)
+	}
+	if stats.DeliveryFailures != 1 || stats.DeliveryStatuses["delivered"] != 1 {
+		t.Errorf("Expected one delivered and one failed message, got %v", stats.DeliveryStatuses)
+	}
+}
+
+func TestMetrics_FailingGaugeIsSkipped(t *testing.T) {
+	m := New()
+	m.SetActiveSessions(func() (int, error) { return 0, errors.New("redis unavailable") })
+
+	var out strings.Builder
+	if err := m.WritePrometheus(&out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(out.String(), "chatbot_active_sessions") {
+		t.Errorf("Expected the failing gauge to be skipped, got:\n%s", out.String())
+	}
+
+	// The stats report the count as unknown instead of no sessions
+	stats := m.Stats()
+	if stats.ActiveSessions != nil || stats.ActiveSessionsErr != "redis unavailable" {
+		t.Errorf("Expected an unknown session count with its error, got %v %q", stats.ActiveSessions, stats.ActiveSessionsErr)
+	}
+}
+
+func TestMetrics_NilIsNoop(t *testing.T) {
+	var m *Metrics
+	m.MessageReceived("text")
+	m.StateTransition("welcome", "option_a")
+	m.SetActiveSessions(nil)
+}
@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"chatbot-wsp/internal/infrastructure/logger"
+)
+
+// Registry holds counters and gauges and renders them in the Prometheus
+// text exposition format
+type Registry struct {
+	mutex    sync.RWMutex
+	counters []*CounterVec
+	gauges   []*gaugeFunc
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// CounterVec is a counter partitioned by label values
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mutex  sync.RWMutex
+	values map[string]float64
+}
+
+// gaugeFunc is a gauge whose value is read when metrics are collected
+type gaugeFunc struct {
+	name string
+	help string
+	fn   func() (float64, error)
+}
+
+// Counter registers a counter with the given label names
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	counter := &CounterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]float64),
+	}
+
+	r.mutex.Lock()
+	r.counters = append(r.counters, counter)
+	r.mutex.Unlock()
+
+	return counter
+}
+
+// GaugeFunc registers a gauge computed by fn on every collection
+func (r *Registry) GaugeFunc(name, help string, fn func() (float64, error)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.gauges = append(r.gauges, &gaugeFunc{name: name, help: help, fn: fn})
+}
+
+// Inc adds one to the counter for the given label values
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds delta to the counter for the given label values
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.values[key] += delta
+}
+
+// Values returns the counter values keyed by their label values joined with "/"
+func (c *CounterVec) Values() map[string]float64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	values := make(map[string]float64, len(c.values))
+	for key, value := range c.values {
+		values[strings.ReplaceAll(key, "\xff", "/")] = value
+	}
+	return values
+}
+
+// Total returns the sum of the counter over every label value
+func (c *CounterVec) Total() float64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var total float64
+	for _, value := range c.values {
+		total += value
+	}
+	return total
+}
+
+// WritePrometheus writes every metric in the Prometheus text format
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, counter := range r.counters {
+		if err := counter.write(w); err != nil {
+			return err
+		}
+	}
+
+	for _, gauge := range r.gauges {
+		value, err := gauge.fn()
+		if err != nil {
+			// Skip the gauge rather than failing the whole scrape
+			logger.GetLogger().WithError(err).WithField("gauge", gauge.name).Warn("Failed to read gauge")
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n",
+			gauge.name, gauge.help, gauge.name, gauge.name, formatValue(value)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *CounterVec) write(w io.Writer) error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", c.name, c.formatLabels(key), formatValue(c.values[key])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// formatLabels renders the label set of a stored key, e.g. {type="text"}
+func (c *CounterVec) formatLabels(key string) string {
+	if len(c.labels) == 0 {
+		return ""
+	}
+
+	values := strings.Split(key, "\xff")
+	pairs := make([]string, len(c.labels))
+	for i, label := range c.labels {
+		var value string
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs[i] = fmt.Sprintf("%s=%q", label, value)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	return fmt.Sprintf("%g", value)
+}
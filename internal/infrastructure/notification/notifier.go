@@ -0,0 +1,97 @@
+package notification
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/service"
+)
+
+// Multi forwards events to several notifiers
+type Multi []service.Notifier
+
+// NotifyRequestCompleted forwards the event to every notifier
+func (m Multi) NotifyRequestCompleted(event *models.RequestCompletedEvent) {
+	for _, notifier := range m {
+		notifier.NotifyRequestCompleted(event)
+	}
+}
+
+// NotifyEmergency forwards the event to every notifier
+func (m Multi) NotifyEmergency(event *models.EmergencyEvent) {
+	for _, notifier := range m {
+		notifier.NotifyEmergency(event)
+	}
+}
+
+// NotifyEscalation forwards the event to every notifier
+func (m Multi) NotifyEscalation(event *models.EscalationEvent) {
+	for _, notifier := range m {
+		notifier.NotifyEscalation(event)
+	}
+}
+
+// FormatSummary builds the message staff receives for a completed request
+func FormatSummary(event *models.RequestCompletedEvent) string {
+	var b strings.Builder
+
+	b.WriteString("📌 Nueva solicitud completada\n")
+	fmt.Fprintf(&b, "Paciente: +%s\n", strings.TrimPrefix(event.UserID, "+"))
+	if event.Option != "" {
+		fmt.Fprintf(&b, "Opción: %s (%s)\n", event.Option, event.State)
+	} else {
+		fmt.Fprintf(&b, "Flujo: %s\n", event.State)
+	}
+	fmt.Fprintf(&b, "Fecha: %s\n", event.CompletedAt.Format("02/01/2006 15:04"))
+	if event.CallbackWindow != nil {
+		if event.CallbackWindow.AfterHours {
+			b.WriteString("🌙 Recibida fuera del horario de atención\n")
+		}
+		fmt.Fprintf(&b, "Responder: %s\n", service.FormatCallbackWindow(event.CallbackWindow))
+	}
+
+	if len(event.Data) > 0 {
+		keys := make([]string, 0, len(event.Data))
+		for key := range event.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		b.WriteString("\n📋 Datos:\n")
+		for _, key := range keys {
+			fmt.Fprintf(&b, "• %s: %s\n", key, event.Data[key])
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// FormatEmergency builds the alert staff receives when a patient describes an emergency
+func FormatEmergency(event *models.EmergencyEvent) string {
+	var b strings.Builder
+
+	b.WriteString("🚨 URGENCIA - contactar al paciente de inmediato\n")
+	fmt.Fprintf(&b, "Paciente: +%s\n", strings.TrimPrefix(event.UserID, "+"))
+	fmt.Fprintf(&b, "Fecha: %s\n", event.DetectedAt.Format("02/01/2006 15:04"))
+	fmt.Fprintf(&b, "Detectado: \"%s\"\n", event.Keyword)
+	fmt.Fprintf(&b, "\nMensaje:\n%s", event.Message)
+
+	return b.String()
+}
+
+// FormatEscalation builds the message staff receives when a conversation is
+// handed to them because the bot could not understand the patient
+func FormatEscalation(event *models.EscalationEvent) string {
+	var b strings.Builder
+
+	b.WriteString("🤝 Conversación derivada - el bot no entendió al paciente\n")
+	fmt.Fprintf(&b, "Paciente: +%s\n", strings.TrimPrefix(event.UserID, "+"))
+	fmt.Fprintf(&b, "Paso: %s\n", event.State)
+	fmt.Fprintf(&b, "Fecha: %s\n", event.EscalatedAt.Format("02/01/2006 15:04"))
+	fmt.Fprintf(&b, "Intentos: %d\n", event.Attempts)
+	fmt.Fprintf(&b, "\nÚltimo mensaje:\n%s", event.LastMessage)
+
+	return b.String()
+}
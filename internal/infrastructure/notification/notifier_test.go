@@ -0,0 +1,133 @@
+package notification
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+// fakeQueue records the messages it is asked to send
+type fakeQueue struct {
+	sent []*models.WhatsAppResponse
+}
+
+func (q *fakeQueue) Enqueue(response *models.WhatsAppResponse) error {
+	q.sent = append(q.sent, response)
+	return nil
+}
+
+func newTestEvent() *models.RequestCompletedEvent {
+	return &models.RequestCompletedEvent{
+		UserID: "5493431234567",
+		State:  "option_a",
+		Option: "A",
+		Data: map[string]string{
+			"nombre": "Juan Pérez",
+			"edad":   "8 meses",
+		},
+		CompletedAt: time.Date(2024, 5, 20, 14, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestFormatSummary(t *testing.T) {
+	summary := FormatSummary(newTestEvent())
+
+	expected := []string{"Nueva solicitud completada", "Paciente: +5493431234567", "Opción: A (option_a)", "20/05/2024 14:30", "• edad: 8 meses\n• nombre: Juan Pérez"}
+	for _, text := range expected {
+		if !strings.Contains(summary, text) {
+			t.Errorf("Expected summary to contain '%s', got: %s", text, summary)
+		}
+	}
+}
+
+func TestFormatSummary_CallbackWindow(t *testing.T) {
+	event := newTestEvent()
+	event.CallbackWindow = &models.CallbackWindow{
+		From:       time.Date(2024, 5, 21, 9, 0, 0, 0, time.UTC),
+		Until:      time.Date(2024, 5, 21, 13, 0, 0, 0, time.UTC),
+		AfterHours: true,
+	}
+
+	summary := FormatSummary(event)
+	for _, text := range []string{"fuera del horario de atención", "Responder: martes 21/05 de 09:00 a 13:00"} {
+		if !strings.Contains(summary, text) {
+			t.Errorf("Expected summary to contain '%s', got: %s", text, summary)
+		}
+	}
+}
+
+func TestFormatEmergency(t *testing.T) {
+	alert := FormatEmergency(&models.EmergencyEvent{
+		UserID:     "5493431234567",
+		Message:    "No respira bien",
+		Keyword:    "no respira",
+		DetectedAt: time.Date(2024, 5, 20, 3, 10, 0, 0, time.UTC),
+	})
+
+	for _, text := range []string{"URGENCIA", "Paciente: +5493431234567", "20/05/2024 03:10", "No respira bien"} {
+		if !strings.Contains(alert, text) {
+			t.Errorf("Expected alert to contain '%s', got: %s", text, alert)
+		}
+	}
+}
+
+func TestFormatEscalation(t *testing.T) {
+	message := FormatEscalation(&models.EscalationEvent{
+		UserID:      "5493431234567",
+		State:       "option_a",
+		Attempts:    3,
+		LastMessage: "no entiendo",
+		EscalatedAt: time.Date(2024, 5, 20, 14, 30, 0, 0, time.UTC),
+	})
+
+	for _, text := range []string{"Conversación derivada", "Paciente: +5493431234567", "Paso: option_a", "Intentos: 3", "no entiendo"} {
+		if !strings.Contains(message, text) {
+			t.Errorf("Expected message to contain '%s', got: %s", text, message)
+		}
+	}
+}
+
+func TestWhatsAppNotifier(t *testing.T) {
+	queue := &fakeQueue{}
+	notifier := NewWhatsAppNotifier(queue, "5493439999999")
+
+	notifier.NotifyRequestCompleted(newTestEvent())
+
+	if len(queue.sent) != 1 {
+		t.Fatalf("Expected 1 message queued, got %d", len(queue.sent))
+	}
+	if queue.sent[0].To != "5493439999999" || !strings.Contains(queue.sent[0].Text.Body, "Juan Pérez") {
+		t.Errorf("Expected summary sent to the staff number, got %+v", queue.sent[0])
+	}
+	if !queue.sent[0].StaffNotification {
+		t.Error("Expected the summary marked as a staff notification")
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	received := make(chan webhookPayload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode payload: %v", err)
+		}
+		received <- payload
+	}))
+	defer server.Close()
+
+	NewWebhookNotifier(server.URL).NotifyRequestCompleted(newTestEvent())
+
+	select {
+	case payload := <-received:
+		if payload.Event != "request_completed" || payload.Request.UserID != "5493431234567" || payload.Request.Data["nombre"] != "Juan Pérez" {
+			t.Errorf("Unexpected payload %+v", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the notification to be posted")
+	}
+}
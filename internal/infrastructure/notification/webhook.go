@@ -0,0 +1,106 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/infrastructure/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Notification priorities
+const (
+	priorityNormal = "normal"
+	priorityHigh   = "high"
+)
+
+// webhookPayload is the JSON body posted for each event
+type webhookPayload struct {
+	Event      string                        `json:"event"`
+	Priority   string                        `json:"priority"`
+	Request    *models.RequestCompletedEvent `json:"request,omitempty"`
+	Emergency  *models.EmergencyEvent        `json:"emergency,omitempty"`
+	Escalation *models.EscalationEvent       `json:"escalation,omitempty"`
+	Summary    string                        `json:"summary"`
+}
+
+// WebhookNotifier posts staff notifications as JSON to a URL
+type WebhookNotifier struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewWebhookNotifier creates a notifier that posts to url
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:        url,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NotifyRequestCompleted posts the event in the background
+func (n *WebhookNotifier) NotifyRequestCompleted(event *models.RequestCompletedEvent) {
+	n.postAsync(event.UserID, event.State, &webhookPayload{
+		Event:    "request_completed",
+		Priority: priorityNormal,
+		Request:  event,
+		Summary:  FormatSummary(event),
+	})
+}
+
+// NotifyEmergency posts the event in the background with high priority
+func (n *WebhookNotifier) NotifyEmergency(event *models.EmergencyEvent) {
+	n.postAsync(event.UserID, event.State, &webhookPayload{
+		Event:     "emergency",
+		Priority:  priorityHigh,
+		Emergency: event,
+		Summary:   FormatEmergency(event),
+	})
+}
+
+// NotifyEscalation posts the event in the background
+func (n *WebhookNotifier) NotifyEscalation(event *models.EscalationEvent) {
+	n.postAsync(event.UserID, event.State, &webhookPayload{
+		Event:      "escalation",
+		Priority:   priorityNormal,
+		Escalation: event,
+		Summary:    FormatEscalation(event),
+	})
+}
+
+func (n *WebhookNotifier) postAsync(userID, state string, payload *webhookPayload) {
+	go func() {
+		if err := n.post(payload); err != nil {
+			logger.GetLogger().WithFields(logrus.Fields{
+				"user_id": userID,
+				"state":   state,
+				"event":   payload.Event,
+				"error":   err.Error(),
+			}).Error("Failed to post notification webhook")
+		}
+	}()
+}
+
+func (n *WebhookNotifier) post(payload *webhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %v", err)
+	}
+
+	resp, err := n.httpClient.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notification webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package notification
+
+import (
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/infrastructure/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MessageQueue schedules WhatsApp messages for delivery
+type MessageQueue interface {
+	Enqueue(response *models.WhatsAppResponse) error
+}
+
+// WhatsAppNotifier sends a summary of each completed request to a staff number
+type WhatsAppNotifier struct {
+	queue       MessageQueue
+	staffNumber string
+}
+
+// NewWhatsAppNotifier creates a notifier that messages staffNumber
+func NewWhatsAppNotifier(queue MessageQueue, staffNumber string) *WhatsAppNotifier {
+	return &WhatsAppNotifier{
+		queue:       queue,
+		staffNumber: staffNumber,
+	}
+}
+
+// NotifyRequestCompleted queues the request summary for the staff number
+func (n *WhatsAppNotifier) NotifyRequestCompleted(event *models.RequestCompletedEvent) {
+	n.send(event.UserID, event.State, FormatSummary(event))
+}
+
+// NotifyEmergency queues an emergency alert for the staff number
+func (n *WhatsAppNotifier) NotifyEmergency(event *models.EmergencyEvent) {
+	n.send(event.UserID, event.State, FormatEmergency(event))
+}
+
+// NotifyEscalation queues a handoff alert for the staff number
+func (n *WhatsAppNotifier) NotifyEscalation(event *models.EscalationEvent) {
+	n.send(event.UserID, event.State, FormatEscalation(event))
+}
+
+func (n *WhatsAppNotifier) send(userID, state, body string) {
+	response := &models.WhatsAppResponse{
+		MessagingProduct:  "whatsapp",
+		To:                n.staffNumber,
+		Type:              "text",
+		StaffNotification: true,
+	}
+	response.Text.Body = body
+
+	if err := n.queue.Enqueue(response); err != nil {
+		logger.GetLogger().WithFields(logrus.Fields{
+			"user_id": userID,
+			"state":   state,
+			"error":   err.Error(),
+		}).Error("Failed to queue staff notification")
+	}
+}
@@ -0,0 +1,229 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
+	"chatbot-wsp/internal/infrastructure/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Queue errors
+var (
+	ErrQueueFull   = errors.New("outbound queue is full")
+	ErrQueueClosed = errors.New("outbound queue is closed")
+)
+
+// Sender delivers a message to WhatsApp, returning the ID the API assigned to it
+type Sender interface {
+	Send(response *models.WhatsAppResponse) (string, error)
+}
+
+// Tracker is told about every message handed to the queue, and whether the
+// WhatsApp API accepted it or it was given up on
+type Tracker interface {
+	MessageQueued(response *models.WhatsAppResponse)
+	MessageAccepted(response *models.WhatsAppResponse, messageID string)
+	MessageFailed(response *models.WhatsAppResponse, err error)
+}
+
+// retryable is implemented by errors that know whether a send may be retried
+type retryable interface {
+	Retryable() bool
+}
+
+// Config holds configuration for the outbound queue
+type Config struct {
+	Workers        int           // Number of concurrent senders
+	QueueSize      int           // Maximum number of messages waiting to be sent
+	MaxAttempts    int           // Attempts before a message is dead-lettered
+	InitialBackoff time.Duration // Delay before the first retry, doubled on each attempt
+	MaxBackoff     time.Duration // Upper bound for the retry delay
+}
+
+// Queue sends messages asynchronously through a pool of workers, retrying
+// transient failures with exponential backoff
+type Queue struct {
+	sender      Sender
+	deadLetters repository.DeadLetterRepository
+	tracker     Tracker
+	window      *WindowFallback
+	config      *Config
+
+	jobs    chan *models.WhatsAppResponse
+	mutex   sync.RWMutex
+	closed  bool
+	abort   chan struct{}
+	workers sync.WaitGroup
+}
+
+// NewQueue creates a new outbound queue
+func NewQueue(sender Sender, deadLetters repository.DeadLetterRepository, config *Config) *Queue {
+	return &Queue{
+		sender:      sender,
+		deadLetters: deadLetters,
+		config:      config,
+		jobs:        make(chan *models.WhatsAppResponse, config.QueueSize),
+		abort:       make(chan struct{}),
+	}
+}
+
+// SetTracker sets the tracker told about queued messages. It must be called before Start.
+func (q *Queue) SetTracker(tracker Tracker) {
+	q.tracker = tracker
+}
+
+// SetWindowFallback sends a template instead of messages to users outside the
+// service window. It must be called before Start.
+func (q *Queue) SetWindowFallback(window *WindowFallback) {
+	q.window = window
+}
+
+// Start launches the worker pool
+func (q *Queue) Start() {
+	for i := 0; i < q.config.Workers; i++ {
+		q.workers.Add(1)
+		go func() {
+			defer q.workers.Done()
+			for response := range q.jobs {
+				q.deliver(response)
+			}
+		}()
+	}
+}
+
+// Enqueue schedules a message for delivery without waiting for it to be sent
+func (q *Queue) Enqueue(response *models.WhatsAppResponse) error {
+	if q.tracker != nil {
+		q.tracker.MessageQueued(response)
+	}
+
+	err := q.push(response)
+	if err != nil && q.tracker != nil {
+		q.tracker.MessageFailed(response, err)
+	}
+	return err
+}
+
+func (q *Queue) push(response *models.WhatsAppResponse) error {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	select {
+	case q.jobs <- response:
+		return nil
+	default:
+		logger.GetLogger().WithField("to", response.To).Error("Outbound queue full - dropping message")
+		return ErrQueueFull
+	}
+}
+
+// Shutdown stops accepting messages and waits for the queued ones to be sent.
+// When ctx expires pending retries are abandoned and dead-lettered.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mutex.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		close(q.abort)
+		<-done
+		return ctx.Err()
+	}
+}
+
+// deliver sends a message, retrying transient failures
+func (q *Queue) deliver(response *models.WhatsAppResponse) {
+	if q.window != nil {
+		response = q.window.Apply(response, time.Now())
+	}
+
+	var err error
+	for attempt := 1; attempt <= q.config.MaxAttempts; attempt++ {
+		var messageID string
+		if messageID, err = q.sender.Send(response); err == nil {
+			if q.tracker != nil {
+				q.tracker.MessageAccepted(response, messageID)
+			}
+			return
+		}
+
+		var r retryable
+		if !errors.As(err, &r) || !r.Retryable() || attempt == q.config.MaxAttempts {
+			q.deadLetter(response, attempt, err)
+			return
+		}
+
+		delay := q.backoff(attempt)
+		logger.GetLogger().WithFields(logrus.Fields{
+			"to":      response.To,
+			"attempt": attempt,
+			"delay":   delay.String(),
+			"error":   err.Error(),
+		}).Warn("Outbound message failed - retrying")
+
+		select {
+		case <-time.After(delay):
+		case <-q.abort:
+			q.deadLetter(response, attempt, err)
+			return
+		}
+	}
+}
+
+// backoff returns the delay before the retry following the given attempt
+func (q *Queue) backoff(attempt int) time.Duration {
+	delay := q.config.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= q.config.MaxBackoff {
+			return q.config.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// deadLetter stores a message that could not be delivered
+func (q *Queue) deadLetter(response *models.WhatsAppResponse, attempts int, err error) {
+	logger.GetLogger().WithFields(logrus.Fields{
+		"to":          response.To,
+		"attempts":    attempts,
+		"error":       err.Error(),
+		"reminder_id": response.ReminderID,
+	}).Error("Outbound message permanently failed")
+
+	letter := &models.DeadLetter{
+		Response:   response,
+		Attempts:   attempts,
+		LastError:  err.Error(),
+		FailedAt:   time.Now(),
+		ReminderID: response.ReminderID,
+	}
+	if storeErr := q.deadLetters.Add(letter); storeErr != nil {
+		logger.GetLogger().WithError(storeErr).Error("Failed to store dead letter")
+	}
+	if q.tracker != nil {
+		q.tracker.MessageFailed(response, err)
+	}
+}
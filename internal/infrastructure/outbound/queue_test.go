@@ -0,0 +1,187 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
+)
+
+// statusError mimics the WhatsApp client's API errors
+type statusError struct {
+	status int
+}
+
+func (e *statusError) Error() string   { return "status error" }
+func (e *statusError) Retryable() bool { return e.status == 429 || e.status >= 500 }
+
+// fakeSender fails with the configured errors before succeeding
+type fakeSender struct {
+	mutex    sync.Mutex
+	failures []error
+	calls    int
+	sent     []*models.WhatsAppResponse
+	block    chan struct{}
+}
+
+func (s *fakeSender) Send(response *models.WhatsAppResponse) (string, error) {
+	if s.block != nil {
+		<-s.block
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.calls++
+	if len(s.failures) > 0 {
+		err := s.failures[0]
+		s.failures = s.failures[1:]
+		return "", err
+	}
+	s.sent = append(s.sent, response)
+	return fmt.Sprintf("wamid.%d", len(s.sent)), nil
+}
+
+func newTestQueue(sender Sender, deadLetters repository.DeadLetterRepository, queueSize int) *Queue {
+	return NewQueue(sender, deadLetters, &Config{
+		Workers:        1,
+		QueueSize:      queueSize,
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	})
+}
+
+func newResponse(to string) *models.WhatsAppResponse {
+	response := &models.WhatsAppResponse{MessagingProduct: "whatsapp", To: to, Type: "text"}
+	response.Text.Body = "hola"
+	return response
+}
+
+func TestQueue_RetriesTransientFailures(t *testing.T) {
+	sender := &fakeSender{failures: []error{&statusError{429}, &statusError{503}}}
+	deadLetters := repository.NewInMemoryDeadLetterRepository(10)
+	queue := newTestQueue(sender, deadLetters, 10)
+	queue.Start()
+
+	if err := queue.Enqueue(newResponse("5493430000000")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := queue.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected shutdown error: %v", err)
+	}
+
+	if sender.calls != 3 || len(sender.sent) != 1 {
+		t.Errorf("Expected 3 attempts and 1 delivery, got %d attempts and %d deliveries", sender.calls, len(sender.sent))
+	}
+
+	letters, _ := deadLetters.List()
+	if len(letters) != 0 {
+		t.Errorf("Expected no dead letters, got %d", len(letters))
+	}
+}
+
+func TestQueue_DeadLettersPermanentFailures(t *testing.T) {
+	tests := []struct {
+		name             string
+		failures         []error
+		expectedAttempts int
+	}{
+		{
+			name:             "Client error is not retried",
+			failures:         []error{&statusError{400}},
+			expectedAttempts: 1,
+		},
+		{
+			name:             "Unclassified error is not retried",
+			failures:         []error{errors.New("configuration incomplete")},
+			expectedAttempts: 1,
+		},
+		{
+			name:             "Retries exhausted",
+			failures:         []error{&statusError{500}, &statusError{500}, &statusError{500}},
+			expectedAttempts: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeSender{failures: tt.failures}
+			deadLetters := repository.NewInMemoryDeadLetterRepository(10)
+			queue := newTestQueue(sender, deadLetters, 10)
+			queue.Start()
+
+			response := newResponse("5493430000000")
+			response.ReminderID = "reminder-1"
+			queue.Enqueue(response)
+			queue.Shutdown(context.Background())
+
+			letters, _ := deadLetters.List()
+			if len(letters) != 1 {
+				t.Fatalf("Expected 1 dead letter, got %d", len(letters))
+			}
+
+			if letters[0].Attempts != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.expectedAttempts, letters[0].Attempts)
+			}
+
+			if letters[0].Response.To != "5493430000000" {
+				t.Errorf("Expected dead letter to keep the response, got %+v", letters[0].Response)
+			}
+
+			if letters[0].ReminderID != "reminder-1" {
+				t.Errorf("Expected dead letter to keep the reminder ID, got %q", letters[0].ReminderID)
+			}
+		})
+	}
+}
+
+func TestQueue_BoundedQueue(t *testing.T) {
+	sender := &fakeSender{block: make(chan struct{})}
+	queue := newTestQueue(sender, repository.NewInMemoryDeadLetterRepository(10), 1)
+	queue.Start()
+
+	// The worker takes the first message and blocks, the second fills the queue
+	queue.Enqueue(newResponse("1"))
+	deadline := time.Now().Add(time.Second)
+	for len(queue.jobs) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := queue.Enqueue(newResponse("2")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := queue.Enqueue(newResponse("3")); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+
+	close(sender.block)
+	queue.Shutdown(context.Background())
+
+	if len(sender.sent) != 2 {
+		t.Errorf("Expected queued messages to be drained on shutdown, got %d sent", len(sender.sent))
+	}
+
+	if err := queue.Enqueue(newResponse("4")); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("Expected ErrQueueClosed after shutdown, got %v", err)
+	}
+}
+
+func TestQueue_Backoff(t *testing.T) {
+	queue := NewQueue(&fakeSender{}, repository.NewInMemoryDeadLetterRepository(1), &Config{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	})
+
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, delay := range expected {
+		if got := queue.backoff(i + 1); got != delay {
+			t.Errorf("backoff(%d) = %s, expected %s", i+1, got, delay)
+		}
+	}
+}
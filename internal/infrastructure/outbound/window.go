@@ -0,0 +1,103 @@
+package outbound
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/infrastructure/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ServiceWindow is how long after a user's last message WhatsApp accepts
+// free-form messages to them. Afterwards only approved templates are delivered.
+const ServiceWindow = 24 * time.Hour
+
+// maxTemplateParameter is the longest text passed as a template variable
+const maxTemplateParameter = 900
+
+// LastInboundFunc returns when a user last wrote to us, the zero time if unknown
+type LastInboundFunc func(userID string) (time.Time, error)
+
+// WindowFallback replaces free-form messages to users whose service window
+// has closed with a pre-approved template
+type WindowFallback struct {
+	lastInbound LastInboundFunc
+	template    models.TemplateContent
+	withText    bool
+}
+
+// NewWindowFallback creates a fallback to the template name in language. When
+// withText is set the text of the replaced message fills the template's only
+// body variable.
+func NewWindowFallback(lastInbound LastInboundFunc, name, language string, withText bool) *WindowFallback {
+	return &WindowFallback{
+		lastInbound: lastInbound,
+		template: models.TemplateContent{
+			Name:     name,
+			Language: models.TemplateLanguage{Code: language},
+		},
+		withText: withText,
+	}
+}
+
+// Apply returns the message to send instead of response at now
+func (f *WindowFallback) Apply(response *models.WhatsAppResponse, now time.Time) *models.WhatsAppResponse {
+	if response.Type == "template" || response.StaffNotification {
+		return response
+	}
+
+	lastInbound, err := f.lastInbound(response.To)
+	if err != nil {
+		// A template is delivered either way, a free-form message might not be
+		logger.GetLogger().WithFields(logrus.Fields{
+			"to":    response.To,
+			"error": err.Error(),
+		}).Warn("Failed to look up last inbound message - sending template")
+	} else if !lastInbound.IsZero() && now.Sub(lastInbound) < ServiceWindow {
+		return response
+	}
+
+	template := f.template
+	if f.withText {
+		template.Components = []models.TemplateComponent{{
+			Type:       "body",
+			Parameters: []models.TemplateParameter{{Type: "text", Text: templateParameter(messageText(response))}},
+		}}
+	}
+
+	// The text is kept so transcripts show what the template stood in for
+	templated := *response
+	templated.Type = "template"
+	templated.Interactive = nil
+	templated.Template = &template
+	templated.Text.Body = messageText(response)
+
+	logger.GetLogger().WithFields(logrus.Fields{
+		"to":           response.To,
+		"template":     template.Name,
+		"last_inbound": lastInbound,
+	}).Info("Service window closed - sending template")
+
+	return &templated
+}
+
+// messageText returns the text shown to the user by a text or interactive message
+func messageText(response *models.WhatsAppResponse) string {
+	if response.Interactive != nil {
+		return response.Interactive.Body.Body
+	}
+	return response.Text.Body
+}
+
+// templateParameter adapts text to the rules of template variables, which
+// can't contain new lines or runs of spaces and have a limited length
+func templateParameter(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= maxTemplateParameter {
+		return text
+	}
+	return string([]rune(text)[:maxTemplateParameter-1]) + "…"
+}
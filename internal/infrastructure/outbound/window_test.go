@@ -0,0 +1,126 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
+)
+
+func TestWindowFallback_Apply(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	lastInbound := map[string]time.Time{
+		"recent": now.Add(-time.Hour),
+		"stale":  now.Add(-25 * time.Hour),
+	}
+	fallback := NewWindowFallback(func(userID string) (time.Time, error) {
+		if userID == "broken" {
+			return time.Time{}, errors.New("storage unavailable")
+		}
+		return lastInbound[userID], nil
+	}, "seguimiento", "es_AR", true)
+
+	tests := []struct {
+		name             string
+		to               string
+		expectedTemplate bool
+	}{
+		{"Inside the window", "recent", false},
+		{"Window closed", "stale", true},
+		{"Never wrote to us", "unknown", true},
+		{"Lookup failed", "broken", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := newResponse(tt.to)
+			response.Text.Body = "Hola,\n\nte recordamos tu turno"
+
+			sent := fallback.Apply(response, now)
+			if (sent.Type == "template") != tt.expectedTemplate {
+				t.Fatalf("Expected template %v, got type %s", tt.expectedTemplate, sent.Type)
+			}
+			if !tt.expectedTemplate {
+				if sent != response {
+					t.Error("Expected the message to be sent unchanged")
+				}
+				return
+			}
+
+			if sent.Template.Name != "seguimiento" || sent.Template.Language.Code != "es_AR" {
+				t.Errorf("Expected the configured template, got %+v", sent.Template)
+			}
+			parameters := sent.Template.Components[0].Parameters
+			if len(parameters) != 1 || parameters[0].Text != "Hola, te recordamos tu turno" {
+				t.Errorf("Expected the text flattened into the body variable, got %+v", parameters)
+			}
+			if response.Type != "text" {
+				t.Error("Expected the original message to be left untouched")
+			}
+		})
+	}
+}
+
+func TestWindowFallback_InteractiveWithoutText(t *testing.T) {
+	fallback := NewWindowFallback(func(string) (time.Time, error) {
+		return time.Time{}, nil
+	}, "seguimiento", "es", false)
+
+	response := &models.WhatsAppResponse{To: "user123", Type: "interactive", Interactive: &models.InteractiveContent{
+		Type: "button",
+		Body: models.TextContent{Body: "Elegí una opción"},
+	}}
+
+	sent := fallback.Apply(response, time.Now())
+	if sent.Interactive != nil || sent.Template == nil || len(sent.Template.Components) != 0 {
+		t.Errorf("Expected a template without variables, got %+v", sent)
+	}
+	if sent.Text.Body != "Elegí una opción" {
+		t.Errorf("Expected the replaced text kept for transcripts, got %q", sent.Text.Body)
+	}
+}
+
+func TestWindowFallback_SkipsStaffNotifications(t *testing.T) {
+	looked := false
+	fallback := NewWindowFallback(func(string) (time.Time, error) {
+		looked = true
+		return time.Time{}, nil
+	}, "seguimiento", "es_AR", true)
+
+	response := newResponse("5493439999999")
+	response.StaffNotification = true
+
+	if sent := fallback.Apply(response, time.Now()); sent != response {
+		t.Errorf("Expected the staff notification sent unchanged, got %+v", sent)
+	}
+	if looked {
+		t.Error("Expected no last inbound lookup for the staff number")
+	}
+}
+
+func TestTemplateParameter_Truncates(t *testing.T) {
+	parameter := templateParameter(strings.Repeat("a", maxTemplateParameter+10))
+	if len([]rune(parameter)) != maxTemplateParameter || !strings.HasSuffix(parameter, "…") {
+		t.Errorf("Expected parameter truncated to %d runes, got %d", maxTemplateParameter, len([]rune(parameter)))
+	}
+}
+
+func TestQueue_SendsTemplateOutsideWindow(t *testing.T) {
+	sender := &fakeSender{}
+	queue := newTestQueue(sender, repository.NewInMemoryDeadLetterRepository(10), 10)
+	queue.SetWindowFallback(NewWindowFallback(func(string) (time.Time, error) {
+		return time.Now().Add(-48 * time.Hour), nil
+	}, "seguimiento", "es_AR", true))
+	queue.Start()
+
+	queue.Enqueue(newResponse("5493430000000"))
+	queue.Shutdown(context.Background())
+
+	if len(sender.sent) != 1 || sender.sent[0].Type != "template" {
+		t.Errorf("Expected a template to be sent, got %+v", sender.sent)
+	}
+}
@@ -0,0 +1,209 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// AppointmentRepository implements repository.AppointmentRepository on a Redis
+// protocol store. Each appointment is a JSON key, and booked appointments are
+// indexed in sorted sets scored by their start time, one for the whole agenda
+// and one per user. A slot is claimed with SET NX in the same script that saves
+// the appointment, so two instances can never book it twice.
+type AppointmentRepository struct {
+	client    *goredis.Client
+	keyPrefix string
+}
+
+// NewAppointmentRepository creates a new Redis appointment repository
+func NewAppointmentRepository(client *goredis.Client, keyPrefix string) *AppointmentRepository {
+	return &AppointmentRepository{
+		client:    client,
+		keyPrefix: keyPrefix,
+	}
+}
+
+// bookScript claims the slot of an appointment and saves it in one step, so no
+// other client sees a claimed slot without its appointment. Scripts are not
+// rolled back on error, so the indexes that can fail go first and the slot is
+// claimed last: list drops index entries without an appointment.
+// KEYS are the slot, the appointment and the agenda and user indexes, ARGV the
+// appointment ID, its JSON and its start time.
+var bookScript = goredis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+redis.call("ZADD", KEYS[3], ARGV[3], ARGV[1])
+redis.call("ZADD", KEYS[4], ARGV[3], ARGV[1])
+redis.call("SET", KEYS[2], ARGV[2])
+redis.call("SET", KEYS[1], ARGV[1])
+return 1
+`)
+
+// Book reserves the slot of an appointment
+func (r *AppointmentRepository) Book(appointment *models.Appointment) error {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	booked := *appointment
+	if booked.ID == "" {
+		booked.ID = repository.NewAppointmentID()
+	}
+	booked.Status = models.AppointmentBooked
+
+	value, err := json.Marshal(&booked)
+	if err != nil {
+		return fmt.Errorf("failed to marshal appointment: %v", err)
+	}
+
+	keys := []string{
+		r.slotKey(booked.LocationID, booked.Start),
+		r.appointmentKey(booked.ID),
+		r.agendaKey(),
+		r.userKey(booked.UserID),
+	}
+	claimed, err := bookScript.Run(ctx, r.client, keys, booked.ID, value, booked.Start.Unix()).Int()
+	if err != nil {
+		return fmt.Errorf("failed to book appointment: %v", err)
+	}
+	if claimed == 0 {
+		return errors.ErrSlotTaken
+	}
+
+	appointment.ID = booked.ID
+	appointment.Status = booked.Status
+	return nil
+}
+
+// Cancel frees the slot of a booked appointment
+func (r *AppointmentRepository) Cancel(id string, cancelledAt time.Time) (*models.Appointment, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	key := r.appointmentKey(id)
+	var cancelled *models.Appointment
+	err := r.client.Watch(ctx, func(tx *goredis.Tx) error {
+		appointment, err := r.get(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if appointment.Status != models.AppointmentBooked {
+			return errors.ErrAppointmentNotFound
+		}
+
+		appointment.Status = models.AppointmentCancelled
+		appointment.CancelledAt = cancelledAt
+		value, err := json.Marshal(appointment)
+		if err != nil {
+			return fmt.Errorf("failed to marshal appointment: %v", err)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+			pipe.Set(ctx, key, value, 0)
+			pipe.Del(ctx, r.slotKey(appointment.LocationID, appointment.Start))
+			pipe.ZRem(ctx, r.agendaKey(), id)
+			pipe.ZRem(ctx, r.userKey(appointment.UserID), id)
+			return nil
+		})
+		cancelled = appointment
+		return err
+	}, key)
+	if err == goredis.TxFailedErr {
+		return nil, fmt.Errorf("failed to cancel appointment: changed concurrently")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return cancelled, nil
+}
+
+// ListByUser returns the booked appointments of a user starting after from
+func (r *AppointmentRepository) ListByUser(userID string, from time.Time) ([]*models.Appointment, error) {
+	return r.list(r.userKey(userID), &goredis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(from.Unix(), 10),
+		Max: "+inf",
+	}, func(appointment *models.Appointment) bool {
+		return appointment.Start.After(from)
+	})
+}
+
+// ListBooked returns the booked appointments starting in [from, to)
+func (r *AppointmentRepository) ListBooked(from, to time.Time) ([]*models.Appointment, error) {
+	return r.list(r.agendaKey(), &goredis.ZRangeBy{
+		Min: strconv.FormatInt(from.Unix(), 10),
+		Max: strconv.FormatInt(to.Unix(), 10),
+	}, func(appointment *models.Appointment) bool {
+		return !appointment.Start.Before(from) && appointment.Start.Before(to)
+	})
+}
+
+// list returns the appointments of an index within a score range, keeping
+// those that match, since scores only have a precision of seconds
+func (r *AppointmentRepository) list(index string, scores *goredis.ZRangeBy, match func(*models.Appointment) bool) ([]*models.Appointment, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	ids, err := r.client.ZRangeByScore(ctx, index, scores).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list appointments: %v", err)
+	}
+
+	var appointments []*models.Appointment
+	for _, id := range ids {
+		appointment, err := r.get(ctx, r.client, id)
+		if err == errors.ErrAppointmentNotFound {
+			r.client.ZRem(ctx, index, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if appointment.Status == models.AppointmentBooked && match(appointment) {
+			appointments = append(appointments, appointment)
+		}
+	}
+
+	return appointments, nil
+}
+
+func (r *AppointmentRepository) get(ctx context.Context, client goredis.Cmdable, id string) (*models.Appointment, error) {
+	value, err := client.Get(ctx, r.appointmentKey(id)).Bytes()
+	if err == goredis.Nil {
+		return nil, errors.ErrAppointmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get appointment: %v", err)
+	}
+
+	var appointment models.Appointment
+	if err := json.Unmarshal(value, &appointment); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal appointment: %v", err)
+	}
+	return &appointment, nil
+}
+
+func (r *AppointmentRepository) appointmentKey(id string) string {
+	return r.keyPrefix + "appointment:" + id
+}
+
+func (r *AppointmentRepository) slotKey(locationID string, start time.Time) string {
+	return r.keyPrefix + "appointment-slot:" + repository.SlotKey(locationID, start)
+}
+
+func (r *AppointmentRepository) agendaKey() string {
+	return r.keyPrefix + "appointments"
+}
+
+func (r *AppointmentRepository) userKey(userID string) string {
+	return r.keyPrefix + "appointments-user:" + userID
+}
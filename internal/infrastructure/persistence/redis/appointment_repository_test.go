@@ -0,0 +1,96 @@
+package redis
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	domainerrors "chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/domain/models"
+)
+
+func TestAppointmentRepository_BookAndCancel(t *testing.T) {
+	_, client := newTestClient(t)
+	repo := NewAppointmentRepository(client, "test:")
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Minute)
+
+	first := &models.Appointment{UserID: "user123", PatientName: "Juan Pérez", LocationID: "cervantes", LocationName: "Centro Médico Cervantes",
+		Start: start, End: start.Add(20 * time.Minute), CreatedAt: time.Now()}
+	if err := repo.Book(first); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first.ID == "" || first.Status != models.AppointmentBooked {
+		t.Errorf("Expected a booked appointment with an ID, got %+v", first)
+	}
+
+	// Same slot, another patient
+	second := &models.Appointment{UserID: "user456", LocationID: "cervantes", Start: start, End: start.Add(20 * time.Minute), CreatedAt: time.Now()}
+	if err := repo.Book(second); !errors.Is(err, domainerrors.ErrSlotTaken) {
+		t.Fatalf("Expected ErrSlotTaken, got %v", err)
+	}
+
+	// Same time at another location is a different slot
+	other := &models.Appointment{UserID: "user456", LocationID: "ospep", Start: start, End: start.Add(30 * time.Minute), CreatedAt: time.Now()}
+	if err := repo.Book(other); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	booked, err := repo.ListByUser("user123", time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(booked) != 1 || booked[0].ID != first.ID || booked[0].PatientName != "Juan Pérez" || !booked[0].Start.Equal(start) {
+		t.Errorf("Expected the appointment of user123, got %+v", booked)
+	}
+
+	cancelled, err := repo.Cancel(first.ID, time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cancelled.Status != models.AppointmentCancelled || cancelled.CancelledAt.IsZero() {
+		t.Errorf("Expected a cancelled appointment, got %+v", cancelled)
+	}
+	if _, err := repo.Cancel(first.ID, time.Now()); !errors.Is(err, domainerrors.ErrAppointmentNotFound) {
+		t.Errorf("Expected ErrAppointmentNotFound cancelling twice, got %v", err)
+	}
+
+	// The cancelled slot can be booked again
+	if err := repo.Book(second); err != nil {
+		t.Fatalf("Expected the freed slot to be bookable, got %v", err)
+	}
+
+	agenda, err := repo.ListBooked(start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(agenda) != 2 {
+		t.Errorf("Expected 2 booked appointments, got %+v", agenda)
+	}
+}
+
+func TestAppointmentRepository_FailedBookingFreesSlot(t *testing.T) {
+	server, client := newTestClient(t)
+	repo := NewAppointmentRepository(client, "test:")
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Minute)
+
+	// A user index of the wrong type makes saving the appointment fail
+	server.Set("test:appointments-user:user123", "broken")
+	appointment := &models.Appointment{UserID: "user123", LocationID: "cervantes", Start: start, End: start.Add(20 * time.Minute), CreatedAt: time.Now()}
+	if err := repo.Book(appointment); err == nil {
+		t.Fatal("Expected an error saving the appointment")
+	}
+
+	// The slot was not left claimed
+	other := &models.Appointment{UserID: "user456", LocationID: "cervantes", Start: start, End: start.Add(20 * time.Minute), CreatedAt: time.Now()}
+	if err := repo.Book(other); err != nil {
+		t.Fatalf("Expected the slot to be bookable after a failed booking, got %v", err)
+	}
+
+	agenda, err := repo.ListBooked(start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(agenda) != 1 || agenda[0].ID != other.ID {
+		t.Errorf("Expected only the second appointment, got %+v", agenda)
+	}
+}
@@ -0,0 +1,187 @@
+package redis
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// ChatbotRepository implements repository.ChatbotRepository on a Redis protocol store.
+// Sessions are stored with a per-key TTL so expired sessions disappear without a cleanup goroutine.
+type ChatbotRepository struct {
+	*repository.FlowCatalog
+	client          *goredis.Client
+	keyPrefix       string
+	mutex           sync.RWMutex
+	expirationHours int
+}
+
+// NewChatbotRepository creates a new Redis repository serving the given flows
+func NewChatbotRepository(client *goredis.Client, keyPrefix string, flows map[string]*models.ChatbotFlow) *ChatbotRepository {
+	return &ChatbotRepository{
+		FlowCatalog:     repository.NewFlowCatalog(flows),
+		client:          client,
+		keyPrefix:       keyPrefix,
+		expirationHours: 24, // Default to 24 hours
+	}
+}
+
+// GetUserState retrieves the current state of a user
+func (r *ChatbotRepository) GetUserState(userID string) (*models.ChatbotState, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	value, err := r.client.Get(ctx, r.sessionKey(userID)).Bytes()
+	if err == goredis.Nil {
+		return repository.NewUserState(userID), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user state: %v", err)
+	}
+
+	var state models.ChatbotState
+	if err := json.Unmarshal(value, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user state: %v", err)
+	}
+	if state.Data == nil {
+		state.Data = make(map[string]string)
+	}
+
+	// The key TTL is refreshed on every save, but a changed expiration may leave older keys behind
+	if repository.IsSessionExpired(&state, r.getExpirationHours()) {
+		return repository.NewUserState(userID), nil
+	}
+
+	return &state, nil
+}
+
+// SaveUserState saves the current state of a user, resetting its expiration
+func (r *ChatbotRepository) SaveUserState(state *models.ChatbotState) error {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal user state: %v", err)
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	ttl := time.Until(state.UpdatedAt.Add(r.expiration()))
+	if ttl <= 0 {
+		return r.client.Del(ctx, r.sessionKey(state.UserID)).Err()
+	}
+
+	if err := r.client.Set(ctx, r.sessionKey(state.UserID), value, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save user state: %v", err)
+	}
+	return nil
+}
+
+// ListUserStates returns the sessions that have not expired, most recently active first
+func (r *ChatbotRepository) ListUserStates() ([]*models.ChatbotState, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	var states []*models.ChatbotState
+	iter := r.client.Scan(ctx, 0, r.sessionKey("*"), 100).Iterator()
+	for iter.Next(ctx) {
+		value, err := r.client.Get(ctx, iter.Val()).Bytes()
+		if err == goredis.Nil {
+			// Expired between the scan and the read
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user state: %v", err)
+		}
+
+		var state models.ChatbotState
+		if err := json.Unmarshal(value, &state); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal user state: %v", err)
+		}
+		if !repository.IsSessionExpired(&state, r.getExpirationHours()) {
+			states = append(states, &state)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list user states: %v", err)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].UpdatedAt.After(states[j].UpdatedAt)
+	})
+	return states, nil
+}
+
+// CountUserStates returns how many session keys exist. Sessions are saved
+// with the expiration as TTL, so keys are counted without reading them, and
+// keys saved under a longer expiration count until Redis expires them.
+func (r *ChatbotRepository) CountUserStates() (int, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	count := 0
+	iter := r.client.Scan(ctx, 0, r.sessionKey("*"), 100).Iterator()
+	for iter.Next(ctx) {
+		count++
+	}
+	if err := iter.Err(); err != nil {
+		return 0, fmt.Errorf("failed to count user states: %v", err)
+	}
+	return count, nil
+}
+
+// DeleteUserState removes the session of a user
+func (r *ChatbotRepository) DeleteUserState(userID string) error {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	value, err := r.client.GetDel(ctx, r.sessionKey(userID)).Bytes()
+	if err == goredis.Nil {
+		return errors.ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete user state: %v", err)
+	}
+
+	// A changed expiration may leave keys behind that are already expired
+	var state models.ChatbotState
+	if err := json.Unmarshal(value, &state); err != nil {
+		return fmt.Errorf("failed to unmarshal user state: %v", err)
+	}
+	if repository.IsSessionExpired(&state, r.getExpirationHours()) {
+		return errors.ErrUserNotFound
+	}
+	return nil
+}
+
+// StartSessionCleanup sets the session expiration. Redis expires the keys on its own,
+// so no background goroutine is started.
+func (r *ChatbotRepository) StartSessionCleanup(expirationHours, cleanupIntervalMin int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.expirationHours = expirationHours
+}
+
+// StopSessionCleanup is a no-op, kept to satisfy repository.ChatbotRepository
+func (r *ChatbotRepository) StopSessionCleanup() {}
+
+func (r *ChatbotRepository) sessionKey(userID string) string {
+	return r.keyPrefix + "session:" + userID
+}
+
+func (r *ChatbotRepository) getExpirationHours() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.expirationHours
+}
+
+func (r *ChatbotRepository) expiration() time.Duration {
+	return time.Duration(r.getExpirationHours()) * time.Hour
+}
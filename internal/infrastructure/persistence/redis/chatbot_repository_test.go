@@ -0,0 +1,139 @@
+package redis
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	domainerrors "chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/domain/models"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func newTestClient(t *testing.T) (*miniredis.Miniredis, *goredis.Client) {
+	t.Helper()
+	server := miniredis.RunT(t)
+
+	client, err := Open(&Config{Addr: server.Addr()})
+	if err != nil {
+		t.Fatalf("Failed to connect to miniredis: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return server, client
+}
+
+func TestChatbotRepository_SharedAcrossInstances(t *testing.T) {
+	_, client := newTestClient(t)
+
+	// Two repositories on the same server behave like two ECS tasks
+	first := NewChatbotRepository(client, "test:", nil)
+	second := NewChatbotRepository(client, "test:", nil)
+
+	state, err := first.GetUserState("user123")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if state.State != "welcome" {
+		t.Errorf("Expected a fresh welcome state, got %s", state.State)
+	}
+
+	state.State = "option_b"
+	state.Data["datos_lectura_estudios"] = "ecografía"
+	state.UpdatedAt = time.Now()
+	if err := first.SaveUserState(state); err != nil {
+		t.Fatalf("Unexpected error saving state: %v", err)
+	}
+
+	stored, err := second.GetUserState("user123")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stored.State != "option_b" || stored.Data["datos_lectura_estudios"] != "ecografía" {
+		t.Errorf("Expected the other instance to see the saved state, got %+v", stored)
+	}
+}
+
+func TestChatbotRepository_SessionTTL(t *testing.T) {
+	server, client := newTestClient(t)
+	repo := NewChatbotRepository(client, "test:", nil)
+	repo.StartSessionCleanup(2, 30)
+
+	repo.SaveUserState(&models.ChatbotState{
+		UserID:    "user123",
+		State:     "collecting_data",
+		Data:      map[string]string{"test": "data"},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+
+	ttl := server.TTL("test:session:user123")
+	if ttl <= time.Hour || ttl > 2*time.Hour {
+		t.Errorf("Expected a TTL close to 2h, got %s", ttl)
+	}
+
+	server.FastForward(3 * time.Hour)
+
+	state, _ := repo.GetUserState("user123")
+	if state.State != "welcome" || len(state.Data) != 0 {
+		t.Errorf("Expected the session to expire, got %+v", state)
+	}
+	if err := repo.DeleteUserState("user123"); !errors.Is(err, domainerrors.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound deleting an expired session, got %v", err)
+	}
+
+	// A key left behind by a longer expiration is removed, but reported as missing
+	repo.SaveUserState(&models.ChatbotState{UserID: "user456", State: "option_a", CreatedAt: time.Now(), UpdatedAt: time.Now()})
+	repo.StartSessionCleanup(0, 30)
+	if err := repo.DeleteUserState("user456"); !errors.Is(err, domainerrors.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound deleting an expired session, got %v", err)
+	}
+	if server.Exists("test:session:user456") {
+		t.Errorf("Expected the expired session to be removed")
+	}
+
+	// A state saved with an already expired timestamp is removed
+	oldTime := time.Now().Add(-25 * time.Hour)
+	repo.SaveUserState(&models.ChatbotState{UserID: "old", State: "option_a", CreatedAt: oldTime, UpdatedAt: oldTime})
+	if server.Exists("test:session:old") {
+		t.Errorf("Expected expired state not to be stored")
+	}
+}
+
+func TestChatbotRepository_ListAndDelete(t *testing.T) {
+	_, client := newTestClient(t)
+	repo := NewChatbotRepository(client, "test:", nil)
+	other := NewChatbotRepository(client, "other:", nil)
+
+	now := time.Now()
+	for i, userID := range []string{"user1", "user2"} {
+		state := &models.ChatbotState{UserID: userID, State: "option_a", CreatedAt: now, UpdatedAt: now.Add(time.Duration(i) * time.Minute)}
+		repo.SaveUserState(state)
+	}
+	other.SaveUserState(&models.ChatbotState{UserID: "user3", State: "welcome", CreatedAt: now, UpdatedAt: now})
+
+	states, err := repo.ListUserStates()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(states) != 2 || states[0].UserID != "user2" || states[1].UserID != "user1" {
+		t.Fatalf("Expected the sessions under the prefix, most recent first, got %+v", states)
+	}
+	if count, err := repo.CountUserStates(); err != nil || count != 2 {
+		t.Errorf("Expected the 2 sessions under the prefix, got %d (%v)", count, err)
+	}
+
+	if err := repo.DeleteUserState("user1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := repo.DeleteUserState("user1"); !errors.Is(err, domainerrors.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound deleting a missing session, got %v", err)
+	}
+
+	states, _ = repo.ListUserStates()
+	if len(states) != 1 || states[0].UserID != "user2" {
+		t.Errorf("Expected only user2 after delete, got %+v", states)
+	}
+}
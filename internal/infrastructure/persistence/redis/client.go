@@ -0,0 +1,43 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// operationTimeout bounds every call made to Redis
+const operationTimeout = 5 * time.Second
+
+// Config holds configuration for the Redis connection
+type Config struct {
+	Addr     string
+	Password string
+	DB       int
+}
+
+// Open connects to the Redis server and checks it is reachable
+func Open(config *Config) (*goredis.Client, error) {
+	client := goredis.NewClient(&goredis.Options{
+		Addr:     config.Addr,
+		Password: config.Password,
+		DB:       config.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis at %s: %v", config.Addr, err)
+	}
+
+	return client, nil
+}
+
+// newContext returns the context used for a single Redis operation
+func newContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), operationTimeout)
+}
@@ -0,0 +1,76 @@
+package redis
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"chatbot-wsp/internal/domain/models"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// DeadLetterRepository implements repository.DeadLetterRepository on a Redis
+// protocol store. Dead letters are a list of JSON entries trimmed to the
+// capacity, so the most recent ones are kept.
+type DeadLetterRepository struct {
+	client    *goredis.Client
+	keyPrefix string
+	capacity  int
+}
+
+// NewDeadLetterRepository creates a new Redis dead letter repository holding
+// at most capacity letters
+func NewDeadLetterRepository(client *goredis.Client, keyPrefix string, capacity int) *DeadLetterRepository {
+	return &DeadLetterRepository{
+		client:    client,
+		keyPrefix: keyPrefix,
+		capacity:  capacity,
+	}
+}
+
+// Add stores a dead letter, discarding the oldest ones past the capacity
+func (r *DeadLetterRepository) Add(letter *models.DeadLetter) error {
+	value, err := json.Marshal(letter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %v", err)
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	_, err = r.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.RPush(ctx, r.deadLettersKey(), value)
+		pipe.LTrim(ctx, r.deadLettersKey(), int64(-r.capacity), -1)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add dead letter: %v", err)
+	}
+	return nil
+}
+
+// List returns the stored dead letters, oldest first
+func (r *DeadLetterRepository) List() ([]*models.DeadLetter, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	values, err := r.client.LRange(ctx, r.deadLettersKey(), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %v", err)
+	}
+
+	letters := make([]*models.DeadLetter, 0, len(values))
+	for _, value := range values {
+		var letter models.DeadLetter
+		if err := json.Unmarshal([]byte(value), &letter); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal dead letter: %v", err)
+		}
+		letters = append(letters, &letter)
+	}
+
+	return letters, nil
+}
+
+func (r *DeadLetterRepository) deadLettersKey() string {
+	return r.keyPrefix + "dead-letters"
+}
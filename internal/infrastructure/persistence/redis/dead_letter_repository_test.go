@@ -0,0 +1,39 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+func TestDeadLetterRepository_AddAndList(t *testing.T) {
+	_, client := newTestClient(t)
+	repo := NewDeadLetterRepository(client, "test:", 2)
+
+	for _, to := range []string{"user1", "user2", "user3"} {
+		letter := &models.DeadLetter{
+			Response:  &models.WhatsAppResponse{To: to, Type: "text", Text: models.TextContent{Body: "Hola"}},
+			Attempts:  5,
+			LastError: "boom",
+			FailedAt:  time.Now(),
+		}
+		if err := repo.Add(letter); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	letters, err := repo.List()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(letters) != 2 {
+		t.Fatalf("Expected the 2 most recent letters, got %d", len(letters))
+	}
+	if letters[0].Response.To != "user2" || letters[1].Response.To != "user3" {
+		t.Errorf("Expected letters oldest first, got %s, %s", letters[0].Response.To, letters[1].Response.To)
+	}
+	if letters[1].Attempts != 5 || letters[1].LastError != "boom" || letters[1].Response.Text.Body != "Hola" {
+		t.Errorf("Expected the letter to round trip, got %+v", letters[1])
+	}
+}
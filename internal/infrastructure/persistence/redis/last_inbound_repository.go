@@ -0,0 +1,66 @@
+package redis
+
+import (
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// LastInboundRepository implements repository.LastInboundRepository on a Redis
+// protocol store, each user's last message expiring through its key TTL
+type LastInboundRepository struct {
+	client    *goredis.Client
+	keyPrefix string
+	ttl       time.Duration
+}
+
+// NewLastInboundRepository creates a repository remembering messages for ttl
+func NewLastInboundRepository(client *goredis.Client, keyPrefix string, ttl time.Duration) *LastInboundRepository {
+	return &LastInboundRepository{
+		client:    client,
+		keyPrefix: keyPrefix,
+		ttl:       ttl,
+	}
+}
+
+// Touch records that the user wrote to us at the given time
+func (r *LastInboundRepository) Touch(userID string, at time.Time) error {
+	expiration := time.Until(at.Add(r.ttl))
+	if expiration <= 0 {
+		return nil
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	if err := r.client.Set(ctx, r.lastInboundKey(userID), at.UnixNano(), expiration).Err(); err != nil {
+		return fmt.Errorf("failed to record last inbound message: %v", err)
+	}
+	return nil
+}
+
+// LastInbound returns when the user last wrote to us, the zero time if not within the TTL
+func (r *LastInboundRepository) LastInbound(userID string) (time.Time, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	nanos, err := r.client.Get(ctx, r.lastInboundKey(userID)).Int64()
+	if err == goredis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get last inbound message: %v", err)
+	}
+	return time.Unix(0, nanos), nil
+}
+
+// StartCleanup is a no-op, messages expire through their key TTL
+func (r *LastInboundRepository) StartCleanup(cleanupIntervalMin int) {}
+
+// StopCleanup is a no-op, messages expire through their key TTL
+func (r *LastInboundRepository) StopCleanup() {}
+
+func (r *LastInboundRepository) lastInboundKey(userID string) string {
+	return r.keyPrefix + "last-inbound:" + userID
+}
@@ -0,0 +1,36 @@
+package redis
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLastInboundRepository_Touch(t *testing.T) {
+	server, client := newTestClient(t)
+	repo := NewLastInboundRepository(client, "test:", time.Hour)
+
+	if at, err := repo.LastInbound("user123"); err != nil || !at.IsZero() {
+		t.Fatalf("Expected no message from an unknown user, got %v err=%v", at, err)
+	}
+
+	now := time.Now()
+	if err := repo.Touch("user123", now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	repo.Touch("user456", now.Add(-2*time.Hour))
+
+	// Another instance sees the message received by the first one
+	other := NewLastInboundRepository(client, "test:", time.Hour)
+	if at, _ := other.LastInbound("user123"); !at.Equal(now) {
+		t.Errorf("Expected the message at %v, got %v", now, at)
+	}
+	if at, _ := other.LastInbound("user456"); !at.IsZero() {
+		t.Errorf("Expected a message older than the TTL to be ignored, got %v", at)
+	}
+
+	server.FastForward(2 * time.Hour)
+
+	if at, _ := repo.LastInbound("user123"); !at.IsZero() {
+		t.Errorf("Expected the message to be forgotten after the TTL, got %v", at)
+	}
+}
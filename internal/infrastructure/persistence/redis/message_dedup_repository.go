@@ -0,0 +1,101 @@
+package redis
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// MessageDedupRepository implements repository.MessageDedupRepository on a Redis
+// protocol store, so every instance sees the messages handled by the others
+type MessageDedupRepository struct {
+	client    *goredis.Client
+	keyPrefix string
+	ttl       time.Duration
+}
+
+// NewMessageDedupRepository creates a repository remembering message IDs for ttl
+func NewMessageDedupRepository(client *goredis.Client, keyPrefix string, ttl time.Duration) *MessageDedupRepository {
+	return &MessageDedupRepository{
+		client:    client,
+		keyPrefix: keyPrefix,
+		ttl:       ttl,
+	}
+}
+
+// MarkProcessed records the message ID and reports whether it had already been seen
+func (r *MessageDedupRepository) MarkProcessed(messageID string) (bool, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	stored, err := r.client.SetNX(ctx, r.messageKey(messageID), 1, r.ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark message as processed: %v", err)
+	}
+	return !stored, nil
+}
+
+// Unmark forgets a message ID
+func (r *MessageDedupRepository) Unmark(messageID string) error {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	if err := r.client.Del(ctx, r.messageKey(messageID), r.replyKey(messageID)).Err(); err != nil {
+		return fmt.Errorf("failed to unmark message: %v", err)
+	}
+	return nil
+}
+
+// KeepReply holds the reply to a processed message for as long as its ID is remembered
+func (r *MessageDedupRepository) KeepReply(messageID string, response *models.WhatsAppResponse) error {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	value, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reply: %v", err)
+	}
+	if err := r.client.Set(ctx, r.replyKey(messageID), value, r.ttl).Err(); err != nil {
+		return fmt.Errorf("failed to keep reply: %v", err)
+	}
+	return nil
+}
+
+// TakeReply returns and forgets the reply kept for a message. Only one
+// instance gets it when several handle redeliveries of the same message.
+func (r *MessageDedupRepository) TakeReply(messageID string) (*models.WhatsAppResponse, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	value, err := r.client.GetDel(ctx, r.replyKey(messageID)).Bytes()
+	if err == goredis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take reply: %v", err)
+	}
+
+	var response models.WhatsAppResponse
+	if err := json.Unmarshal(value, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal reply: %v", err)
+	}
+	return &response, nil
+}
+
+// StartCleanup is a no-op, message IDs expire through their key TTL
+func (r *MessageDedupRepository) StartCleanup(cleanupIntervalMin int) {}
+
+// StopCleanup is a no-op, message IDs expire through their key TTL
+func (r *MessageDedupRepository) StopCleanup() {}
+
+func (r *MessageDedupRepository) messageKey(messageID string) string {
+	return r.keyPrefix + "message:" + messageID
+}
+
+func (r *MessageDedupRepository) replyKey(messageID string) string {
+	return r.keyPrefix + "message-reply:" + messageID
+}
@@ -0,0 +1,80 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+func TestMessageDedupRepository_MarkProcessed(t *testing.T) {
+	server, client := newTestClient(t)
+	repo := NewMessageDedupRepository(client, "test:", time.Hour)
+
+	seen, err := repo.MarkProcessed("wamid.1")
+	if err != nil || seen {
+		t.Fatalf("Expected first delivery to be new, got seen=%v err=%v", seen, err)
+	}
+
+	seen, _ = repo.MarkProcessed("wamid.1")
+	if !seen {
+		t.Errorf("Expected redelivery to be reported as seen")
+	}
+
+	server.FastForward(2 * time.Hour)
+
+	seen, _ = repo.MarkProcessed("wamid.1")
+	if seen {
+		t.Errorf("Expected message ID to be forgotten after the TTL")
+	}
+}
+
+func TestMessageDedupRepository_Unmark(t *testing.T) {
+	server, client := newTestClient(t)
+	repo := NewMessageDedupRepository(client, "test:", time.Hour)
+	repo.MarkProcessed("wamid.1")
+
+	// Another instance sees the message handled by the first one
+	other := NewMessageDedupRepository(client, "test:", time.Hour)
+	if seen, _ := other.MarkProcessed("wamid.1"); !seen {
+		t.Error("Expected a redelivered message to be seen by another instance")
+	}
+
+	if err := repo.Unmark("wamid.1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if server.Exists("test:message:wamid.1") {
+		t.Error("Expected the message ID key to be deleted")
+	}
+	if seen, _ := other.MarkProcessed("wamid.1"); seen {
+		t.Error("Expected an unmarked message to be processed again")
+	}
+}
+
+func TestMessageDedupRepository_KeepReply(t *testing.T) {
+	server, client := newTestClient(t)
+	repo := NewMessageDedupRepository(client, "test:", time.Hour)
+	repo.MarkProcessed("wamid.1")
+	repo.MarkProcessed("wamid.2")
+
+	response := &models.WhatsAppResponse{MessagingProduct: "whatsapp", To: "user123", Type: "text", Text: models.TextContent{Body: "Recibido"}}
+	if err := repo.KeepReply("wamid.1", response); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	repo.KeepReply("wamid.2", response)
+
+	// The instance handling the redelivery takes the reply
+	other := NewMessageDedupRepository(client, "test:", time.Hour)
+	kept, err := other.TakeReply("wamid.1")
+	if err != nil || kept == nil || kept.To != "user123" || kept.Text.Body != "Recibido" {
+		t.Fatalf("Expected the kept reply, got %+v err=%v", kept, err)
+	}
+	if kept, _ := repo.TakeReply("wamid.1"); kept != nil {
+		t.Errorf("Expected the reply to be taken once, got %+v", kept)
+	}
+
+	server.FastForward(2 * time.Hour)
+	if kept, _ := repo.TakeReply("wamid.2"); kept != nil {
+		t.Errorf("Expected the reply to be forgotten with the message ID, got %+v", kept)
+	}
+}
@@ -0,0 +1,141 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// maxStatusRetries bounds the optimistic locking retries of a status update
+const maxStatusRetries = 5
+
+// MessageStatusRepository implements repository.MessageStatusRepository on a
+// Redis protocol store. Each status is a JSON key expiring after ttl, and failed
+// messages are indexed in a sorted set scored by the time they failed.
+type MessageStatusRepository struct {
+	client    *goredis.Client
+	keyPrefix string
+	ttl       time.Duration
+}
+
+// NewMessageStatusRepository creates a repository remembering statuses for ttl
+func NewMessageStatusRepository(client *goredis.Client, keyPrefix string, ttl time.Duration) *MessageStatusRepository {
+	return &MessageStatusRepository{
+		client:    client,
+		keyPrefix: keyPrefix,
+		ttl:       ttl,
+	}
+}
+
+// Update applies a status change, retrying when another instance updates the same message
+func (r *MessageStatusRepository) Update(status *models.MessageStatus) error {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	key := r.statusKey(status.MessageID)
+	for attempt := 0; attempt < maxStatusRetries; attempt++ {
+		err := r.client.Watch(ctx, func(tx *goredis.Tx) error {
+			return r.update(ctx, tx, key, status)
+		}, key)
+		if err != goredis.TxFailedErr {
+			return err
+		}
+	}
+
+	return fmt.Errorf("failed to update message status: too many concurrent updates")
+}
+
+func (r *MessageStatusRepository) update(ctx context.Context, tx *goredis.Tx, key string, status *models.MessageStatus) error {
+	current, err := r.get(ctx, tx, key)
+	if err != nil && err != errors.ErrMessageNotFound {
+		return err
+	}
+
+	merged, changed := repository.MergeMessageStatus(current, status)
+	if !changed {
+		return nil
+	}
+
+	value, err := json.Marshal(merged)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message status: %v", err)
+	}
+
+	_, err = tx.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.Set(ctx, key, value, r.ttl)
+		if merged.Status == models.DeliveryFailed {
+			pipe.ZAdd(ctx, r.failedKey(), goredis.Z{Score: float64(merged.UpdatedAt.Unix()), Member: merged.MessageID})
+		}
+		return nil
+	})
+	if err != nil && err != goredis.TxFailedErr {
+		return fmt.Errorf("failed to save message status: %v", err)
+	}
+	return err
+}
+
+// Get returns the status of a message
+func (r *MessageStatusRepository) Get(messageID string) (*models.MessageStatus, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	return r.get(ctx, r.client, r.statusKey(messageID))
+}
+
+// ListFailed returns the messages that could not be delivered, most recent first.
+// Statuses that already expired are dropped from the index.
+func (r *MessageStatusRepository) ListFailed() ([]*models.MessageStatus, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	ids, err := r.client.ZRevRange(ctx, r.failedKey(), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list failed messages: %v", err)
+	}
+
+	var statuses []*models.MessageStatus
+	for _, id := range ids {
+		status, err := r.get(ctx, r.client, r.statusKey(id))
+		if err == errors.ErrMessageNotFound {
+			r.client.ZRem(ctx, r.failedKey(), id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+func (r *MessageStatusRepository) get(ctx context.Context, client goredis.Cmdable, key string) (*models.MessageStatus, error) {
+	value, err := client.Get(ctx, key).Bytes()
+	if err == goredis.Nil {
+		return nil, errors.ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message status: %v", err)
+	}
+
+	var status models.MessageStatus
+	if err := json.Unmarshal(value, &status); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal message status: %v", err)
+	}
+	return &status, nil
+}
+
+func (r *MessageStatusRepository) statusKey(messageID string) string {
+	return r.keyPrefix + "status:" + messageID
+}
+
+func (r *MessageStatusRepository) failedKey() string {
+	return r.keyPrefix + "status-failed"
+}
@@ -0,0 +1,41 @@
+package redis
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	domainerrors "chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/domain/models"
+)
+
+func TestMessageStatusRepository_Update(t *testing.T) {
+	_, client := newTestClient(t)
+	repo := NewMessageStatusRepository(client, "test:", time.Hour)
+	now := time.Now()
+
+	repo.Update(&models.MessageStatus{MessageID: "wamid.1", Status: models.DeliveryDelivered, UpdatedAt: now})
+	// The webhook arrived before the API call returned, keep the later status
+	repo.Update(&models.MessageStatus{MessageID: "wamid.1", UserID: "user123", Status: models.DeliveryAccepted, AcceptedAt: now, UpdatedAt: now})
+
+	status, err := repo.Get("wamid.1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if status.Status != models.DeliveryDelivered || status.UserID != "user123" || status.AcceptedAt.IsZero() {
+		t.Errorf("Expected delivered message of user123 with accepted time, got %+v", status)
+	}
+
+	repo.Update(&models.MessageStatus{MessageID: "wamid.1", Status: models.DeliveryFailed, ErrorCode: 131047, UpdatedAt: now})
+	failed, err := repo.ListFailed()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(failed) != 1 || failed[0].ErrorCode != 131047 {
+		t.Errorf("Expected one failed message, got %+v", failed)
+	}
+
+	if _, err := repo.Get("unknown"); !errors.Is(err, domainerrors.ErrMessageNotFound) {
+		t.Errorf("Expected ErrMessageNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package redis
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// ReminderRepository implements repository.ReminderRepository on a Redis
+// protocol store. Each pending reminder is a JSON key indexed in a sorted set
+// scored by its due time. Reminders are forgotten once sent or cancelled.
+type ReminderRepository struct {
+	client    *goredis.Client
+	keyPrefix string
+}
+
+// NewReminderRepository creates a new Redis reminder repository
+func NewReminderRepository(client *goredis.Client, keyPrefix string) *ReminderRepository {
+	return &ReminderRepository{
+		client:    client,
+		keyPrefix: keyPrefix,
+	}
+}
+
+// Schedule saves a pending reminder, replacing a pending one with the same ID
+func (r *ReminderRepository) Schedule(reminder *models.Reminder) error {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	pending := *reminder
+	pending.Status = models.ReminderPending
+	value, err := json.Marshal(&pending)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reminder: %v", err)
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.Set(ctx, r.reminderKey(pending.ID), value, 0)
+		pipe.ZAdd(ctx, r.dueKey(), goredis.Z{Score: float64(pending.DueAt.Unix()), Member: pending.ID})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule reminder: %v", err)
+	}
+	return nil
+}
+
+// Cancel drops a pending reminder
+func (r *ReminderRepository) Cancel(id string) error {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	_, err := r.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.ZRem(ctx, r.dueKey(), id)
+		pipe.Del(ctx, r.reminderKey(id))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to drop reminder: %v", err)
+	}
+	return nil
+}
+
+// ListDue returns up to limit pending reminders due at now, earliest first
+func (r *ReminderRepository) ListDue(now time.Time, limit int) ([]*models.Reminder, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	ids, err := r.client.ZRangeByScore(ctx, r.dueKey(), &goredis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.Unix(), 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due reminders: %v", err)
+	}
+
+	var reminders []*models.Reminder
+	for _, id := range ids {
+		value, err := r.client.Get(ctx, r.reminderKey(id)).Bytes()
+		if err == goredis.Nil {
+			r.client.ZRem(ctx, r.dueKey(), id)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get reminder: %v", err)
+		}
+
+		var reminder models.Reminder
+		if err := json.Unmarshal(value, &reminder); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal reminder: %v", err)
+		}
+		// Scores only have a precision of seconds
+		if reminder.DueAt.After(now) {
+			continue
+		}
+		reminders = append(reminders, &reminder)
+	}
+
+	return reminders, nil
+}
+
+// Claim forgets a pending reminder, reporting whether it was still pending.
+// Removing it from the due index is atomic, so one instance wins each reminder.
+func (r *ReminderRepository) Claim(id string, sentAt time.Time) (bool, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	removed, err := r.client.ZRem(ctx, r.dueKey(), id).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim reminder: %v", err)
+	}
+	if removed == 0 {
+		return false, nil
+	}
+
+	if err := r.client.Del(ctx, r.reminderKey(id)).Err(); err != nil {
+		return true, fmt.Errorf("failed to delete claimed reminder: %v", err)
+	}
+	return true, nil
+}
+
+func (r *ReminderRepository) reminderKey(id string) string {
+	return r.keyPrefix + "reminder:" + id
+}
+
+func (r *ReminderRepository) dueKey() string {
+	return r.keyPrefix + "reminders-due"
+}
@@ -0,0 +1,121 @@
+package redis
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"chatbot-wsp/internal/domain/models"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// TranscriptRepository implements repository.TranscriptRepository on a Redis
+// protocol store. Each user's transcript is a list of JSON entries without
+// expiration, since it is part of the patient's record. The delivery of
+// outbound entries is kept in a hash per user and merged when listing, so the
+// list is never rewritten.
+type TranscriptRepository struct {
+	client    *goredis.Client
+	keyPrefix string
+}
+
+// NewTranscriptRepository creates a new Redis transcript repository
+func NewTranscriptRepository(client *goredis.Client, keyPrefix string) *TranscriptRepository {
+	return &TranscriptRepository{
+		client:    client,
+		keyPrefix: keyPrefix,
+	}
+}
+
+// Append adds a message to the transcript of its user
+func (r *TranscriptRepository) Append(entry *models.TranscriptEntry) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transcript entry: %v", err)
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	if err := r.client.RPush(ctx, r.transcriptKey(entry.UserID), value).Err(); err != nil {
+		return fmt.Errorf("failed to append transcript entry: %v", err)
+	}
+	return nil
+}
+
+// ListByUser returns the transcript of a user, oldest message first
+func (r *TranscriptRepository) ListByUser(userID string) ([]*models.TranscriptEntry, error) {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	values, err := r.client.LRange(ctx, r.transcriptKey(userID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transcript: %v", err)
+	}
+	deliveries, err := r.client.HGetAll(ctx, r.deliveryKey(userID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transcript deliveries: %v", err)
+	}
+
+	entries := make([]*models.TranscriptEntry, 0, len(values))
+	for _, value := range values {
+		var entry models.TranscriptEntry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal transcript entry: %v", err)
+		}
+		if value, ok := deliveries[entry.ID]; ok && entry.ID != "" {
+			var update transcriptDelivery
+			if err := json.Unmarshal([]byte(value), &update); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal transcript delivery: %v", err)
+			}
+			if update.MessageID != "" {
+				entry.MessageID = update.MessageID
+			}
+			entry.Status = update.Status
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, nil
+}
+
+// transcriptDelivery is the delivery of an outbound entry
+type transcriptDelivery struct {
+	MessageID string `json:"message_id,omitempty"`
+	Status    string `json:"status"`
+}
+
+// UpdateDelivery records the delivery status of an outbound entry
+func (r *TranscriptRepository) UpdateDelivery(userID, entryID, messageID, status string) error {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	key := r.deliveryKey(userID)
+	update := transcriptDelivery{MessageID: messageID, Status: status}
+	if messageID == "" {
+		// Keep a message ID recorded earlier
+		if value, err := r.client.HGet(ctx, key, entryID).Result(); err == nil {
+			var previous transcriptDelivery
+			if json.Unmarshal([]byte(value), &previous) == nil {
+				update.MessageID = previous.MessageID
+			}
+		}
+	}
+
+	value, err := json.Marshal(&update)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transcript delivery: %v", err)
+	}
+	if err := r.client.HSet(ctx, key, entryID, value).Err(); err != nil {
+		return fmt.Errorf("failed to update transcript entry: %v", err)
+	}
+	return nil
+}
+
+func (r *TranscriptRepository) transcriptKey(userID string) string {
+	return r.keyPrefix + "transcript:" + userID
+}
+
+func (r *TranscriptRepository) deliveryKey(userID string) string {
+	return r.keyPrefix + "transcript-delivery:" + userID
+}
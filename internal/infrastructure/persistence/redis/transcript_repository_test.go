@@ -0,0 +1,62 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+func TestTranscriptRepository_AppendAndList(t *testing.T) {
+	_, client := newTestClient(t)
+	repo := NewTranscriptRepository(client, "test:")
+
+	repo.Append(&models.TranscriptEntry{UserID: "user123", MessageID: "wamid.1", Direction: models.DirectionInbound, Type: "image", MediaRef: "media/abc.jpg", Timestamp: time.Now()})
+	repo.Append(&models.TranscriptEntry{UserID: "user123", Direction: models.DirectionOutbound, Type: "text", Text: "Recibido", Timestamp: time.Now()})
+
+	transcript, err := repo.ListByUser("user123")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(transcript) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(transcript))
+	}
+	if transcript[0].MediaRef != "media/abc.jpg" || transcript[1].Text != "Recibido" {
+		t.Errorf("Expected entries in arrival order, got %+v, %+v", transcript[0], transcript[1])
+	}
+
+	empty, err := repo.ListByUser("unknown")
+	if err != nil || len(empty) != 0 {
+		t.Errorf("Expected an empty transcript, got %v (%v)", empty, err)
+	}
+}
+
+func TestTranscriptRepository_UpdateDelivery(t *testing.T) {
+	_, client := newTestClient(t)
+	repo := NewTranscriptRepository(client, "test:")
+	now := time.Now()
+	repo.Append(&models.TranscriptEntry{UserID: "user123", MessageID: "wamid.in", Direction: models.DirectionInbound, Type: "text", Text: "hola", Timestamp: now})
+	repo.Append(&models.TranscriptEntry{ID: "entry1", UserID: "user123", Direction: models.DirectionOutbound, Type: "text", Text: "Bienvenido", Status: models.DeliveryQueued, Timestamp: now})
+	repo.Append(&models.TranscriptEntry{ID: "entry2", UserID: "user123", Direction: models.DirectionOutbound, Type: "text", Text: "Recibido", Status: models.DeliveryQueued, Timestamp: now})
+
+	if err := repo.UpdateDelivery("user123", "entry1", "wamid.1", models.DeliveryAccepted); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := repo.UpdateDelivery("user123", "entry2", "", models.DeliveryFailed); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	transcript, err := repo.ListByUser("user123")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(transcript) != 3 || transcript[0].MessageID != "wamid.in" || transcript[0].Status != "" {
+		t.Fatalf("Expected the inbound message untouched, got %+v", transcript)
+	}
+	if transcript[1].ID != "entry1" || transcript[1].MessageID != "wamid.1" || transcript[1].Status != models.DeliveryAccepted {
+		t.Errorf("Expected the accepted reply with its message ID, got %+v", transcript[1])
+	}
+	if transcript[2].MessageID != "" || transcript[2].Status != models.DeliveryFailed || transcript[2].Text != "Recibido" {
+		t.Errorf("Expected the failed reply kept, got %+v", transcript[2])
+	}
+}
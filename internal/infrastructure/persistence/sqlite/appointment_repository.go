@@ -0,0 +1,119 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
+)
+
+// AppointmentRepository implements repository.AppointmentRepository on SQLite.
+// A unique index on booked slots guarantees a slot is never booked twice, even
+// by concurrent conversations.
+type AppointmentRepository struct {
+	db *sql.DB
+}
+
+// NewAppointmentRepository creates a new SQLite appointment repository
+func NewAppointmentRepository(db *sql.DB) *AppointmentRepository {
+	return &AppointmentRepository{db: db}
+}
+
+const appointmentColumns = `id, user_id, patient_name, location_id, location_name, start_at, end_at, status, created_at, cancelled_at`
+
+// Book reserves the slot of an appointment
+func (r *AppointmentRepository) Book(appointment *models.Appointment) error {
+	id := appointment.ID
+	if id == "" {
+		id = repository.NewAppointmentID()
+	}
+
+	_, err := r.db.Exec(`INSERT INTO appointments (`+appointmentColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 0)`,
+		id, appointment.UserID, appointment.PatientName, appointment.LocationID, appointment.LocationName,
+		toUnix(appointment.Start), toUnix(appointment.End), models.AppointmentBooked, toUnix(appointment.CreatedAt))
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return errors.ErrSlotTaken
+		}
+		return fmt.Errorf("failed to book appointment: %v", err)
+	}
+
+	appointment.ID = id
+	appointment.Status = models.AppointmentBooked
+	return nil
+}
+
+// Cancel frees the slot of a booked appointment
+func (r *AppointmentRepository) Cancel(id string, cancelledAt time.Time) (*models.Appointment, error) {
+	result, err := r.db.Exec(`UPDATE appointments SET status = ?, cancelled_at = ? WHERE id = ? AND status = ?`,
+		models.AppointmentCancelled, toUnix(cancelledAt), id, models.AppointmentBooked)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel appointment: %v", err)
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return nil, errors.ErrAppointmentNotFound
+	}
+
+	appointment, err := scanAppointment(r.db.QueryRow(`SELECT `+appointmentColumns+` FROM appointments WHERE id = ?`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get appointment: %v", err)
+	}
+	return appointment, nil
+}
+
+// ListByUser returns the booked appointments of a user starting after from
+func (r *AppointmentRepository) ListByUser(userID string, from time.Time) ([]*models.Appointment, error) {
+	return r.list(`WHERE user_id = ? AND status = ? AND start_at > ? ORDER BY start_at`,
+		userID, models.AppointmentBooked, toUnix(from))
+}
+
+// ListBooked returns the booked appointments starting in [from, to)
+func (r *AppointmentRepository) ListBooked(from, to time.Time) ([]*models.Appointment, error) {
+	return r.list(`WHERE status = ? AND start_at >= ? AND start_at < ? ORDER BY start_at`,
+		models.AppointmentBooked, toUnix(from), toUnix(to))
+}
+
+func (r *AppointmentRepository) list(where string, args ...interface{}) ([]*models.Appointment, error) {
+	rows, err := r.db.Query(`SELECT `+appointmentColumns+` FROM appointments `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list appointments: %v", err)
+	}
+	defer rows.Close()
+
+	var appointments []*models.Appointment
+	for rows.Next() {
+		appointment, err := scanAppointment(rows)
+		if err != nil {
+			return nil, err
+		}
+		appointments = append(appointments, appointment)
+	}
+
+	return appointments, rows.Err()
+}
+
+func scanAppointment(row rowScanner) (*models.Appointment, error) {
+	var (
+		appointment                            models.Appointment
+		startAt, endAt, createdAt, cancelledAt int64
+	)
+
+	if err := row.Scan(&appointment.ID, &appointment.UserID, &appointment.PatientName, &appointment.LocationID,
+		&appointment.LocationName, &startAt, &endAt, &appointment.Status, &createdAt, &cancelledAt); err != nil {
+		return nil, err
+	}
+
+	appointment.Start = fromUnix(startAt)
+	appointment.End = fromUnix(endAt)
+	appointment.CreatedAt = fromUnix(createdAt)
+	if cancelledAt != 0 {
+		appointment.CancelledAt = fromUnix(cancelledAt)
+	}
+
+	return &appointment, nil
+}
@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	domainerrors "chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/domain/models"
+)
+
+func TestAppointmentRepository_BookAndCancel(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "chatbot.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewAppointmentRepository(db)
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Minute)
+
+	first := &models.Appointment{UserID: "user123", PatientName: "Juan Pérez", LocationID: "cervantes", LocationName: "Centro Médico Cervantes",
+		Start: start, End: start.Add(20 * time.Minute), CreatedAt: time.Now()}
+	if err := repo.Book(first); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first.ID == "" || first.Status != models.AppointmentBooked {
+		t.Errorf("Expected a booked appointment with an ID, got %+v", first)
+	}
+
+	// Same slot, another patient
+	second := &models.Appointment{UserID: "user456", LocationID: "cervantes", Start: start, End: start.Add(20 * time.Minute), CreatedAt: time.Now()}
+	if err := repo.Book(second); !errors.Is(err, domainerrors.ErrSlotTaken) {
+		t.Fatalf("Expected ErrSlotTaken, got %v", err)
+	}
+
+	// Same time at another location is a different slot
+	other := &models.Appointment{UserID: "user456", LocationID: "ospep", Start: start, End: start.Add(30 * time.Minute), CreatedAt: time.Now()}
+	if err := repo.Book(other); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	booked, err := repo.ListByUser("user123", time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(booked) != 1 || booked[0].ID != first.ID || booked[0].PatientName != "Juan Pérez" || !booked[0].Start.Equal(start) {
+		t.Errorf("Expected the appointment of user123, got %+v", booked)
+	}
+
+	cancelled, err := repo.Cancel(first.ID, time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cancelled.Status != models.AppointmentCancelled || cancelled.CancelledAt.IsZero() {
+		t.Errorf("Expected a cancelled appointment, got %+v", cancelled)
+	}
+	if _, err := repo.Cancel(first.ID, time.Now()); !errors.Is(err, domainerrors.ErrAppointmentNotFound) {
+		t.Errorf("Expected ErrAppointmentNotFound cancelling twice, got %v", err)
+	}
+
+	// The cancelled slot can be booked again
+	if err := repo.Book(second); err != nil {
+		t.Fatalf("Expected the freed slot to be bookable, got %v", err)
+	}
+
+	agenda, err := repo.ListBooked(start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(agenda) != 2 {
+		t.Errorf("Expected 2 booked appointments, got %+v", agenda)
+	}
+}
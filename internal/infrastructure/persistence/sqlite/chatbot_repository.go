@@ -0,0 +1,244 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
+	"chatbot-wsp/internal/infrastructure/logger"
+)
+
+// ChatbotRepository implements repository.ChatbotRepository on SQLite
+type ChatbotRepository struct {
+	*repository.FlowCatalog
+	db              *sql.DB
+	mutex           sync.RWMutex
+	stopCleanup     chan bool
+	expirationHours int
+}
+
+// NewChatbotRepository creates a new SQLite repository serving the given flows
+func NewChatbotRepository(db *sql.DB, flows map[string]*models.ChatbotFlow) *ChatbotRepository {
+	return &ChatbotRepository{
+		FlowCatalog:     repository.NewFlowCatalog(flows),
+		db:              db,
+		stopCleanup:     make(chan bool),
+		expirationHours: 24, // Default to 24 hours
+	}
+}
+
+// GetUserState retrieves the current state of a user
+func (r *ChatbotRepository) GetUserState(userID string) (*models.ChatbotState, error) {
+	row := r.db.QueryRow(`SELECT `+userStateColumns+`
+		FROM user_states WHERE user_id = ?`, userID)
+
+	state, err := scanUserState(row)
+	if err == sql.ErrNoRows {
+		return repository.NewUserState(userID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if session has expired (lazy cleanup)
+	if repository.IsSessionExpired(state, r.getExpirationHours()) {
+		return repository.NewUserState(userID), nil
+	}
+
+	return state, nil
+}
+
+// SaveUserState saves the current state of a user
+func (r *ChatbotRepository) SaveUserState(state *models.ChatbotState) error {
+	data, err := json.Marshal(state.Data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal user data: %v", err)
+	}
+	history, err := json.Marshal(state.History)
+	if err != nil {
+		return fmt.Errorf("failed to marshal user history: %v", err)
+	}
+	booking, err := json.Marshal(state.Booking)
+	if err != nil {
+		return fmt.Errorf("failed to marshal booking progress: %v", err)
+	}
+
+	var handoffAt, emergencyAt int64
+	if state.Handoff {
+		handoffAt = toUnix(state.HandoffAt)
+	}
+	if state.Emergency {
+		emergencyAt = toUnix(state.EmergencyAt)
+	}
+
+	_, err = r.db.Exec(`INSERT INTO user_states (user_id, state, option, data, field_index, history, invalid_attempts, booking, handoff, handoff_at, emergency, emergency_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			state = excluded.state,
+			option = excluded.option,
+			data = excluded.data,
+			field_index = excluded.field_index,
+			history = excluded.history,
+			invalid_attempts = excluded.invalid_attempts,
+			booking = excluded.booking,
+			handoff = excluded.handoff,
+			handoff_at = excluded.handoff_at,
+			emergency = excluded.emergency,
+			emergency_at = excluded.emergency_at,
+			created_at = excluded.created_at,
+			updated_at = excluded.updated_at`,
+		state.UserID, state.State, state.Option, string(data), state.FieldIndex, string(history), state.InvalidAttempts, string(booking), state.Handoff, handoffAt,
+		state.Emergency, emergencyAt, toUnix(state.CreatedAt), toUnix(state.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to save user state: %v", err)
+	}
+
+	return nil
+}
+
+// ListUserStates returns the sessions that have not expired, most recently active first
+func (r *ChatbotRepository) ListUserStates() ([]*models.ChatbotState, error) {
+	cutoff := time.Now().Add(-time.Duration(r.getExpirationHours()) * time.Hour)
+	rows, err := r.db.Query(`SELECT `+userStateColumns+`
+		FROM user_states WHERE updated_at >= ? ORDER BY updated_at DESC`, toUnix(cutoff))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user states: %v", err)
+	}
+	defer rows.Close()
+
+	var states []*models.ChatbotState
+	for rows.Next() {
+		state, err := scanUserState(rows)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+
+	return states, rows.Err()
+}
+
+// CountUserStates returns how many sessions have not expired
+func (r *ChatbotRepository) CountUserStates() (int, error) {
+	cutoff := time.Now().Add(-time.Duration(r.getExpirationHours()) * time.Hour)
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM user_states WHERE updated_at >= ?`, toUnix(cutoff)).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count user states: %v", err)
+	}
+	return count, nil
+}
+
+// DeleteUserState removes the session of a user
+func (r *ChatbotRepository) DeleteUserState(userID string) error {
+	cutoff := time.Now().Add(-time.Duration(r.getExpirationHours()) * time.Hour)
+	result, err := r.db.Exec(`DELETE FROM user_states WHERE user_id = ? AND updated_at >= ?`, userID, toUnix(cutoff))
+	if err != nil {
+		return fmt.Errorf("failed to delete user state: %v", err)
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		// Drop an expired session the cleanup has not reached yet
+		if _, err := r.db.Exec(`DELETE FROM user_states WHERE user_id = ?`, userID); err != nil {
+			return fmt.Errorf("failed to delete user state: %v", err)
+		}
+		return errors.ErrUserNotFound
+	}
+	return nil
+}
+
+// StartSessionCleanup starts the background cleanup goroutine
+func (r *ChatbotRepository) StartSessionCleanup(expirationHours, cleanupIntervalMin int) {
+	r.mutex.Lock()
+	r.expirationHours = expirationHours
+	r.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(cleanupIntervalMin) * time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := r.cleanupExpiredSessions(); err != nil {
+					logger.GetLogger().WithError(err).Error("Failed to clean up expired sessions")
+				}
+			case <-r.stopCleanup:
+				return
+			}
+		}
+	}()
+}
+
+// StopSessionCleanup stops the background cleanup goroutine
+func (r *ChatbotRepository) StopSessionCleanup() {
+	close(r.stopCleanup)
+}
+
+// cleanupExpiredSessions deletes sessions not updated within the expiration window
+func (r *ChatbotRepository) cleanupExpiredSessions() error {
+	cutoff := time.Now().Add(-time.Duration(r.getExpirationHours()) * time.Hour)
+	result, err := r.db.Exec(`DELETE FROM user_states WHERE updated_at < ?`, toUnix(cutoff))
+	if err != nil {
+		return err
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted > 0 {
+		logger.GetLogger().WithField("sessions", deleted).Info("Cleaned up expired sessions")
+	}
+	return nil
+}
+
+func (r *ChatbotRepository) getExpirationHours() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.expirationHours
+}
+
+// userStateColumns are the columns read by scanUserState, in order
+const userStateColumns = `user_id, state, option, data, field_index, history, invalid_attempts, booking, handoff, handoff_at, emergency, emergency_at, created_at, updated_at`
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUserState(row rowScanner) (*models.ChatbotState, error) {
+	var (
+		state                                        models.ChatbotState
+		data, history, booking                       string
+		handoffAt, emergencyAt, createdAt, updatedAt int64
+	)
+
+	if err := row.Scan(&state.UserID, &state.State, &state.Option, &data, &state.FieldIndex, &history, &state.InvalidAttempts, &booking,
+		&state.Handoff, &handoffAt, &state.Emergency, &emergencyAt, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(data), &state.Data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user data: %v", err)
+	}
+	if state.Data == nil {
+		state.Data = make(map[string]string)
+	}
+	if err := json.Unmarshal([]byte(history), &state.History); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user history: %v", err)
+	}
+	if err := json.Unmarshal([]byte(booking), &state.Booking); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal booking progress: %v", err)
+	}
+	if state.Handoff {
+		state.HandoffAt = fromUnix(handoffAt)
+	}
+	if state.Emergency {
+		state.EmergencyAt = fromUnix(emergencyAt)
+	}
+	state.CreatedAt = fromUnix(createdAt)
+	state.UpdatedAt = fromUnix(updatedAt)
+
+	return &state, nil
+}
@@ -0,0 +1,187 @@
+package sqlite
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	domainerrors "chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/domain/models"
+)
+
+func newTestRepository(t *testing.T) (*ChatbotRepository, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chatbot.db")
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	flows := map[string]*models.ChatbotFlow{
+		"welcome": {State: "welcome", Message: "Hola"},
+	}
+	return NewChatbotRepository(db, flows), path
+}
+
+func TestChatbotRepository_SaveAndGetUserState(t *testing.T) {
+	repo, _ := newTestRepository(t)
+
+	state, err := repo.GetUserState("user123")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if state.State != "welcome" || len(state.Data) != 0 {
+		t.Errorf("Expected a fresh welcome state, got %+v", state)
+	}
+
+	state.State = "option_a"
+	state.Option = "A"
+	state.Data["datos_consulta_medica"] = "Juan, 3 años"
+	state.FieldIndex = 2
+	state.History = []string{"welcome", "option_a"}
+	state.InvalidAttempts = 1
+	state.Handoff = true
+	state.HandoffAt = time.Now().Add(-time.Minute)
+	state.Emergency = true
+	state.EmergencyAt = time.Now().Add(-2 * time.Minute)
+	state.Booking = &models.BookingProgress{Step: "patient", LocationID: "cervantes"}
+	state.UpdatedAt = time.Now()
+	if err := repo.SaveUserState(state); err != nil {
+		t.Fatalf("Unexpected error saving state: %v", err)
+	}
+
+	stored, err := repo.GetUserState("user123")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stored.State != "option_a" || stored.Option != "A" || stored.Data["datos_consulta_medica"] != "Juan, 3 años" || stored.FieldIndex != 2 || len(stored.History) != 2 || stored.InvalidAttempts != 1 {
+		t.Errorf("Stored state does not match, got %+v", stored)
+	}
+	if !stored.Handoff || !stored.HandoffAt.Equal(state.HandoffAt) {
+		t.Errorf("Expected handoff since %v, got %v since %v", state.HandoffAt, stored.Handoff, stored.HandoffAt)
+	}
+	if !stored.Emergency || !stored.EmergencyAt.Equal(state.EmergencyAt) {
+		t.Errorf("Expected emergency at %v, got %v at %v", state.EmergencyAt, stored.Emergency, stored.EmergencyAt)
+	}
+	if stored.Booking == nil || stored.Booking.Step != "patient" || stored.Booking.LocationID != "cervantes" {
+		t.Errorf("Expected the booking progress, got %+v", stored.Booking)
+	}
+	if !stored.UpdatedAt.Equal(state.UpdatedAt) {
+		t.Errorf("Expected UpdatedAt %v, got %v", state.UpdatedAt, stored.UpdatedAt)
+	}
+}
+
+func TestChatbotRepository_ListAndDelete(t *testing.T) {
+	repo, _ := newTestRepository(t)
+
+	now := time.Now()
+	states := []*models.ChatbotState{
+		{UserID: "user1", State: "option_a", CreatedAt: now, UpdatedAt: now.Add(-time.Minute)},
+		{UserID: "user2", State: "option_b", CreatedAt: now, UpdatedAt: now},
+		{UserID: "expired", State: "option_c", CreatedAt: now, UpdatedAt: now.Add(-48 * time.Hour)},
+	}
+	for _, state := range states {
+		repo.SaveUserState(state)
+	}
+
+	listed, err := repo.ListUserStates()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(listed) != 2 || listed[0].UserID != "user2" || listed[1].UserID != "user1" {
+		t.Fatalf("Expected active sessions most recent first, got %+v", listed)
+	}
+	if count, err := repo.CountUserStates(); err != nil || count != 2 {
+		t.Errorf("Expected 2 active sessions, got %d (%v)", count, err)
+	}
+
+	if err := repo.DeleteUserState("user2"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := repo.DeleteUserState("user2"); !errors.Is(err, domainerrors.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound deleting a missing session, got %v", err)
+	}
+
+	listed, _ = repo.ListUserStates()
+	if len(listed) != 1 || listed[0].UserID != "user1" {
+		t.Errorf("Expected only user1 after delete, got %+v", listed)
+	}
+
+	// An expired session is removed, but reported as missing
+	if err := repo.DeleteUserState("expired"); !errors.Is(err, domainerrors.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound deleting an expired session, got %v", err)
+	}
+	var count int
+	repo.db.QueryRow(`SELECT COUNT(*) FROM user_states WHERE user_id = 'expired'`).Scan(&count)
+	if count != 0 {
+		t.Errorf("Expected the expired session to be removed, got %d rows", count)
+	}
+}
+
+func TestChatbotRepository_SessionExpiration(t *testing.T) {
+	repo, _ := newTestRepository(t)
+
+	oldTime := time.Now().Add(-25 * time.Hour)
+	repo.SaveUserState(&models.ChatbotState{
+		UserID:    "expired",
+		State:     "collecting_data",
+		Data:      map[string]string{"test": "data"},
+		CreatedAt: oldTime,
+		UpdatedAt: oldTime,
+	})
+	repo.SaveUserState(&models.ChatbotState{
+		UserID:    "active",
+		State:     "option_b",
+		Data:      map[string]string{},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+
+	state, _ := repo.GetUserState("expired")
+	if state.State != "welcome" || len(state.Data) != 0 {
+		t.Errorf("Expected expired session to be reset, got %+v", state)
+	}
+
+	if err := repo.cleanupExpiredSessions(); err != nil {
+		t.Fatalf("Unexpected cleanup error: %v", err)
+	}
+
+	var count int
+	repo.db.QueryRow(`SELECT COUNT(*) FROM user_states`).Scan(&count)
+	if count != 1 {
+		t.Errorf("Expected only the active session to remain, got %d rows", count)
+	}
+}
+
+func TestOpen_PersistsAcrossRestartsAndMigratesOnce(t *testing.T) {
+	repo, path := newTestRepository(t)
+	repo.SaveUserState(&models.ChatbotState{
+		UserID:    "user123",
+		State:     "option_d",
+		Data:      map[string]string{"datos_babyhome": "semana 30"},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	repo.db.Close()
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	var version int
+	db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version)
+	if version != len(migrations) {
+		t.Errorf("Expected schema version %d, got %d", len(migrations), version)
+	}
+
+	reopened := NewChatbotRepository(db, nil)
+	state, _ := reopened.GetUserState("user123")
+	if state.State != "option_d" || state.Data["datos_babyhome"] != "semana 30" {
+		t.Errorf("Expected state to survive a restart, got %+v", state)
+	}
+}
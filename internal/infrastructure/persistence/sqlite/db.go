@@ -0,0 +1,211 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"chatbot-wsp/internal/infrastructure/logger"
+
+	"github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
+)
+
+// migrations are applied in order; the schema version is the index of the last applied one plus one.
+// Never edit an existing migration, append a new one instead.
+var migrations = []string{
+	// 1: conversation state per user
+	`CREATE TABLE user_states (
+		user_id    TEXT PRIMARY KEY,
+		state      TEXT NOT NULL,
+		option     TEXT NOT NULL DEFAULT '',
+		data       TEXT NOT NULL DEFAULT '{}',
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+	CREATE INDEX idx_user_states_updated_at ON user_states (updated_at);`,
+
+	// 2: position in a flow that collects several fields
+	`ALTER TABLE user_states ADD COLUMN field_index INTEGER NOT NULL DEFAULT 0;`,
+
+	// 3: conversations handed to staff
+	`ALTER TABLE user_states ADD COLUMN handoff INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE user_states ADD COLUMN handoff_at INTEGER NOT NULL DEFAULT 0;`,
+
+	// 4: transcript of every message exchanged with patients
+	`CREATE TABLE transcript_entries (
+		id           INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id      TEXT NOT NULL,
+		message_id   TEXT NOT NULL DEFAULT '',
+		direction    TEXT NOT NULL,
+		type         TEXT NOT NULL DEFAULT '',
+		text         TEXT NOT NULL DEFAULT '',
+		media_ref    TEXT NOT NULL DEFAULT '',
+		state_before TEXT NOT NULL DEFAULT '',
+		state_after  TEXT NOT NULL DEFAULT '',
+		created_at   INTEGER NOT NULL
+	);
+	CREATE INDEX idx_transcript_entries_user_id ON transcript_entries (user_id, id);`,
+
+	// 5: sessions flagged by the emergency detector
+	`ALTER TABLE user_states ADD COLUMN emergency INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE user_states ADD COLUMN emergency_at INTEGER NOT NULL DEFAULT 0;`,
+
+	// 6: navigation history used by the back command
+	`ALTER TABLE user_states ADD COLUMN history TEXT NOT NULL DEFAULT '[]';`,
+
+	// 7: consecutive invalid answers, used to escalate to staff
+	`ALTER TABLE user_states ADD COLUMN invalid_attempts INTEGER NOT NULL DEFAULT 0;`,
+
+	// 8: delivery status of outbound messages
+	`CREATE TABLE message_statuses (
+		message_id  TEXT PRIMARY KEY,
+		user_id     TEXT NOT NULL DEFAULT '',
+		status      TEXT NOT NULL,
+		error_code  INTEGER NOT NULL DEFAULT 0,
+		error_title TEXT NOT NULL DEFAULT '',
+		accepted_at INTEGER NOT NULL DEFAULT 0,
+		updated_at  INTEGER NOT NULL
+	);
+	CREATE INDEX idx_message_statuses_status ON message_statuses (status, updated_at);`,
+
+	// 9: last message received from each user, opening the 24 hour service window
+	`ALTER TABLE user_states ADD COLUMN last_inbound_at INTEGER NOT NULL DEFAULT 0;`,
+
+	// 10: appointments booked in the chat, at most one booked appointment per slot
+	`CREATE TABLE appointments (
+		id            TEXT PRIMARY KEY,
+		user_id       TEXT NOT NULL,
+		patient_name  TEXT NOT NULL DEFAULT '',
+		location_id   TEXT NOT NULL,
+		location_name TEXT NOT NULL DEFAULT '',
+		start_at      INTEGER NOT NULL,
+		end_at        INTEGER NOT NULL,
+		status        TEXT NOT NULL,
+		created_at    INTEGER NOT NULL,
+		cancelled_at  INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE UNIQUE INDEX idx_appointments_slot ON appointments (location_id, start_at) WHERE status = 'booked';
+	CREATE INDEX idx_appointments_start_at ON appointments (status, start_at);
+	CREATE INDEX idx_appointments_user_id ON appointments (user_id, start_at);
+	ALTER TABLE user_states ADD COLUMN booking TEXT NOT NULL DEFAULT 'null';`,
+
+	// 11: reminders scheduled for patients, kept after they are sent
+	`CREATE TABLE reminders (
+		id             TEXT PRIMARY KEY,
+		kind           TEXT NOT NULL,
+		user_id        TEXT NOT NULL,
+		appointment_id TEXT NOT NULL DEFAULT '',
+		text           TEXT NOT NULL,
+		due_at         INTEGER NOT NULL,
+		expires_at     INTEGER NOT NULL DEFAULT 0,
+		status         TEXT NOT NULL,
+		created_at     INTEGER NOT NULL,
+		sent_at        INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX idx_reminders_due ON reminders (status, due_at);`,
+
+	// 12: delivery of outbound transcript entries, recorded when they are queued
+	`ALTER TABLE transcript_entries ADD COLUMN entry_id TEXT NOT NULL DEFAULT '';
+	ALTER TABLE transcript_entries ADD COLUMN status TEXT NOT NULL DEFAULT '';`,
+
+	// 13: last message received from each user, kept apart from sessions that can
+	// expire or be deleted while the 24 hour service window is still open
+	`CREATE TABLE last_inbound (
+		user_id     TEXT PRIMARY KEY,
+		received_at INTEGER NOT NULL
+	);
+	INSERT INTO last_inbound (user_id, received_at) SELECT user_id, last_inbound_at FROM user_states WHERE last_inbound_at > 0;
+	ALTER TABLE user_states DROP COLUMN last_inbound_at;`,
+
+	// 14: messages the outbound queue gave up on, kept for staff to inspect
+	`CREATE TABLE dead_letters (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		response   TEXT NOT NULL,
+		attempts   INTEGER NOT NULL,
+		last_error TEXT NOT NULL DEFAULT '',
+		failed_at  INTEGER NOT NULL
+	);`,
+
+	// 15: reminder delivered by a dead letter, marked sent when it was queued
+	`ALTER TABLE dead_letters ADD COLUMN reminder_id TEXT NOT NULL DEFAULT '';`,
+}
+
+// Open opens the SQLite database at path and applies pending migrations
+func Open(path string) (*sql.DB, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %v", err)
+		}
+	}
+
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+
+	// SQLite allows a single writer, serialize access instead of failing with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// migrate applies the migrations newer than the current schema version
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at INTEGER NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %v", err)
+	}
+
+	for version := current + 1; version <= len(migrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to start migration %d: %v", version, err)
+		}
+
+		if _, err := tx.Exec(migrations[version-1]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %v", version, err)
+		}
+
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, version, time.Now().UnixNano()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %v", version, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %v", version, err)
+		}
+
+		logger.GetLogger().WithFields(logrus.Fields{
+			"version": version,
+		}).Info("Applied database migration")
+	}
+
+	return nil
+}
+
+// toUnix converts a timestamp to the representation stored in the database
+func toUnix(t time.Time) int64 {
+	return t.UnixNano()
+}
+
+// fromUnix converts a stored timestamp back to time.Time
+func fromUnix(nanos int64) time.Time {
+	return time.Unix(0, nanos)
+}
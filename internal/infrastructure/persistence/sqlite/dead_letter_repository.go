@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+// DeadLetterRepository implements repository.DeadLetterRepository on SQLite
+type DeadLetterRepository struct {
+	db       *sql.DB
+	capacity int
+}
+
+// NewDeadLetterRepository creates a new SQLite dead letter repository holding
+// at most capacity letters
+func NewDeadLetterRepository(db *sql.DB, capacity int) *DeadLetterRepository {
+	return &DeadLetterRepository{db: db, capacity: capacity}
+}
+
+// Add stores a dead letter, discarding the oldest ones past the capacity
+func (r *DeadLetterRepository) Add(letter *models.DeadLetter) error {
+	response, err := json.Marshal(letter.Response)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %v", err)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to add dead letter: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO dead_letters (response, attempts, last_error, failed_at, reminder_id) VALUES (?, ?, ?, ?, ?)`,
+		string(response), letter.Attempts, letter.LastError, toUnix(letter.FailedAt), letter.ReminderID); err != nil {
+		return fmt.Errorf("failed to add dead letter: %v", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM dead_letters WHERE id NOT IN (SELECT id FROM dead_letters ORDER BY id DESC LIMIT ?)`,
+		r.capacity); err != nil {
+		return fmt.Errorf("failed to prune dead letters: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to add dead letter: %v", err)
+	}
+	return nil
+}
+
+// List returns the stored dead letters, oldest first
+func (r *DeadLetterRepository) List() ([]*models.DeadLetter, error) {
+	rows, err := r.db.Query(`SELECT response, attempts, last_error, failed_at, reminder_id FROM dead_letters ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %v", err)
+	}
+	defer rows.Close()
+
+	letters := []*models.DeadLetter{}
+	for rows.Next() {
+		var (
+			letter   models.DeadLetter
+			response string
+			failedAt int64
+		)
+		if err := rows.Scan(&response, &letter.Attempts, &letter.LastError, &failedAt, &letter.ReminderID); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(response), &letter.Response); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal dead letter: %v", err)
+		}
+		letter.FailedAt = fromUnix(failedAt)
+		letters = append(letters, &letter)
+	}
+
+	return letters, rows.Err()
+}
@@ -0,0 +1,54 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+func TestDeadLetterRepository_AddAndList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chatbot.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	repo := NewDeadLetterRepository(db, 2)
+	now := time.Now().Truncate(time.Second)
+	for _, to := range []string{"user1", "user2", "user3"} {
+		letter := &models.DeadLetter{
+			Response:   &models.WhatsAppResponse{To: to, Type: "text", Text: models.TextContent{Body: "Hola"}},
+			Attempts:   5,
+			LastError:  "boom",
+			FailedAt:   now,
+			ReminderID: "reminder-" + to,
+		}
+		if err := repo.Add(letter); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	db.Close()
+
+	// Dead letters survive a restart
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	letters, err := NewDeadLetterRepository(db, 2).List()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(letters) != 2 {
+		t.Fatalf("Expected the 2 most recent letters, got %d", len(letters))
+	}
+	if letters[0].Response.To != "user2" || letters[1].Response.To != "user3" {
+		t.Errorf("Expected letters oldest first, got %s, %s", letters[0].Response.To, letters[1].Response.To)
+	}
+	if letters[0].Attempts != 5 || letters[0].LastError != "boom" || !letters[0].FailedAt.Equal(now) || letters[0].ReminderID != "reminder-user2" {
+		t.Errorf("Expected the letter to round trip, got %+v", letters[0])
+	}
+}
@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"chatbot-wsp/internal/infrastructure/logger"
+)
+
+// LastInboundRepository implements repository.LastInboundRepository on SQLite
+type LastInboundRepository struct {
+	db          *sql.DB
+	ttl         time.Duration
+	stopCleanup chan bool
+}
+
+// NewLastInboundRepository creates a repository remembering messages for ttl
+func NewLastInboundRepository(db *sql.DB, ttl time.Duration) *LastInboundRepository {
+	return &LastInboundRepository{
+		db:          db,
+		ttl:         ttl,
+		stopCleanup: make(chan bool),
+	}
+}
+
+// Touch records that the user wrote to us at the given time
+func (r *LastInboundRepository) Touch(userID string, at time.Time) error {
+	_, err := r.db.Exec(`INSERT INTO last_inbound (user_id, received_at) VALUES (?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET received_at = excluded.received_at`, userID, toUnix(at))
+	if err != nil {
+		return fmt.Errorf("failed to record last inbound message: %v", err)
+	}
+	return nil
+}
+
+// LastInbound returns when the user last wrote to us, the zero time if not within the TTL
+func (r *LastInboundRepository) LastInbound(userID string) (time.Time, error) {
+	var receivedAt int64
+	err := r.db.QueryRow(`SELECT received_at FROM last_inbound WHERE user_id = ? AND received_at >= ?`,
+		userID, toUnix(time.Now().Add(-r.ttl))).Scan(&receivedAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get last inbound message: %v", err)
+	}
+	return fromUnix(receivedAt), nil
+}
+
+// StartCleanup starts the background goroutine deleting expired messages
+func (r *LastInboundRepository) StartCleanup(cleanupIntervalMin int) {
+	go func() {
+		ticker := time.NewTicker(time.Duration(cleanupIntervalMin) * time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := r.cleanupExpired(); err != nil {
+					logger.GetLogger().WithError(err).Error("Failed to clean up last inbound messages")
+				}
+			case <-r.stopCleanup:
+				return
+			}
+		}
+	}()
+}
+
+// StopCleanup stops the background cleanup goroutine
+func (r *LastInboundRepository) StopCleanup() {
+	close(r.stopCleanup)
+}
+
+// cleanupExpired deletes messages older than the TTL
+func (r *LastInboundRepository) cleanupExpired() error {
+	_, err := r.db.Exec(`DELETE FROM last_inbound WHERE received_at < ?`, toUnix(time.Now().Add(-r.ttl)))
+	return err
+}
@@ -0,0 +1,43 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLastInboundRepository_Touch(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "chatbot.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewLastInboundRepository(db, time.Hour)
+	if at, err := repo.LastInbound("user123"); err != nil || !at.IsZero() {
+		t.Fatalf("Expected no message from an unknown user, got %v err=%v", at, err)
+	}
+
+	now := time.Now()
+	repo.Touch("user123", now.Add(-time.Minute))
+	if err := repo.Touch("user123", now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	repo.Touch("user456", now.Add(-2*time.Hour))
+
+	if at, _ := repo.LastInbound("user123"); !at.Equal(now) {
+		t.Errorf("Expected the latest message at %v, got %v", now, at)
+	}
+	if at, _ := repo.LastInbound("user456"); !at.IsZero() {
+		t.Errorf("Expected a message older than the TTL to be ignored, got %v", at)
+	}
+
+	if err := repo.cleanupExpired(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var remaining int
+	db.QueryRow(`SELECT COUNT(*) FROM last_inbound`).Scan(&remaining)
+	if remaining != 1 {
+		t.Errorf("Expected only the expired message to be deleted, got %d left", remaining)
+	}
+}
@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
+)
+
+// MessageStatusRepository implements repository.MessageStatusRepository on SQLite
+type MessageStatusRepository struct {
+	db *sql.DB
+}
+
+// NewMessageStatusRepository creates a new SQLite message status repository
+func NewMessageStatusRepository(db *sql.DB) *MessageStatusRepository {
+	return &MessageStatusRepository{db: db}
+}
+
+const messageStatusColumns = `message_id, user_id, status, error_code, error_title, accepted_at, updated_at`
+
+// Update applies a status change
+func (r *MessageStatusRepository) Update(status *models.MessageStatus) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	current, err := scanMessageStatus(tx.QueryRow(`SELECT `+messageStatusColumns+`
+		FROM message_statuses WHERE message_id = ?`, status.MessageID))
+	if err == sql.ErrNoRows {
+		current = nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get message status: %v", err)
+	}
+
+	merged, changed := repository.MergeMessageStatus(current, status)
+	if !changed {
+		return nil
+	}
+
+	var acceptedAt int64
+	if !merged.AcceptedAt.IsZero() {
+		acceptedAt = toUnix(merged.AcceptedAt)
+	}
+
+	_, err = tx.Exec(`INSERT INTO message_statuses (`+messageStatusColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (message_id) DO UPDATE SET
+			user_id = excluded.user_id,
+			status = excluded.status,
+			error_code = excluded.error_code,
+			error_title = excluded.error_title,
+			accepted_at = excluded.accepted_at,
+			updated_at = excluded.updated_at`,
+		merged.MessageID, merged.UserID, merged.Status, merged.ErrorCode, merged.ErrorTitle, acceptedAt, toUnix(merged.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to save message status: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// Get returns the status of a message
+func (r *MessageStatusRepository) Get(messageID string) (*models.MessageStatus, error) {
+	status, err := scanMessageStatus(r.db.QueryRow(`SELECT `+messageStatusColumns+`
+		FROM message_statuses WHERE message_id = ?`, messageID))
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message status: %v", err)
+	}
+	return status, nil
+}
+
+// ListFailed returns the messages that could not be delivered, most recent first
+func (r *MessageStatusRepository) ListFailed() ([]*models.MessageStatus, error) {
+	rows, err := r.db.Query(`SELECT `+messageStatusColumns+`
+		FROM message_statuses WHERE status = ? ORDER BY updated_at DESC`, models.DeliveryFailed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list failed messages: %v", err)
+	}
+	defer rows.Close()
+
+	var statuses []*models.MessageStatus
+	for rows.Next() {
+		status, err := scanMessageStatus(rows)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, rows.Err()
+}
+
+func scanMessageStatus(row rowScanner) (*models.MessageStatus, error) {
+	var (
+		status                models.MessageStatus
+		acceptedAt, updatedAt int64
+	)
+
+	if err := row.Scan(&status.MessageID, &status.UserID, &status.Status, &status.ErrorCode,
+		&status.ErrorTitle, &acceptedAt, &updatedAt); err != nil {
+		return nil, err
+	}
+
+	if acceptedAt != 0 {
+		status.AcceptedAt = fromUnix(acceptedAt)
+	}
+	status.UpdatedAt = fromUnix(updatedAt)
+
+	return &status, nil
+}
@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	domainerrors "chatbot-wsp/internal/domain/errors"
+	"chatbot-wsp/internal/domain/models"
+)
+
+func TestMessageStatusRepository_Update(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "chatbot.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewMessageStatusRepository(db)
+	accepted := time.Now().Truncate(time.Second)
+
+	updates := []*models.MessageStatus{
+		{MessageID: "wamid.1", UserID: "user123", Status: models.DeliveryAccepted, AcceptedAt: accepted, UpdatedAt: accepted},
+		{MessageID: "wamid.1", Status: models.DeliveryRead, UpdatedAt: accepted.Add(2 * time.Second)},
+		// Late webhook, must not move the message back to delivered
+		{MessageID: "wamid.1", Status: models.DeliveryDelivered, UpdatedAt: accepted.Add(time.Second)},
+		{MessageID: "wamid.2", UserID: "user456", Status: models.DeliveryFailed, ErrorCode: 131026, ErrorTitle: "Message undeliverable", UpdatedAt: accepted},
+	}
+	for _, update := range updates {
+		if err := repo.Update(update); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	status, err := repo.Get("wamid.1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if status.Status != models.DeliveryRead || status.UserID != "user123" {
+		t.Errorf("Expected read message of user123, got %+v", status)
+	}
+	if !status.AcceptedAt.Equal(accepted) {
+		t.Errorf("Expected accepted at %v, got %v", accepted, status.AcceptedAt)
+	}
+
+	failed, err := repo.ListFailed()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(failed) != 1 || failed[0].MessageID != "wamid.2" || failed[0].ErrorCode != 131026 {
+		t.Errorf("Expected wamid.2 to be the only failed message, got %+v", failed)
+	}
+
+	if _, err := repo.Get("unknown"); !errors.Is(err, domainerrors.ErrMessageNotFound) {
+		t.Errorf("Expected ErrMessageNotFound, got %v", err)
+	}
+}
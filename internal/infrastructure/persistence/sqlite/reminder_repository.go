@@ -0,0 +1,119 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+// ReminderRepository implements repository.ReminderRepository on SQLite.
+// Sent and cancelled reminders are kept with their final status.
+type ReminderRepository struct {
+	db *sql.DB
+}
+
+// NewReminderRepository creates a new SQLite reminder repository
+func NewReminderRepository(db *sql.DB) *ReminderRepository {
+	return &ReminderRepository{db: db}
+}
+
+const reminderColumns = `id, kind, user_id, appointment_id, text, due_at, expires_at, status, created_at, sent_at`
+
+// Schedule saves a pending reminder, replacing a pending one with the same ID.
+// A reminder that was already sent or cancelled is scheduled again.
+func (r *ReminderRepository) Schedule(reminder *models.Reminder) error {
+	var expiresAt int64
+	if !reminder.ExpiresAt.IsZero() {
+		expiresAt = toUnix(reminder.ExpiresAt)
+	}
+
+	_, err := r.db.Exec(`INSERT INTO reminders (`+reminderColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 0)
+		ON CONFLICT (id) DO UPDATE SET
+			kind = excluded.kind,
+			user_id = excluded.user_id,
+			appointment_id = excluded.appointment_id,
+			text = excluded.text,
+			due_at = excluded.due_at,
+			expires_at = excluded.expires_at,
+			status = excluded.status,
+			created_at = excluded.created_at,
+			sent_at = 0`,
+		reminder.ID, reminder.Kind, reminder.UserID, reminder.AppointmentID, reminder.Text,
+		toUnix(reminder.DueAt), expiresAt, models.ReminderPending, toUnix(reminder.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to schedule reminder: %v", err)
+	}
+	return nil
+}
+
+// Cancel drops a pending reminder
+func (r *ReminderRepository) Cancel(id string) error {
+	if _, err := r.db.Exec(`UPDATE reminders SET status = ? WHERE id = ? AND status = ?`,
+		models.ReminderCancelled, id, models.ReminderPending); err != nil {
+		return fmt.Errorf("failed to cancel reminder: %v", err)
+	}
+	return nil
+}
+
+// ListDue returns up to limit pending reminders due at now, earliest first
+func (r *ReminderRepository) ListDue(now time.Time, limit int) ([]*models.Reminder, error) {
+	rows, err := r.db.Query(`SELECT `+reminderColumns+`
+		FROM reminders WHERE status = ? AND due_at <= ? ORDER BY due_at LIMIT ?`,
+		models.ReminderPending, toUnix(now), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due reminders: %v", err)
+	}
+	defer rows.Close()
+
+	var reminders []*models.Reminder
+	for rows.Next() {
+		reminder, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, reminder)
+	}
+
+	return reminders, rows.Err()
+}
+
+// Claim marks a pending reminder as sent, reporting whether it was still pending
+func (r *ReminderRepository) Claim(id string, sentAt time.Time) (bool, error) {
+	result, err := r.db.Exec(`UPDATE reminders SET status = ?, sent_at = ? WHERE id = ? AND status = ?`,
+		models.ReminderSent, toUnix(sentAt), id, models.ReminderPending)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim reminder: %v", err)
+	}
+
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim reminder: %v", err)
+	}
+	return claimed == 1, nil
+}
+
+func scanReminder(row rowScanner) (*models.Reminder, error) {
+	var (
+		reminder                            models.Reminder
+		dueAt, expiresAt, createdAt, sentAt int64
+	)
+
+	if err := row.Scan(&reminder.ID, &reminder.Kind, &reminder.UserID, &reminder.AppointmentID, &reminder.Text,
+		&dueAt, &expiresAt, &reminder.Status, &createdAt, &sentAt); err != nil {
+		return nil, err
+	}
+
+	reminder.DueAt = fromUnix(dueAt)
+	if expiresAt != 0 {
+		reminder.ExpiresAt = fromUnix(expiresAt)
+	}
+	reminder.CreatedAt = fromUnix(createdAt)
+	if sentAt != 0 {
+		reminder.SentAt = fromUnix(sentAt)
+	}
+
+	return &reminder, nil
+}
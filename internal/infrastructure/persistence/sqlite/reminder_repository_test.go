@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+func TestReminderRepository_SurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chatbot.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	now := time.Now()
+	repo := NewReminderRepository(db)
+	reminders := []*models.Reminder{
+		{ID: "appointment:1", Kind: models.ReminderAppointment, UserID: "user123", AppointmentID: "1", Text: "Turno",
+			DueAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour), CreatedAt: now},
+		{ID: "callback:user456", Kind: models.ReminderCallback, UserID: "user456", Text: "Te llamamos",
+			DueAt: now.Add(-2 * time.Minute), CreatedAt: now},
+		{ID: "appointment:2", Kind: models.ReminderAppointment, UserID: "user789", Text: "Turno", DueAt: now.Add(time.Hour), CreatedAt: now},
+	}
+	for _, reminder := range reminders {
+		if err := repo.Schedule(reminder); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	db.Close()
+
+	// Reopen the database as a restarted service would
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+	repo = NewReminderRepository(db)
+
+	due, err := repo.ListDue(now, 10)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(due) != 2 || due[0].ID != "callback:user456" || due[1].ID != "appointment:1" {
+		t.Fatalf("Expected the 2 due reminders, earliest first, got %+v", due)
+	}
+	if due[1].AppointmentID != "1" || !due[1].ExpiresAt.Equal(reminders[0].ExpiresAt) || due[1].Status != models.ReminderPending {
+		t.Errorf("Stored reminder does not match, got %+v", due[1])
+	}
+
+	if claimed, err := repo.Claim("callback:user456", now); err != nil || !claimed {
+		t.Fatalf("Expected to claim the pending reminder, got %v err=%v", claimed, err)
+	}
+	if claimed, _ := repo.Claim("callback:user456", now); claimed {
+		t.Error("Expected a sent reminder not to be claimed again")
+	}
+	repo.Cancel("appointment:1")
+	if claimed, _ := repo.Claim("appointment:1", now); claimed {
+		t.Error("Expected a cancelled reminder not to be claimed")
+	}
+	if due, _ := repo.ListDue(now.Add(2*time.Hour), 10); len(due) != 1 || due[0].ID != "appointment:2" {
+		t.Errorf("Expected only the pending reminder, got %+v", due)
+	}
+}
@@ -0,0 +1,69 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+// TranscriptRepository implements repository.TranscriptRepository on SQLite
+type TranscriptRepository struct {
+	db *sql.DB
+}
+
+// NewTranscriptRepository creates a new SQLite transcript repository
+func NewTranscriptRepository(db *sql.DB) *TranscriptRepository {
+	return &TranscriptRepository{db: db}
+}
+
+// Append adds a message to the transcript of its user
+func (r *TranscriptRepository) Append(entry *models.TranscriptEntry) error {
+	_, err := r.db.Exec(`INSERT INTO transcript_entries
+		(entry_id, user_id, message_id, direction, type, text, media_ref, state_before, state_after, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.ID, entry.UserID, entry.MessageID, entry.Direction, entry.Type, entry.Text, entry.MediaRef,
+		entry.StateBefore, entry.StateAfter, entry.Status, toUnix(entry.Timestamp))
+	if err != nil {
+		return fmt.Errorf("failed to append transcript entry: %v", err)
+	}
+	return nil
+}
+
+// ListByUser returns the transcript of a user, oldest message first
+func (r *TranscriptRepository) ListByUser(userID string) ([]*models.TranscriptEntry, error) {
+	rows, err := r.db.Query(`SELECT entry_id, user_id, message_id, direction, type, text, media_ref, state_before, state_after, status, created_at
+		FROM transcript_entries WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transcript: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.TranscriptEntry
+	for rows.Next() {
+		var (
+			entry     models.TranscriptEntry
+			createdAt int64
+		)
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.MessageID, &entry.Direction, &entry.Type, &entry.Text,
+			&entry.MediaRef, &entry.StateBefore, &entry.StateAfter, &entry.Status, &createdAt); err != nil {
+			return nil, err
+		}
+		entry.Timestamp = fromUnix(createdAt)
+		entries = append(entries, &entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// UpdateDelivery records the delivery status of an outbound entry
+func (r *TranscriptRepository) UpdateDelivery(userID, entryID, messageID, status string) error {
+	_, err := r.db.Exec(`UPDATE transcript_entries
+		SET message_id = CASE WHEN ? = '' THEN message_id ELSE ? END, status = ?
+		WHERE user_id = ? AND entry_id = ?`,
+		messageID, messageID, status, userID, entryID)
+	if err != nil {
+		return fmt.Errorf("failed to update transcript entry: %v", err)
+	}
+	return nil
+}
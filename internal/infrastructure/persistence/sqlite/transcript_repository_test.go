@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+)
+
+func TestTranscriptRepository_AppendAndList(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "chatbot.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewTranscriptRepository(db)
+	now := time.Now().Truncate(time.Second)
+	entries := []*models.TranscriptEntry{
+		{UserID: "user123", MessageID: "wamid.1", Direction: models.DirectionInbound, Type: "text", Text: "hola", StateAfter: "welcome", Timestamp: now},
+		{UserID: "user456", MessageID: "wamid.2", Direction: models.DirectionInbound, Type: "text", Text: "A", Timestamp: now},
+		{UserID: "user123", Direction: models.DirectionOutbound, Type: "text", Text: "Bienvenido", StateBefore: "welcome", StateAfter: "welcome", Timestamp: now},
+	}
+	for _, entry := range entries {
+		if err := repo.Append(entry); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	transcript, err := repo.ListByUser("user123")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(transcript) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(transcript))
+	}
+	if transcript[0].Direction != models.DirectionInbound || transcript[0].MessageID != "wamid.1" {
+		t.Errorf("Expected the inbound message first, got %+v", transcript[0])
+	}
+	if transcript[1].Text != "Bienvenido" || transcript[1].StateAfter != "welcome" {
+		t.Errorf("Expected the reply second, got %+v", transcript[1])
+	}
+	if !transcript[0].Timestamp.Equal(now) {
+		t.Errorf("Expected timestamp %v, got %v", now, transcript[0].Timestamp)
+	}
+}
+
+func TestTranscriptRepository_UpdateDelivery(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "chatbot.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewTranscriptRepository(db)
+	now := time.Now()
+	repo.Append(&models.TranscriptEntry{UserID: "user123", MessageID: "wamid.in", Direction: models.DirectionInbound, Type: "text", Text: "hola", Timestamp: now})
+	repo.Append(&models.TranscriptEntry{ID: "entry1", UserID: "user123", Direction: models.DirectionOutbound, Type: "text", Text: "Bienvenido", Status: models.DeliveryQueued, Timestamp: now})
+	repo.Append(&models.TranscriptEntry{ID: "entry2", UserID: "user123", Direction: models.DirectionOutbound, Type: "text", Text: "Recibido", Status: models.DeliveryQueued, Timestamp: now})
+
+	if err := repo.UpdateDelivery("user123", "entry1", "wamid.1", models.DeliveryAccepted); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := repo.UpdateDelivery("user123", "entry2", "", models.DeliveryFailed); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	transcript, err := repo.ListByUser("user123")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(transcript) != 3 || transcript[0].MessageID != "wamid.in" || transcript[0].Status != "" {
+		t.Fatalf("Expected the inbound message untouched, got %+v", transcript)
+	}
+	if transcript[1].ID != "entry1" || transcript[1].MessageID != "wamid.1" || transcript[1].Status != models.DeliveryAccepted {
+		t.Errorf("Expected the accepted reply with its message ID, got %+v", transcript[1])
+	}
+	if transcript[2].MessageID != "" || transcript[2].Status != models.DeliveryFailed || transcript[2].Text != "Recibido" {
+		t.Errorf("Expected the failed reply kept, got %+v", transcript[2])
+	}
+}
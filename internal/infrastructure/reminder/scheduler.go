@@ -0,0 +1,265 @@
+package reminder
+
+import (
+	"fmt"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
+	"chatbot-wsp/internal/domain/service"
+	"chatbot-wsp/internal/infrastructure/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+// batchSize is how many due reminders are sent per round
+const batchSize = 100
+
+// MessageQueue schedules WhatsApp messages for delivery
+type MessageQueue interface {
+	Enqueue(response *models.WhatsAppResponse) error
+}
+
+// Config holds configuration for the reminder scheduler
+type Config struct {
+	AppointmentLead time.Duration  // How long before an appointment it is reminded; zero disables appointment reminders
+	Callbacks       bool           // Remind patients who wrote after hours when staff starts answering
+	Timezone        *time.Location // Timezone appointment times are shown in
+}
+
+// Scheduler persists reminders in a repository and sends them through the
+// outbound queue once they are due. Reminders survive restarts when the
+// repository does; the ones that came due while the service was down are
+// sent on start, unless they are no longer relevant.
+type Scheduler struct {
+	reminders    repository.ReminderRepository
+	appointments repository.AppointmentRepository
+	queue        MessageQueue
+	config       *Config
+	stop         chan bool
+	done         chan bool
+}
+
+// NewScheduler creates a scheduler. Call Start to begin sending reminders.
+func NewScheduler(reminders repository.ReminderRepository, appointments repository.AppointmentRepository, queue MessageQueue, config *Config) *Scheduler {
+	if config.Timezone == nil {
+		config.Timezone = time.Local
+	}
+	return &Scheduler{
+		reminders:    reminders,
+		appointments: appointments,
+		queue:        queue,
+		config:       config,
+		stop:         make(chan bool),
+	}
+}
+
+// AppointmentBooked schedules the reminder of an appointment. Appointments
+// booked closer than the lead time are not reminded, the patient just confirmed them.
+func (s *Scheduler) AppointmentBooked(appointment *models.Appointment) {
+	if s.config.AppointmentLead <= 0 {
+		return
+	}
+
+	now := time.Now()
+	dueAt := appointment.Start.Add(-s.config.AppointmentLead)
+	if dueAt.Before(now) {
+		return
+	}
+
+	s.schedule(&models.Reminder{
+		ID:            appointmentReminderID(appointment.ID),
+		Kind:          models.ReminderAppointment,
+		UserID:        appointment.UserID,
+		AppointmentID: appointment.ID,
+		Text:          s.formatAppointment(appointment),
+		DueAt:         dueAt,
+		ExpiresAt:     appointment.Start,
+		CreatedAt:     now,
+	})
+}
+
+// AppointmentCancelled drops the reminder of an appointment
+func (s *Scheduler) AppointmentCancelled(appointment *models.Appointment) {
+	if err := s.reminders.Cancel(appointmentReminderID(appointment.ID)); err != nil {
+		logger.GetLogger().WithFields(logrus.Fields{
+			"appointment_id": appointment.ID,
+			"error":          err.Error(),
+		}).Error("Failed to cancel appointment reminder")
+	}
+}
+
+// CallbackPending schedules a reminder for when staff starts answering a
+// request made after hours. A newer request replaces the pending reminder.
+func (s *Scheduler) CallbackPending(userID string, window *models.CallbackWindow) {
+	if !s.config.Callbacks {
+		return
+	}
+
+	s.schedule(&models.Reminder{
+		ID:        "callback:" + userID,
+		Kind:      models.ReminderCallback,
+		UserID:    userID,
+		Text:      formatCallback(window),
+		DueAt:     window.From,
+		ExpiresAt: window.Until,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (s *Scheduler) schedule(reminder *models.Reminder) {
+	if err := s.reminders.Schedule(reminder); err != nil {
+		logger.GetLogger().WithFields(logrus.Fields{
+			"user_id":     reminder.UserID,
+			"reminder_id": reminder.ID,
+			"error":       err.Error(),
+		}).Error("Failed to schedule reminder")
+		return
+	}
+
+	logger.GetLogger().WithFields(logrus.Fields{
+		"user_id":     reminder.UserID,
+		"reminder_id": reminder.ID,
+		"due_at":      reminder.DueAt,
+	}).Info("Reminder scheduled")
+}
+
+// Start sends the reminders already due, then checks for due reminders every interval
+func (s *Scheduler) Start(interval time.Duration) {
+	s.done = make(chan bool)
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.sendDue(time.Now())
+
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops checking for due reminders, waiting for a round in progress to finish
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	if s.done != nil {
+		<-s.done
+	}
+}
+
+// sendDue queues the reminders due at now, returning how many were queued
+func (s *Scheduler) sendDue(now time.Time) int {
+	due, err := s.reminders.ListDue(now, batchSize)
+	if err != nil {
+		logger.GetLogger().WithError(err).Error("Failed to list due reminders")
+		return 0
+	}
+
+	sent := 0
+	for _, reminder := range due {
+		if reason := s.staleReason(reminder, now); reason != "" {
+			logger.GetLogger().WithFields(logrus.Fields{
+				"user_id":     reminder.UserID,
+				"reminder_id": reminder.ID,
+				"reason":      reason,
+			}).Info("Dropping reminder")
+			if err := s.reminders.Cancel(reminder.ID); err != nil {
+				logger.GetLogger().WithError(err).WithField("reminder_id", reminder.ID).Error("Failed to drop reminder")
+			}
+			continue
+		}
+
+		// Instances sharing the repository list the same reminders, only the
+		// one claiming a reminder sends it
+		claimed, err := s.reminders.Claim(reminder.ID, now)
+		if err != nil {
+			logger.GetLogger().WithError(err).WithField("reminder_id", reminder.ID).Error("Failed to claim reminder")
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		response := &models.WhatsAppResponse{
+			MessagingProduct: "whatsapp",
+			To:               reminder.UserID,
+			Type:             "text",
+			ReminderID:       reminder.ID,
+		}
+		response.Text.Body = reminder.Text
+
+		if err := s.queue.Enqueue(response); err != nil {
+			// The queue is full, the reminder is pending again for the next round
+			logger.GetLogger().WithFields(logrus.Fields{
+				"user_id":     reminder.UserID,
+				"reminder_id": reminder.ID,
+				"error":       err.Error(),
+			}).Warn("Failed to queue reminder - retrying later")
+			if err := s.reminders.Schedule(reminder); err != nil {
+				logger.GetLogger().WithError(err).WithField("reminder_id", reminder.ID).Error("Failed to reschedule reminder")
+			}
+			break
+		}
+		sent++
+
+		logger.GetLogger().WithFields(logrus.Fields{
+			"user_id":     reminder.UserID,
+			"reminder_id": reminder.ID,
+			"kind":        reminder.Kind,
+		}).Info("Reminder queued")
+	}
+
+	return sent
+}
+
+// staleReason explains why a due reminder must not be sent anymore, or returns
+// an empty string when it is still relevant
+func (s *Scheduler) staleReason(reminder *models.Reminder, now time.Time) string {
+	if !reminder.ExpiresAt.IsZero() && !now.Before(reminder.ExpiresAt) {
+		return "expired"
+	}
+	if reminder.Kind != models.ReminderAppointment {
+		return ""
+	}
+
+	// Staff may have cancelled the appointment from the admin API
+	booked, err := s.appointments.ListByUser(reminder.UserID, now)
+	if err != nil {
+		// Better a reminder too many than a missed appointment
+		logger.GetLogger().WithError(err).WithField("reminder_id", reminder.ID).Warn("Failed to check appointment - sending reminder")
+		return ""
+	}
+	for _, appointment := range booked {
+		if appointment.ID == reminder.AppointmentID {
+			return ""
+		}
+	}
+	return "appointment cancelled"
+}
+
+func appointmentReminderID(appointmentID string) string {
+	return "appointment:" + appointmentID
+}
+
+// formatAppointment builds the reminder of an appointment
+func (s *Scheduler) formatAppointment(appointment *models.Appointment) string {
+	text := fmt.Sprintf("⏰ Te recordamos tu turno:\n📅 %s\n📍 %s",
+		service.FormatSlotTime(appointment.Start.In(s.config.Timezone)), appointment.LocationName)
+	if appointment.PatientName != "" {
+		text += "\n👶 " + appointment.PatientName
+	}
+	return text + "\n\nSi no podés asistir, escribinos para cancelarlo o reprogramarlo."
+}
+
+// formatCallback builds the reminder of a request made after hours
+func formatCallback(window *models.CallbackWindow) string {
+	return fmt.Sprintf("👋 ¡Hola! Recibimos tu consulta fuera del horario de atención. La Dra. te responderá el %s.",
+		service.FormatCallbackWindow(window))
+}
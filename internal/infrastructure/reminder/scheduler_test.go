@@ -0,0 +1,197 @@
+package reminder
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/domain/repository"
+	"chatbot-wsp/internal/infrastructure/persistence/redis"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// fakeQueue records the messages it is asked to send, failing while full is set
+type fakeQueue struct {
+	mutex sync.Mutex
+	sent  []*models.WhatsAppResponse
+	full  bool
+}
+
+func (q *fakeQueue) Enqueue(response *models.WhatsAppResponse) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.full {
+		return errors.New("outbound queue is full")
+	}
+	q.sent = append(q.sent, response)
+	return nil
+}
+
+func (q *fakeQueue) messages() []*models.WhatsAppResponse {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return append([]*models.WhatsAppResponse(nil), q.sent...)
+}
+
+func newTestScheduler(config *Config) (*Scheduler, *repository.InMemoryAppointmentRepository, *fakeQueue) {
+	appointments := repository.NewInMemoryAppointmentRepository()
+	queue := &fakeQueue{}
+	return NewScheduler(repository.NewInMemoryReminderRepository(), appointments, queue, config), appointments, queue
+}
+
+func bookTestAppointment(t *testing.T, appointments repository.AppointmentRepository, userID string, start time.Time) *models.Appointment {
+	appointment := &models.Appointment{UserID: userID, PatientName: "Juan Pérez", LocationID: "cervantes",
+		LocationName: "Centro Médico Cervantes", Start: start, End: start.Add(20 * time.Minute), CreatedAt: time.Now()}
+	if err := appointments.Book(appointment); err != nil {
+		t.Fatalf("Failed to book appointment: %v", err)
+	}
+	return appointment
+}
+
+func TestScheduler_RemindsAppointments(t *testing.T) {
+	scheduler, appointments, queue := newTestScheduler(&Config{AppointmentLead: time.Hour})
+	now := time.Now()
+
+	kept := bookTestAppointment(t, appointments, "user123", now.Add(3*time.Hour))
+	scheduler.AppointmentBooked(kept)
+	cancelled := bookTestAppointment(t, appointments, "user456", now.Add(3*time.Hour+20*time.Minute))
+	scheduler.AppointmentBooked(cancelled)
+	// Booked right before it starts, the patient needs no reminder
+	soon := bookTestAppointment(t, appointments, "user789", now.Add(30*time.Minute))
+	scheduler.AppointmentBooked(soon)
+
+	if sent := scheduler.sendDue(now); sent != 0 {
+		t.Errorf("Expected nothing due yet, got %d", sent)
+	}
+
+	// Staff cancels one of them from the admin API
+	appointments.Cancel(cancelled.ID, now)
+
+	if sent := scheduler.sendDue(now.Add(2*time.Hour + 30*time.Minute)); sent != 1 {
+		t.Fatalf("Expected 1 reminder, got %d", sent)
+	}
+	messages := queue.messages()
+	if messages[0].To != "user123" || !strings.Contains(messages[0].Text.Body, "Centro Médico Cervantes") || !strings.Contains(messages[0].Text.Body, "Juan Pérez") {
+		t.Errorf("Expected the reminder of user123's appointment, got %+v", messages[0])
+	}
+	if messages[0].ReminderID == "" {
+		t.Errorf("Expected the message to carry its reminder ID")
+	}
+
+	// Each reminder is sent once
+	if sent := scheduler.sendDue(now.Add(2*time.Hour + 40*time.Minute)); sent != 0 {
+		t.Errorf("Expected no reminder left, got %d", sent)
+	}
+}
+
+func TestScheduler_CancelledAppointment(t *testing.T) {
+	scheduler, appointments, queue := newTestScheduler(&Config{AppointmentLead: time.Hour})
+	appointment := bookTestAppointment(t, appointments, "user123", time.Now().Add(3*time.Hour))
+
+	scheduler.AppointmentBooked(appointment)
+	scheduler.AppointmentCancelled(appointment)
+
+	scheduler.sendDue(time.Now().Add(2*time.Hour + 30*time.Minute))
+	if messages := queue.messages(); len(messages) != 0 {
+		t.Errorf("Expected no reminder for a cancelled appointment, got %d", len(messages))
+	}
+}
+
+func TestScheduler_RemindsCallbacks(t *testing.T) {
+	scheduler, _, queue := newTestScheduler(&Config{Callbacks: true})
+	now := time.Now()
+
+	scheduler.CallbackPending("user123", &models.CallbackWindow{From: now.Add(time.Hour), Until: now.Add(5 * time.Hour), AfterHours: true})
+	// Staff already answered in this window
+	scheduler.CallbackPending("user456", &models.CallbackWindow{From: now.Add(-5 * time.Hour), Until: now.Add(-time.Hour), AfterHours: true})
+
+	if sent := scheduler.sendDue(now.Add(time.Hour)); sent != 1 {
+		t.Fatalf("Expected 1 reminder, got %d", sent)
+	}
+	if messages := queue.messages(); messages[0].To != "user123" || !strings.Contains(messages[0].Text.Body, "La Dra. te responderá") {
+		t.Errorf("Expected the callback reminder of user123, got %+v", messages[0])
+	}
+}
+
+func TestScheduler_RetriesWhenQueueIsFull(t *testing.T) {
+	scheduler, _, queue := newTestScheduler(&Config{Callbacks: true})
+	now := time.Now()
+	scheduler.CallbackPending("user123", &models.CallbackWindow{From: now, Until: now.Add(time.Hour), AfterHours: true})
+
+	queue.full = true
+	if sent := scheduler.sendDue(now); sent != 0 {
+		t.Fatalf("Expected nothing sent while the queue is full, got %d", sent)
+	}
+
+	queue.full = false
+	if sent := scheduler.sendDue(now.Add(time.Minute)); sent != 1 {
+		t.Errorf("Expected the reminder to be sent once the queue has room, got %d", sent)
+	}
+}
+
+func TestScheduler_SharedRepositorySendsOnce(t *testing.T) {
+	server := miniredis.RunT(t)
+	newInstance := func() (*Scheduler, *fakeQueue) {
+		client, err := redis.Open(&redis.Config{Addr: server.Addr()})
+		if err != nil {
+			t.Fatalf("Failed to connect to miniredis: %v", err)
+		}
+		t.Cleanup(func() { client.Close() })
+
+		queue := &fakeQueue{}
+		reminders := redis.NewReminderRepository(client, "test:")
+		return NewScheduler(reminders, repository.NewInMemoryAppointmentRepository(), queue, &Config{Callbacks: true}), queue
+	}
+	first, firstQueue := newInstance()
+	second, secondQueue := newInstance()
+
+	now := time.Now()
+	for i := 0; i < 20; i++ {
+		first.CallbackPending(fmt.Sprintf("user%d", i), &models.CallbackWindow{From: now.Add(-time.Minute), Until: now.Add(time.Hour), AfterHours: true})
+	}
+
+	// Both instances find the same reminders due at once
+	var wg sync.WaitGroup
+	for _, scheduler := range []*Scheduler{first, second} {
+		wg.Add(1)
+		go func(scheduler *Scheduler) {
+			defer wg.Done()
+			scheduler.sendDue(now)
+		}(scheduler)
+	}
+	wg.Wait()
+
+	sent := map[string]int{}
+	for _, message := range append(firstQueue.messages(), secondQueue.messages()...) {
+		sent[message.To]++
+	}
+	if len(sent) != 20 {
+		t.Errorf("Expected a reminder for each of the 20 users, got %d", len(sent))
+	}
+	for userID, count := range sent {
+		if count != 1 {
+			t.Errorf("Expected %s to be reminded once, got %d", userID, count)
+		}
+	}
+}
+
+func TestScheduler_StartAndStop(t *testing.T) {
+	scheduler, _, queue := newTestScheduler(&Config{Callbacks: true})
+	now := time.Now()
+	// Came due while the service was down
+	scheduler.CallbackPending("user123", &models.CallbackWindow{From: now.Add(-time.Minute), Until: now.Add(time.Hour), AfterHours: true})
+
+	scheduler.Start(time.Hour)
+	scheduler.Stop()
+
+	if messages := queue.messages(); len(messages) != 1 {
+		t.Errorf("Expected the overdue reminder to be sent on start, got %d", len(messages))
+	}
+}
@@ -0,0 +1,262 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	// The runtime image ships without a zoneinfo database
+	_ "time/tzdata"
+)
+
+// DefaultTimezone is the timezone of the practice
+const DefaultTimezone = "America/Argentina/Buenos_Aires"
+
+// maxLookaheadDays bounds the search for the next opening, e.g. across long holiday periods
+const maxLookaheadDays = 370
+
+// holidayLayout is the format of holiday dates
+const holidayLayout = "2006-01-02"
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// interval is an opening range within a day, in minutes since midnight
+type interval struct {
+	start int
+	end   int
+}
+
+// WeeklySchedule holds the opening hours of each weekday, closed on holidays
+type WeeklySchedule struct {
+	location *time.Location
+	days     [7][]interval
+	holidays map[string]bool
+}
+
+// New parses a weekly schedule such as "mon-fri 09:00-13:00,15:00-19:00; sat 09:00-12:00".
+// Holidays are dates formatted as YYYY-MM-DD, and timezone is an IANA name; an empty
+// timezone uses DefaultTimezone.
+func New(spec string, holidays []string, timezone string) (*WeeklySchedule, error) {
+	if timezone == "" {
+		timezone = DefaultTimezone
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %v", timezone, err)
+	}
+
+	s := &WeeklySchedule{
+		location: location,
+		holidays: make(map[string]bool, len(holidays)),
+	}
+
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if err := s.parseEntry(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	open := false
+	for day := range s.days {
+		sort.Slice(s.days[day], func(i, j int) bool {
+			return s.days[day][i].start < s.days[day][j].start
+		})
+		open = open || len(s.days[day]) > 0
+	}
+	if !open {
+		return nil, fmt.Errorf("schedule %q has no opening hours", spec)
+	}
+
+	for _, holiday := range holidays {
+		date, err := time.Parse(holidayLayout, strings.TrimSpace(holiday))
+		if err != nil {
+			return nil, fmt.Errorf("invalid holiday %q, expected YYYY-MM-DD", holiday)
+		}
+		s.holidays[date.Format(holidayLayout)] = true
+	}
+
+	return s, nil
+}
+
+// Location returns the timezone of the schedule
+func (s *WeeklySchedule) Location() *time.Location {
+	return s.location
+}
+
+// IsOpen reports whether t falls within opening hours
+func (s *WeeklySchedule) IsOpen(t time.Time) bool {
+	t = t.In(s.location)
+	if s.isHoliday(t) {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	for _, iv := range s.days[t.Weekday()] {
+		if minute >= iv.start && minute < iv.end {
+			return true
+		}
+	}
+	return false
+}
+
+// CallbackWindow returns the opening hours in which a request made at t can be
+// answered: the rest of the current range when open, otherwise the next range.
+// ok is false when no opening is found within a year.
+func (s *WeeklySchedule) CallbackWindow(t time.Time) (start, end time.Time, ok bool) {
+	t = t.In(s.location)
+
+	for offset := 0; offset <= maxLookaheadDays; offset++ {
+		day := time.Date(t.Year(), t.Month(), t.Day()+offset, 0, 0, 0, 0, s.location)
+		if s.isHoliday(day) {
+			continue
+		}
+
+		for _, iv := range s.days[day.Weekday()] {
+			start = time.Date(day.Year(), day.Month(), day.Day(), iv.start/60, iv.start%60, 0, 0, s.location)
+			end = time.Date(day.Year(), day.Month(), day.Day(), iv.end/60, iv.end%60, 0, 0, s.location)
+			if !end.After(t) {
+				continue
+			}
+			if start.Before(t) {
+				start = t
+			}
+			return start, end, true
+		}
+	}
+
+	return time.Time{}, time.Time{}, false
+}
+
+// Slots splits the opening hours between from and to into consecutive slots of
+// length, returning the start of each slot that begins at or after from and
+// ends within its range
+func (s *WeeklySchedule) Slots(from, to time.Time, length time.Duration) []time.Time {
+	if length <= 0 {
+		return nil
+	}
+	from = from.In(s.location)
+
+	var starts []time.Time
+	for day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, s.location); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if s.isHoliday(day) {
+			continue
+		}
+
+		for _, iv := range s.days[day.Weekday()] {
+			end := time.Date(day.Year(), day.Month(), day.Day(), iv.end/60, iv.end%60, 0, 0, s.location)
+			start := time.Date(day.Year(), day.Month(), day.Day(), iv.start/60, iv.start%60, 0, 0, s.location)
+			for ; !start.Add(length).After(end) && start.Before(to); start = start.Add(length) {
+				if !start.Before(from) {
+					starts = append(starts, start)
+				}
+			}
+		}
+	}
+
+	return starts
+}
+
+func (s *WeeklySchedule) isHoliday(t time.Time) bool {
+	return s.holidays[t.Format(holidayLayout)]
+}
+
+// parseEntry parses "<days> <ranges>", e.g. "mon-fri 09:00-18:00" or "sat,sun 10:00-12:00"
+func (s *WeeklySchedule) parseEntry(entry string) error {
+	fields := strings.Fields(entry)
+	if len(fields) != 2 {
+		return fmt.Errorf("invalid schedule entry %q, expected \"<days> <HH:MM-HH:MM>\"", entry)
+	}
+
+	days, err := parseDays(strings.ToLower(fields[0]))
+	if err != nil {
+		return err
+	}
+
+	for _, value := range strings.Split(fields[1], ",") {
+		iv, err := parseInterval(value)
+		if err != nil {
+			return err
+		}
+		for _, day := range days {
+			s.days[day] = append(s.days[day], iv)
+		}
+	}
+
+	return nil
+}
+
+// parseDays parses a comma separated list of days or day ranges, e.g. "mon-fri,sun"
+func parseDays(value string) ([]time.Weekday, error) {
+	var days []time.Weekday
+
+	for _, part := range strings.Split(value, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, ok := weekdays[from]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = weekdays[to]; !ok {
+				return nil, fmt.Errorf("invalid day %q", to)
+			}
+		}
+
+		// Ranges may wrap around the week, e.g. "sat-mon"
+		for day := first; ; day = (day + 1) % 7 {
+			days = append(days, day)
+			if day == last {
+				break
+			}
+		}
+	}
+
+	return days, nil
+}
+
+// parseInterval parses "HH:MM-HH:MM"; the end may be 24:00
+func parseInterval(value string) (interval, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(value), "-")
+	if !ok {
+		return interval{}, fmt.Errorf("invalid opening range %q, expected HH:MM-HH:MM", value)
+	}
+
+	start, err := parseClock(from)
+	if err != nil {
+		return interval{}, err
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return interval{}, err
+	}
+	if start >= end {
+		return interval{}, fmt.Errorf("opening range %q ends before it starts", value)
+	}
+
+	return interval{start: start, end: end}, nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(value string) (int, error) {
+	hours, minutes, ok := strings.Cut(value, ":")
+	h, errH := strconv.Atoi(hours)
+	m, errM := strconv.Atoi(minutes)
+	if !ok || errH != nil || errM != nil || h < 0 || m < 0 || m > 59 || h*60+m > 24*60 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return h*60 + m, nil
+}
@@ -0,0 +1,139 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestSchedule(t *testing.T) *WeeklySchedule {
+	t.Helper()
+	s, err := New("mon-fri 09:00-13:00,15:00-19:00; sat 09:00-12:00", []string{"2026-10-12"}, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return s
+}
+
+func TestWeeklySchedule_IsOpen(t *testing.T) {
+	s := newTestSchedule(t)
+	at := func(value string) time.Time {
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", value, s.Location())
+		if err != nil {
+			t.Fatalf("Invalid test time %q: %v", value, err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name string
+		time time.Time
+		open bool
+	}{
+		{"weekday morning", at("2026-10-14 10:30"), true},
+		{"lunch break", at("2026-10-14 14:00"), false},
+		{"closing time", at("2026-10-14 19:00"), false},
+		{"saturday morning", at("2026-10-17 11:59"), true},
+		{"sunday", at("2026-10-18 10:00"), false},
+		{"holiday", at("2026-10-12 10:00"), false},
+		{"other timezone", time.Date(2026, 10, 14, 13, 30, 0, 0, time.UTC), true}, // 10:30 in Buenos Aires
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.IsOpen(tt.time); got != tt.open {
+				t.Errorf("Expected open=%v, got %v", tt.open, got)
+			}
+		})
+	}
+}
+
+func TestWeeklySchedule_CallbackWindow(t *testing.T) {
+	s := newTestSchedule(t)
+	at := func(value string) time.Time {
+		parsed, _ := time.ParseInLocation("2006-01-02 15:04", value, s.Location())
+		return parsed
+	}
+
+	tests := []struct {
+		name  string
+		time  time.Time
+		start time.Time
+		end   time.Time
+	}{
+		{"open now", at("2026-10-14 10:30"), at("2026-10-14 10:30"), at("2026-10-14 13:00")},
+		{"lunch break", at("2026-10-14 14:00"), at("2026-10-14 15:00"), at("2026-10-14 19:00")},
+		{"night", at("2026-10-14 23:00"), at("2026-10-15 09:00"), at("2026-10-15 13:00")},
+		{"saturday afternoon", at("2026-10-17 15:00"), at("2026-10-19 09:00"), at("2026-10-19 13:00")},
+		{"before holiday", at("2026-10-11 20:00"), at("2026-10-13 09:00"), at("2026-10-13 13:00")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, ok := s.CallbackWindow(tt.time)
+			if !ok {
+				t.Fatal("Expected a callback window")
+			}
+			if !start.Equal(tt.start) || !end.Equal(tt.end) {
+				t.Errorf("Expected %v - %v, got %v - %v", tt.start, tt.end, start, end)
+			}
+		})
+	}
+}
+
+func TestNew_RejectsInvalidSchedules(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		holidays []string
+		timezone string
+	}{
+		{"empty", "", nil, ""},
+		{"unknown day", "lun-vie 09:00-18:00", nil, ""},
+		{"missing range", "mon-fri", nil, ""},
+		{"reversed range", "mon 18:00-09:00", nil, ""},
+		{"invalid time", "mon 09:00-25:00", nil, ""},
+		{"invalid holiday", "mon 09:00-18:00", []string{"25/12/2026"}, ""},
+		{"invalid timezone", "mon 09:00-18:00", nil, "Mars/Olympus"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.spec, tt.holidays, tt.timezone); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestWeeklySchedule_Slots(t *testing.T) {
+	s, err := New("tue 16:00-17:00; thu 09:00-09:50", []string{"2026-10-22"}, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	at := func(value string) time.Time {
+		parsed, _ := time.ParseInLocation("2006-01-02 15:04", value, s.Location())
+		return parsed
+	}
+
+	// From tuesday 16:10 to the end of the following week
+	slots := s.Slots(at("2026-10-13 16:10"), at("2026-10-25 00:00"), 20*time.Minute)
+
+	expected := []time.Time{
+		at("2026-10-13 16:20"),
+		at("2026-10-13 16:40"),
+		at("2026-10-15 09:00"), // 09:40 would end after closing time
+		at("2026-10-15 09:20"),
+		at("2026-10-20 16:00"),
+		at("2026-10-20 16:20"),
+		at("2026-10-20 16:40"),
+		// Thursday 22 is a holiday
+	}
+	if len(slots) != len(expected) {
+		t.Fatalf("Expected %d slots, got %d: %v", len(expected), len(slots), slots)
+	}
+	for i := range expected {
+		if !slots[i].Equal(expected[i]) {
+			t.Errorf("Expected slot %d at %v, got %v", i, expected[i], slots[i])
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package whatsapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/infrastructure/logger"
+	"chatbot-wsp/internal/infrastructure/metrics"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Defaults of the WhatsApp Business (Graph) API endpoint
+const (
+	DefaultBaseURL    = "https://graph.facebook.com"
+	DefaultAPIVersion = "v17.0"
+)
+
+// messagingProduct is the product every Graph API message belongs to
+const messagingProduct = "whatsapp"
+
+// WhatsAppClient talks to the WhatsApp Business API
+type WhatsAppClient interface {
+	// Send delivers a reply built by the chatbot, returning the ID assigned to it
+	Send(response *models.WhatsAppResponse) (string, error)
+	SendText(to, body string) (string, error)
+	SendInteractive(to string, interactive *models.InteractiveContent) (string, error)
+	SendTemplate(to string, template *models.TemplateContent) (string, error)
+	// DownloadMedia fetches the content of a media object received in a webhook.
+	// The caller must close the returned reader.
+	DownloadMedia(mediaID string) (io.ReadCloser, string, error)
+	// MarkRead shows the blue ticks on a message received from a user
+	MarkRead(messageID string) error
+}
+
+// Config holds configuration for the WhatsApp Business API client
+type Config struct {
+	AccessToken    string
+	PhoneNumberID  string
+	MyPhoneNumber  string
+	SandboxMode    bool     // Only deliver to MyPhoneNumber and SandboxNumbers
+	SandboxNumbers []string // Additional numbers allowed to receive messages in sandbox mode
+	BaseURL        string   // Defaults to DefaultBaseURL
+	APIVersion     string   // Defaults to DefaultAPIVersion
+}
+
+// APIError is returned when the WhatsApp API answers with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("WhatsApp API error: status %d, response: %s", e.StatusCode, e.Body)
+}
+
+// Retryable reports whether the request may succeed if sent again
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// RequestError is returned when the request could not reach the WhatsApp API
+type RequestError struct {
+	Err error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("failed to send request: %v", e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the request may succeed if sent again
+func (e *RequestError) Retryable() bool {
+	return true
+}
+
+// GraphClient implements WhatsAppClient on the Graph API
+type GraphClient struct {
+	config     *Config
+	baseURL    string
+	httpClient *http.Client
+	metrics    *metrics.Metrics
+}
+
+// NewGraphClient creates a new WhatsApp Business API client
+func NewGraphClient(config *Config, metrics *metrics.Metrics) *GraphClient {
+	baseURL := strings.TrimRight(config.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	version := config.APIVersion
+	if version == "" {
+		version = DefaultAPIVersion
+	}
+
+	return &GraphClient{
+		config:     config,
+		baseURL:    baseURL + "/" + version,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		metrics:    metrics,
+	}
+}
+
+// sendResult is the body the WhatsApp API returns for an accepted message
+type sendResult struct {
+	Messages []struct {
+		ID string `json:"id"`
+	} `json:"messages"`
+}
+
+// Send sends a reply to WhatsApp Business API and returns the ID assigned to it
+func (c *GraphClient) Send(response *models.WhatsAppResponse) (string, error) {
+	switch response.Type {
+	case "interactive":
+		return c.SendInteractive(response.To, response.Interactive)
+	case "template":
+		return c.SendTemplate(response.To, response.Template)
+	default:
+		return c.SendText(response.To, response.Text.Body)
+	}
+}
+
+// SendText sends a plain text message
+func (c *GraphClient) SendText(to, body string) (string, error) {
+	return c.sendMessage(to, "text", models.TextContent{Body: body})
+}
+
+// SendInteractive sends a reply-button or list message
+func (c *GraphClient) SendInteractive(to string, interactive *models.InteractiveContent) (string, error) {
+	if interactive == nil {
+		return "", fmt.Errorf("interactive message has no content")
+	}
+	return c.sendMessage(to, "interactive", interactive)
+}
+
+// SendTemplate sends a pre-approved template, the only kind of message
+// WhatsApp delivers outside the 24 hour customer service window
+func (c *GraphClient) SendTemplate(to string, template *models.TemplateContent) (string, error) {
+	if template == nil || template.Name == "" {
+		return "", fmt.Errorf("template message has no template name")
+	}
+	return c.sendMessage(to, "template", template)
+}
+
+// sendMessage posts a message of the given type and returns the ID assigned to it
+func (c *GraphClient) sendMessage(to, messageType string, content interface{}) (string, error) {
+	// Check if we have the required configuration
+	if c.config.AccessToken == "" || c.config.PhoneNumberID == "" {
+		logger.GetLogger().Warn("WhatsApp configuration missing - skipping message send")
+		return "", fmt.Errorf("WhatsApp configuration incomplete")
+	}
+
+	recipient, err := c.resolveRecipient(to)
+	if err != nil {
+		logger.GetLogger().WithError(err).Error("Failed to resolve message recipient")
+		return "", err
+	}
+
+	payload := map[string]interface{}{
+		"messaging_product": messagingProduct,
+		"to":                recipient,
+		"type":              messageType,
+		messageType:         content,
+	}
+
+	statusCode, body, err := c.post(fmt.Sprintf("%s/%s/messages", c.baseURL, c.config.PhoneNumberID), payload)
+	if err != nil {
+		logger.GetLogger().WithError(err).Error("Failed to send message to WhatsApp API")
+		if _, ok := err.(*RequestError); ok {
+			c.metrics.MessageSent("error")
+		}
+		return "", err
+	}
+	c.metrics.MessageSent(strconv.Itoa(statusCode))
+
+	// Log the response
+	logger.GetLogger().WithFields(logrus.Fields{
+		"status_code": statusCode,
+		"response":    string(body),
+		"to":          recipient,
+		"type":        messageType,
+	}).Info("WhatsApp API response")
+
+	// Check if the request was successful
+	if statusCode >= 200 && statusCode < 300 {
+		var result sendResult
+		if err := json.Unmarshal(body, &result); err != nil || len(result.Messages) == 0 {
+			// The message was accepted, only its status can't be tracked
+			logger.GetLogger().WithField("response", string(body)).Warn("WhatsApp API response has no message ID")
+			return "", nil
+		}
+
+		logger.GetLogger().WithField("message_id", result.Messages[0].ID).Info("Message sent successfully to WhatsApp")
+		return result.Messages[0].ID, nil
+	}
+
+	// Log error response
+	logger.GetLogger().WithFields(logrus.Fields{
+		"status_code": statusCode,
+		"response":    string(body),
+	}).Error("WhatsApp API returned error")
+
+	return "", &APIError{StatusCode: statusCode, Body: string(body)}
+}
+
+// MarkRead marks a message received from a user as read
+func (c *GraphClient) MarkRead(messageID string) error {
+	if c.config.AccessToken == "" || c.config.PhoneNumberID == "" {
+		return fmt.Errorf("WhatsApp configuration incomplete")
+	}
+
+	statusCode, body, err := c.post(fmt.Sprintf("%s/%s/messages", c.baseURL, c.config.PhoneNumberID), map[string]interface{}{
+		"messaging_product": messagingProduct,
+		"status":            "read",
+		"message_id":        messageID,
+	})
+	if err != nil {
+		return err
+	}
+	if statusCode < 200 || statusCode >= 300 {
+		return &APIError{StatusCode: statusCode, Body: string(body)}
+	}
+
+	return nil
+}
+
+// post sends a JSON payload with the access token and returns the status and body of the response
+func (c *GraphClient) post(url string, payload interface{}) (int, []byte, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.config.AccessToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, &RequestError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, &RequestError{Err: fmt.Errorf("failed to read response: %v", err)}
+	}
+
+	return resp.StatusCode, body, nil
+}
+
+// DownloadMedia fetches the content of a media object received in a webhook.
+// The caller must close the returned reader.
+func (c *GraphClient) DownloadMedia(mediaID string) (io.ReadCloser, string, error) {
+	if c.config.AccessToken == "" {
+		return nil, "", fmt.Errorf("WhatsApp configuration incomplete")
+	}
+
+	// Resolve the temporary download URL of the media object
+	resp, err := c.authorizedGet(fmt.Sprintf("%s/%s", c.baseURL, mediaID))
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	var media struct {
+		URL      string `json:"url"`
+		MimeType string `json:"mime_type"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&media); err != nil {
+		return nil, "", fmt.Errorf("failed to decode media metadata: %v", err)
+	}
+	if media.URL == "" {
+		return nil, "", fmt.Errorf("media %s has no download URL", mediaID)
+	}
+
+	// Download the content itself, which requires the same authorization
+	content, err := c.authorizedGet(media.URL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	logger.GetLogger().WithFields(logrus.Fields{
+		"media_id":  mediaID,
+		"mime_type": media.MimeType,
+	}).Info("Downloading WhatsApp media")
+
+	return content.Body, media.MimeType, nil
+}
+
+// authorizedGet performs a GET request with the access token, failing on non-2xx statuses
+func (c *GraphClient) authorizedGet(url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.config.AccessToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, &RequestError{Err: err}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return resp, nil
+}
+
+// resolveRecipient returns the number a message must be delivered to. In sandbox
+// mode messages to numbers outside the allow-list are redirected to MyPhoneNumber
+// so that development environments never message real patients.
+func (c *GraphClient) resolveRecipient(to string) (string, error) {
+	if !c.config.SandboxMode {
+		if to == "" {
+			return "", fmt.Errorf("message has no recipient")
+		}
+		return to, nil
+	}
+
+	if c.config.MyPhoneNumber == "" {
+		return "", fmt.Errorf("sandbox mode requires MyPhoneNumber")
+	}
+
+	if to != "" {
+		for _, allowed := range append([]string{c.config.MyPhoneNumber}, c.config.SandboxNumbers...) {
+			if normalizePhoneNumber(allowed) == normalizePhoneNumber(to) {
+				return to, nil
+			}
+		}
+	}
+
+	logger.GetLogger().WithFields(logrus.Fields{
+		"original_to": to,
+		"redirect_to": c.config.MyPhoneNumber,
+	}).Info("Sandbox mode - redirecting message")
+
+	return c.config.MyPhoneNumber, nil
+}
+
+// normalizePhoneNumber keeps only the digits of a phone number
+func normalizePhoneNumber(number string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, number)
+}
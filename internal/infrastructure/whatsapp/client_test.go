@@ -0,0 +1,200 @@
+package whatsapp_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/infrastructure/metrics"
+	"chatbot-wsp/internal/infrastructure/whatsapp"
+	"chatbot-wsp/internal/infrastructure/whatsapp/whatsapptest"
+)
+
+func TestGraphClient_Send(t *testing.T) {
+	server := whatsapptest.NewServer()
+	defer server.Close()
+	client := whatsapp.NewGraphClient(server.Config(), metrics.New())
+
+	interactive := &models.InteractiveContent{
+		Type: "button",
+		Body: models.TextContent{Body: "Elegí una opción"},
+		Action: models.InteractiveAction{Buttons: []models.InteractiveButton{
+			{Type: "reply", Reply: models.InteractiveReply{ID: "A", Title: "Consulta"}},
+		}},
+	}
+	template := &models.TemplateContent{
+		Name:     "recordatorio_turno",
+		Language: models.TemplateLanguage{Code: "es_AR"},
+	}
+
+	responses := []*models.WhatsAppResponse{
+		{To: "5491111111111", Type: "text", Text: models.TextContent{Body: "Hola"}},
+		{To: "5491111111111", Type: "interactive", Interactive: interactive},
+		{To: "5491111111111", Type: "template", Template: template},
+	}
+	for _, response := range responses {
+		id, err := client.Send(response)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if id == "" {
+			t.Errorf("Expected a message ID for %s message", response.Type)
+		}
+	}
+
+	messages := server.Messages()
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(messages))
+	}
+	if messages[0].Type != "text" || messages[0].Text == nil || messages[0].Text.Body != "Hola" || messages[0].MessagingProduct != "whatsapp" {
+		t.Errorf("Expected text message, got %+v", messages[0])
+	}
+	if messages[1].Interactive == nil || messages[1].Interactive.Action.Buttons[0].Reply.ID != "A" || messages[1].Text != nil {
+		t.Errorf("Expected interactive message without text, got %+v", messages[1])
+	}
+	if messages[2].Template == nil || messages[2].Template.Name != "recordatorio_turno" || messages[2].Template.Language.Code != "es_AR" {
+		t.Errorf("Expected template message, got %+v", messages[2])
+	}
+}
+
+func TestGraphClient_SandboxRedirect(t *testing.T) {
+	server := whatsapptest.NewServer()
+	defer server.Close()
+	config := server.Config()
+	config.SandboxMode = true
+	config.MyPhoneNumber = "5490000000000"
+	config.SandboxNumbers = []string{"+54 9 11 2222-2222"}
+	client := whatsapp.NewGraphClient(config, metrics.New())
+
+	client.SendText("5491122222222", "allowed")
+	client.SendText("5491133333333", "redirected")
+
+	messages := server.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(messages))
+	}
+	if messages[0].To != "5491122222222" {
+		t.Errorf("Expected allowed number to receive the message, got %s", messages[0].To)
+	}
+	if messages[1].To != "5490000000000" {
+		t.Errorf("Expected message redirected to MyPhoneNumber, got %s", messages[1].To)
+	}
+}
+
+func TestGraphClient_Recipient(t *testing.T) {
+	tests := []struct {
+		name          string
+		sandbox       bool
+		myNumber      string
+		to            string
+		expectedTo    string
+		expectedError bool
+	}{
+		{name: "live replies to the sender", to: "5491133333333", expectedTo: "5491133333333"},
+		{name: "live without recipient", expectedError: true},
+		{name: "sandbox to my number", sandbox: true, myNumber: "+54 9 0000000000", to: "5490000000000", expectedTo: "5490000000000"},
+		{name: "sandbox without recipient", sandbox: true, myNumber: "5490000000000", expectedTo: "5490000000000"},
+		{name: "sandbox without my number", sandbox: true, to: "5491133333333", expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := whatsapptest.NewServer()
+			defer server.Close()
+			config := server.Config()
+			config.SandboxMode = tt.sandbox
+			config.MyPhoneNumber = tt.myNumber
+			client := whatsapp.NewGraphClient(config, metrics.New())
+
+			_, err := client.SendText(tt.to, "Hola")
+			if tt.expectedError {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				if len(server.Messages()) != 0 {
+					t.Errorf("Expected nothing sent, got %d messages", len(server.Messages()))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if messages := server.Messages(); len(messages) != 1 || messages[0].To != tt.expectedTo {
+				t.Errorf("Expected the message delivered to %s, got %+v", tt.expectedTo, messages)
+			}
+		})
+	}
+}
+
+func TestGraphClient_APIError(t *testing.T) {
+	server := whatsapptest.NewServer()
+	defer server.Close()
+	client := whatsapp.NewGraphClient(server.Config(), metrics.New())
+
+	tests := []struct {
+		statusCode int
+		retryable  bool
+	}{
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		server.FailNext(tt.statusCode, "failure")
+
+		_, err := client.SendText("5491111111111", "Hola")
+		var apiErr *whatsapp.APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("Expected APIError, got %v", err)
+		}
+		if apiErr.StatusCode != tt.statusCode || apiErr.Retryable() != tt.retryable {
+			t.Errorf("Expected status %d retryable %v, got %d %v", tt.statusCode, tt.retryable, apiErr.StatusCode, apiErr.Retryable())
+		}
+	}
+
+	if len(server.Messages()) != 0 {
+		t.Errorf("Expected no message to be recorded, got %d", len(server.Messages()))
+	}
+}
+
+func TestGraphClient_DownloadMedia(t *testing.T) {
+	server := whatsapptest.NewServer()
+	defer server.Close()
+	server.AddMedia("media123", "image/jpeg", []byte("jpeg-bytes"))
+	client := whatsapp.NewGraphClient(server.Config(), metrics.New())
+
+	content, mimeType, err := client.DownloadMedia("media123")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer content.Close()
+
+	data, _ := io.ReadAll(content)
+	if string(data) != "jpeg-bytes" || mimeType != "image/jpeg" {
+		t.Errorf("Expected jpeg content, got %q (%s)", data, mimeType)
+	}
+
+	if _, _, err := client.DownloadMedia("unknown"); err == nil {
+		t.Error("Expected error for unknown media")
+	}
+}
+
+func TestGraphClient_MarkRead(t *testing.T) {
+	server := whatsapptest.NewServer()
+	defer server.Close()
+	client := whatsapp.NewGraphClient(server.Config(), metrics.New())
+
+	if err := client.MarkRead("wamid.inbound"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	receipts := server.ReadReceipts()
+	if len(receipts) != 1 || receipts[0] != "wamid.inbound" {
+		t.Errorf("Expected wamid.inbound to be marked as read, got %v", receipts)
+	}
+	if len(server.Messages()) != 0 {
+		t.Errorf("Expected read receipts not to count as messages, got %d", len(server.Messages()))
+	}
+}
@@ -0,0 +1,216 @@
+// Package whatsapptest provides an in-process fake of the WhatsApp Business
+// (Graph) API, recording every request so tests can assert what was sent.
+package whatsapptest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"chatbot-wsp/internal/domain/models"
+	"chatbot-wsp/internal/infrastructure/whatsapp"
+)
+
+// Credentials the fake server expects, used by Server.Config
+const (
+	AccessToken   = "test-access-token"
+	PhoneNumberID = "1234567890"
+	APIVersion    = "v17.0"
+)
+
+// Message is a message posted to the fake server, decoded as the Graph API sees it
+type Message struct {
+	MessagingProduct string                     `json:"messaging_product"`
+	To               string                     `json:"to"`
+	Type             string                     `json:"type"`
+	Text             *models.TextContent        `json:"text,omitempty"`
+	Interactive      *models.InteractiveContent `json:"interactive,omitempty"`
+	Template         *models.TemplateContent    `json:"template,omitempty"`
+
+	// Set on read receipts instead of the fields above
+	Status    string `json:"status,omitempty"`
+	MessageID string `json:"message_id,omitempty"`
+
+	ID string `json:"-"` // ID the fake server answered with
+}
+
+// media is a media object served by the fake server
+type media struct {
+	mimeType string
+	content  []byte
+}
+
+// failure is a canned error response for the next sends
+type failure struct {
+	statusCode int
+	body       string
+}
+
+// Server is a fake Graph API. Create it with NewServer and close it when done.
+type Server struct {
+	*httptest.Server
+
+	mutex    sync.Mutex
+	messages []*Message
+	receipts []string
+	media    map[string]*media
+	failures []failure
+	nextID   int
+}
+
+// NewServer starts a fake Graph API
+func NewServer() *Server {
+	s := &Server{media: make(map[string]*media)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Config returns a client configuration pointing at the fake server
+func (s *Server) Config() *whatsapp.Config {
+	return &whatsapp.Config{
+		AccessToken:   AccessToken,
+		PhoneNumberID: PhoneNumberID,
+		BaseURL:       s.URL,
+		APIVersion:    APIVersion,
+	}
+}
+
+// Messages returns the messages sent so far, in order, without read receipts
+func (s *Server) Messages() []*Message {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]*Message(nil), s.messages...)
+}
+
+// ReadReceipts returns the IDs of the messages marked as read
+func (s *Server) ReadReceipts() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]string(nil), s.receipts...)
+}
+
+// AddMedia makes a media object available for download
+func (s *Server) AddMedia(mediaID, mimeType string, content []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.media[mediaID] = &media{mimeType: mimeType, content: content}
+}
+
+// FailNext makes the next send answer with the given status and body
+func (s *Server) FailNext(statusCode int, body string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failures = append(s.failures, failure{statusCode: statusCode, body: body})
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+AccessToken {
+		writeError(w, http.StatusUnauthorized, "Invalid OAuth access token")
+		return
+	}
+
+	// Paths are /{version}/{phone number ID}/messages, /{version}/{media ID}
+	// and /download/{media ID} for the URLs handed out by the media endpoint
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[0] == APIVersion && parts[2] == "messages":
+		s.handleMessage(w, r, parts[1])
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == APIVersion:
+		s.handleMediaURL(w, parts[1])
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "download":
+		s.handleDownload(w, parts[1])
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Unknown path %s %s", r.Method, r.URL.Path))
+	}
+}
+
+func (s *Server) handleMessage(w http.ResponseWriter, r *http.Request, phoneNumberID string) {
+	if phoneNumberID != PhoneNumberID {
+		writeError(w, http.StatusBadRequest, "Unknown phone number ID "+phoneNumberID)
+		return
+	}
+
+	var message Message
+	if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if message.Status == "read" {
+		s.receipts = append(s.receipts, message.MessageID)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+		return
+	}
+
+	if len(s.failures) > 0 {
+		failed := s.failures[0]
+		s.failures = s.failures[1:]
+		writeError(w, failed.statusCode, failed.body)
+		return
+	}
+
+	s.nextID++
+	message.ID = fmt.Sprintf("wamid.test%d", s.nextID)
+	s.messages = append(s.messages, &message)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"contacts":          []map[string]string{{"input": message.To, "wa_id": message.To}},
+		"messages":          []map[string]string{{"id": message.ID}},
+	})
+}
+
+func (s *Server) handleMediaURL(w http.ResponseWriter, mediaID string) {
+	s.mutex.Lock()
+	object, ok := s.media[mediaID]
+	s.mutex.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "Unknown media "+mediaID)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":        mediaID,
+		"url":       s.URL + "/download/" + mediaID,
+		"mime_type": object.mimeType,
+	})
+}
+
+func (s *Server) handleDownload(w http.ResponseWriter, mediaID string) {
+	s.mutex.Lock()
+	object, ok := s.media[mediaID]
+	s.mutex.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "Unknown media "+mediaID)
+		return
+	}
+
+	w.Header().Set("Content-Type", object.mimeType)
+	w.Write(object.content)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError answers with an error shaped like the Graph API ones
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"code":    statusCode,
+		},
+	})
+}
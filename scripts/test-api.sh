@@ -2,6 +2,9 @@
 
 # Test script for WhatsApp Chatbot API
 
+# Webhook payloads below are unsigned: run the server with
+# WHATSAPP_SKIP_SIGNATURE_VERIFICATION=true when using this script.
+
 API_URL="http://localhost:8080"
 
 echo "Testing WhatsApp Chatbot API..."